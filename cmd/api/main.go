@@ -2,189 +2,141 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/jackc/pgx/v5"
-	"github.com/joho/godotenv"
-	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/petermazzocco/go-ecommerce-api/internal/cleanup"
+	"github.com/petermazzocco/go-ecommerce-api/internal/config"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
 	"github.com/petermazzocco/go-ecommerce-api/internal/handlers"
+	"github.com/petermazzocco/go-ecommerce-api/internal/logging"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+	"github.com/petermazzocco/go-ecommerce-api/internal/notifications"
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+	"github.com/petermazzocco/go-ecommerce-api/internal/recovery"
+	"github.com/petermazzocco/go-ecommerce-api/internal/server"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tracing"
+	"github.com/petermazzocco/go-ecommerce-api/internal/webhooks"
 )
 
 func main() {
-	// Load ENV
-	if err := godotenv.Load(); err != nil {
-		fmt.Println("Failed to load local env")
+	// Load and check the config before starting anything
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
+	logger := logging.New(os.Stderr, cfg.Log)
+	slog.SetDefault(logger)
+	logger.Info("Starting", "env", cfg.Env)
 
-	// Start db
-	ctx := context.Background()
-	url := os.Getenv("DB_URL")
+	// Stop on SIGINT or SIGTERM, draining requests and workers first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	conn, err := pgx.Connect(ctx, url)
+	// Export spans over OTLP, flushing what's left on the way out
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer conn.Close(ctx)
-
-	// Chi routers
-	r := chi.NewRouter()
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-
-	r.Route("/api", func(r chi.Router) {
-		// Health check
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("Ecommerce API"))
-		})
-
-		// Portal login for admin users
-		r.Route("/auth", func(r chi.Router) {
-			r.Post("/login", func(w http.ResponseWriter, r *http.Request) {
-				handlers.LoginHandler(w, r, ctx, conn)
-			})
-			r.Post("/logout", func(w http.ResponseWriter, r *http.Request) {
-				handlers.LogoutHandler(w, r)
-			})
-		})
-
-		// Admin route group to require admin role
-		r.Route("/admin", func(r chi.Router) {
-			r.Use(auth.AdminMiddleware) // Require each route has a valid JWT with a maps claim to an admin role
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "text/plain")
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("Admin Portal"))
-			})
-			r.Route("/users", func(r chi.Router) {
-				r.Post("/register", func(w http.ResponseWriter, r *http.Request) {
-					handlers.RegisterAdminUserHandler(w, r, ctx, conn)
-				})
-				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.GetUserHandler(w, r, ctx, conn)
-					})
-					r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.DeleteUserHandler(w, r, ctx, conn)
-					})
-				})
-			})
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Warn("Error flushing traces", "err", err)
+		}
+	}()
+	// Trace every Stripe API call, including checkout
+	stripeClient := &http.Client{Transport: tracing.Transport(nil), Timeout: 80 * time.Second}
 
-			// Products route group to manage products
-			r.Route("/products", func(r chi.Router) {
-				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-					handlers.ListProductsHandler(w, r, ctx, conn)
-				})
-				r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-					handlers.CreateProductHandler(w, r, ctx, conn)
-				})
-				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.GetProductHandler(w, r, ctx, conn)
-					})
-					r.Put("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.UpdateProductHandler(w, r, ctx, conn)
-					})
-					r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.DeleteProductHandler(w, r, ctx, conn)
-					})
-				})
-			})
-
-			// Collections route group to manage collections
-			r.Route("/collections", func(r chi.Router) {
-				r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-					handlers.GetCollectionsHandler(w, r, ctx, conn)
-				})
-				r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-					handlers.CreateCollectionHandler(w, r, ctx, conn)
-				})
-				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.GetCollectionByIDHandler(w, r, ctx, conn)
-					})
-					r.Put("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.UpdateCollectionByIDHandler(w, r, ctx, conn)
-					})
-					r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-						handlers.DeleteCollectionByIDHandler(w, r, ctx, conn)
-					})
-					r.Route("/product", func(r chi.Router) {
-						// Add or remove products from a collection
-						r.Route("/{id}", func(r chi.Router) {
-							r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-								handlers.AddProductToCollectionHandler(w, r, ctx, conn)
-							})
-							r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-								handlers.RemoveProductFromCollectionHandler(w, r, ctx, conn)
-							})
-						})
-					})
-				})
-			})
-		})
-
-		// Public facing products route group to return product information
-		r.Route("/products", func(r chi.Router) {
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-				handlers.ListProductsHandler(w, r, ctx, conn)
-			})
-			r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
-				handlers.GetProductHandler(w, r, ctx, conn)
-			})
-		})
-
-		// Public facing collections route group to return collection information
-		r.Route("/collections", func(r chi.Router) {
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-				handlers.GetCollectionsHandler(w, r, ctx, conn)
-			})
-			r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
-				handlers.GetCollectionByIDHandler(w, r, ctx, conn)
-			})
-		})
+	// Start db
+	url := cfg.DatabaseURL
 
-		// Creates a new cart with a unique ID that is stored in a cookie with a JWT for authentication
-		r.Post("/new-cart", func(w http.ResponseWriter, r *http.Request) {
-			handlers.NewCartHandler(w, r, ctx, conn)
-		})
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	poolConfig.ConnConfig.Tracer = multitracer.New(metrics.QueryTracer{}, tracing.QueryTracer{})
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+	if err := metrics.RegisterPool(pool); err != nil {
+		log.Fatal(err)
+	}
 
-		// Cart route group requires a valid JWT and cart session ID
-		r.Route("/cart", func(r chi.Router) {
-			r.Use(auth.CartMiddleware) // Require each route has a valid JWT and cart session ID
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-				handlers.GetCartProductsHandler(w, r, ctx, conn)
-			})
-			r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-				handlers.ClearCartHandler(w, r, ctx, conn)
-			})
-			r.Post("/add", func(w http.ResponseWriter, r *http.Request) {
-				handlers.AddItemHandler(w, r, ctx, conn)
-			})
-			// Product ID in the cart to update quan or remove
-			r.Route("/{productID}", func(r chi.Router) {
-				r.Put("/", func(w http.ResponseWriter, r *http.Request) {
-					handlers.UpdateItemQuantityHandler(w, r, ctx, conn)
-				})
-				r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-					handlers.RemoveItemHandler(w, r, ctx, conn)
-				})
-			})
-			// Create a Stripe check out session
-			r.Post("/checkout", func(w http.ResponseWriter, r *http.Request) {
-				handlers.CreateCheckoutSession(w, r, ctx, conn)
-			})
-		})
+	// Email transport for the outbox dispatcher
+	notifications.SetStoreName(cfg.Email.StoreName)
+	transport, err := notifications.NewTransport(cfg.Email)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	})
+	// In-process subscribers for domain events
+	bus := events.NewBus()
+	bus.Subscribe("log", events.LogHandler)
+	bus.Subscribe("webhooks", webhooks.Subscriber)
+
+	cartGC := cleanup.NewWorker(cfg.Cleanup, pool)
+
+	// Handlers and workers share the pool, handlers use each request's context
+	app := handlers.NewApp(handlers.Config{
+		Auth:                cfg.Auth,
+		StripeWebhookSecret: cfg.StripeWebhookSecret,
+		IdempotencyTTL:      cfg.IdempotencyTTL,
+		CartRestoreURL:      cfg.Recovery.RedirectURL,
+		Metrics:             cfg.Metrics,
+		RateLimits:          cfg.RateLimits,
+		Lockout:             cfg.Lockout,
+		Tax:                 cfg.Tax,
+		Checkout:            cfg.Checkout,
+		StockLow:            cfg.StockLow,
+	}, pool, tracing.Payments(payments.NewStripe(cfg.StripeKey, stripeClient)), logger)
+	app.CartGC = cartGC
+
+	r, err := server.NewRouter(app)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	http.ListenAndServe(":8080", r)
+	srv := &server.Server{
+		Config:  cfg.Server,
+		Handler: r,
+		Workers: []server.Worker{
+			// Send queued emails from the outbox
+			notifications.NewDispatcher(transport, cfg.Email, pool),
+			// Deliver domain events from the outbox to in-process subscribers
+			events.NewDispatcher(bus, cfg.Events, pool),
+			// Post queued webhook deliveries to their endpoints
+			webhooks.NewWorker(cfg.Webhooks, pool),
+			// Remind customers about carts they left at checkout
+			recovery.NewWorker(cfg.Recovery, pool, recovery.EmailNotifier{}),
+			// Delete carts nobody has touched within the retention period
+			cartGC,
+		},
+		OnDrain: app.Health.Drain,
+	}
+	// Serve /metrics on its own port, kept up until the workers stop. The API
+	// stops if it can't listen.
+	if cfg.Metrics.Addr != "" {
+		srv.Listeners = append(srv.Listeners, metrics.NewServer(cfg.Metrics))
+	}
+	// Prune idle rate limit buckets kept in Postgres
+	if w, ok := app.Limits.(server.Worker); ok {
+		srv.Workers = append(srv.Workers, w)
+	}
+	// Forget failed logins older than the failure window
+	srv.Workers = append(srv.Workers, app.Lockout)
+	// Delete ended sessions and revocations of expired tokens
+	srv.Workers = append(srv.Workers, app.Auth)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
 }
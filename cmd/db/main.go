@@ -2,32 +2,46 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"flag"
 	"log"
 	"math/big"
-	"os"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/joho/godotenv"
+	ecommerce "github.com/petermazzocco/go-ecommerce-api"
+	"github.com/petermazzocco/go-ecommerce-api/internal/config"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 )
 
 func main() {
-	// Load ENV
-	if err := godotenv.Load(); err != nil {
-		fmt.Println("Failed to load local env")
+	seed := flag.Bool("seed", true, "create a test product after applying the schema")
+	flag.Parse()
+
+	// Only DB_URL is needed, read from the same config files as the API
+	url, err := config.DatabaseURL()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Start db
 	ctx := context.Background()
-	url := os.Getenv("DB_URL")
 
 	conn, err := pgx.Connect(ctx, url)
 	if err != nil {
 		log.Fatal(err)
 	}
 	log.Println("DB Running")
+	defer conn.Close(ctx)
+
+	// schema.sql only creates what is missing and upgrades older tables, so
+	// it is applied on every run
+	if _, err := conn.Exec(ctx, ecommerce.Schema); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Schema applied")
+	if !*seed {
+		return
+	}
 
 	// create a product in the db so we can test the get product endpoint
 	q := db.New(conn)
@@ -42,6 +56,4 @@ func main() {
 		log.Fatal(err)
 	}
 	log.Printf("Created product: %+v\n", product)
-
-	defer conn.Close(ctx)
 }
@@ -0,0 +1,146 @@
+// Package apperr is the error type shared by methods and handlers. Methods
+// return an *Error for anything a client should hear about (a missing
+// record, a failed validation, a conflicting state) and Write turns any error
+// into a JSON response with the matching status code. Errors that are not an
+// *Error are treated as internal and never shown to clients.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies the kind of error in response bodies
+type Code string
+
+const (
+	CodeNotFound     Code = "not_found"
+	CodeValidation   Code = "validation_error"
+	CodeConflict     Code = "conflict"
+	CodeUnauthorized Code = "unauthorized"
+	CodeRateLimited  Code = "rate_limited"
+	CodeInternal     Code = "internal_error"
+)
+
+// FieldError describes why one request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Message string // shown to clients
+	Fields  []FieldError
+	Status  int   // overrides the code's default status when set
+	Err     error // cause, logged but never shown to clients
+
+	parent *Error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is lets errors.Is match the error a Withf copy was made from
+func (e *Error) Is(target error) bool {
+	for p := e.parent; p != nil; p = p.parent {
+		if p == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Withf returns a copy of e with detail appended to its message.
+// errors.Is(copy, e) stays true.
+func (e *Error) Withf(format string, args ...any) *Error {
+	return &Error{
+		Code:    e.Code,
+		Message: e.Message + ": " + fmt.Sprintf(format, args...),
+		Fields:  e.Fields,
+		Status:  e.Status,
+		Err:     e.Err,
+		parent:  e,
+	}
+}
+
+// HTTPStatus is the response status for the error
+func (e *Error) HTTPStatus() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	switch e.Code {
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeValidation:
+		return http.StatusBadRequest
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: message, Fields: fields}
+}
+
+// Invalid is a validation error for a single field
+func Invalid(field, message string) *Error {
+	return Validation(message, Field(field, message))
+}
+
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+// RateLimited tells a client to slow down. Set Retry-After before writing it.
+func RateLimited(message string) *Error {
+	return &Error{Code: CodeRateLimited, Message: message}
+}
+
+// Internal is a server side failure. message is shown to clients, so keep
+// the details in the log.
+func Internal(message string) *Error {
+	return &Error{Code: CodeInternal, Message: message}
+}
+
+func Field(field, message string) FieldError {
+	return FieldError{Field: field, Message: message}
+}
+
+// From returns the *Error in err's chain, or a generic internal error
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Code: CodeInternal, Message: "An unknown error occurred", Err: err}
+}
+
+// Is reports whether err is an *Error with the given code
+func Is(err error, code Code) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
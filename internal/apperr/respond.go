@@ -0,0 +1,45 @@
+package apperr
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Body is the JSON envelope every error response uses
+type Body struct {
+	Error BodyError `json:"error"`
+}
+
+type BodyError struct {
+	Code      Code         `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// Write sends err as a JSON error response. Internal errors are logged with
+// the request ID so the response can be matched to the log.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	requestID := middleware.GetReqID(r.Context())
+
+	status := e.HTTPStatus()
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "Internal error", "method", r.Method, "path", r.URL.Path, "err", err)
+	}
+
+	j, _ := json.Marshal(Body{Error: BodyError{
+		Code:      e.Code,
+		Message:   e.Message,
+		Fields:    e.Fields,
+		RequestID: requestID,
+	}})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(j)
+}
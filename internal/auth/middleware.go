@@ -1,184 +1,236 @@
 package auth
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 )
 
-func CreateJWT(w http.ResponseWriter, r *http.Request, c db.Cart) (string, error) {
-	key := os.Getenv("JWT_KEY")
-	if key == "" {
-		log.Println("JWT_KEY environment variable is not set")
-		return "", fmt.Errorf("Permission denied")
-	}
+var ErrPermissionDenied = apperr.Unauthorized("Permission denied")
+
+// Config is how sessions are signed and which cookies carry them
+type Config struct {
+	JWTKey          string
+	CookieName      string // cart session
+	AdminCookieName string
+	SecureCookies   bool          // only send the cookies over HTTPS
+	AccessTTL       time.Duration // lifetime of an access token
+	CartSessionTTL  time.Duration // how long a cart session lasts without a refresh
+	AdminSessionTTL time.Duration // how long an admin session lasts without a refresh
+	RequireMFA      bool          // admins must set up two-factor authentication to log in
+	MFAIssuer       string        // name shown in authenticator apps
+}
+
+const (
+	DefaultAccessTTL       = 15 * time.Minute
+	DefaultCartSessionTTL  = 24 * time.Hour
+	DefaultAdminSessionTTL = 24 * time.Hour
+)
 
-	cookieName := os.Getenv("COOKIE_NAME")
-	claims := jwt.MapClaims{
-		"expiresAt": time.Now().Add(24 * time.Hour),
-		"cartID":    c.ID, // pass the cart ID
+// MFAChallengeTTL is how long a password login waits for its two-factor code
+const MFAChallengeTTL = 5 * time.Minute
+
+// Sessions signs and checks the cart and admin session cookies. Access
+// tokens are short-lived JWTs; the sessions behind them are stored with a
+// refresh token that is rotated each time it's used.
+type Sessions struct {
+	Config Config
+	Store  db.Store // sessions, revoked tokens and the user behind an admin session
+}
+
+func NewSessions(config Config, store db.Store) *Sessions {
+	if config.AccessTTL <= 0 {
+		config.AccessTTL = DefaultAccessTTL
+	}
+	if config.CartSessionTTL <= 0 {
+		config.CartSessionTTL = DefaultCartSessionTTL
 	}
+	if config.AdminSessionTTL <= 0 {
+		config.AdminSessionTTL = DefaultAdminSessionTTL
+	}
+	return &Sessions{Config: config, Store: store}
+}
+
+// CreateJWT starts a session for the cart and sets its cookies
+func (s *Sessions) CreateJWT(w http.ResponseWriter, r *http.Request, c db.Cart) (string, error) {
+	return s.startSession(w, r, &Claims{Type: TypeCart, CartID: c.ID.String()})
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// CreateAdminJWT starts a session for the admin and sets its cookies
+func (s *Sessions) CreateAdminJWT(w http.ResponseWriter, r *http.Request, u db.User) (string, error) {
+	return s.startSession(w, r, &Claims{Type: TypeAdmin, UserID: u.ID})
+}
+
+// ClearAdminCookie ends the admin session in the browser. Logout revokes it
+// as well.
+func (s *Sessions) ClearAdminCookie(w http.ResponseWriter) {
+	s.clearCookies(w, s.kind(TypeAdmin))
+}
 
-	ss, err := token.SignedString([]byte(key))
+// ClearCartCookie ends the cart session in the browser, e.g. once the cart
+// is cleared
+func (s *Sessions) ClearCartCookie(w http.ResponseWriter) {
+	s.clearCookies(w, s.kind(TypeCart))
+}
+
+// mfaCookieName holds the challenge between the password and the
+// two-factor code
+func (s *Sessions) mfaCookieName() string {
+	return s.Config.AdminCookieName + "-mfa"
+}
+
+// CreateMFAChallenge remembers that userID got their password right and
+// still owes a two-factor code. setup marks an admin who has to set up
+// two-factor authentication before getting a session.
+func (s *Sessions) CreateMFAChallenge(w http.ResponseWriter, r *http.Request, userID int32, setup bool) error {
+	now := time.Now()
+	c := &Claims{Type: TypeMFA, UserID: userID, MFASetup: setup}
+	stamp(c, now, MFAChallengeTTL)
+
+	ss, err := s.sign(c)
 	if err != nil {
-		log.Println("ERROR SIGNING TOKEN: ", err.Error())
-		return "", fmt.Errorf("Permission denied")
+		slog.ErrorContext(r.Context(), "Error signing token", "err", err)
+		return apperr.Internal("Error creating session")
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     cookieName,
+		Name:     s.mfaCookieName(),
 		Value:    ss,
+		Path:     "/",
 		HttpOnly: true,
-		Secure:   false,
-		Expires:  time.Now().Add(24 * time.Hour),
-		MaxAge:   int(24 * time.Hour),
-		SameSite: http.SameSiteLaxMode,
+		Secure:   s.Config.SecureCookies,
+		Expires:  now.Add(MFAChallengeTTL),
+		MaxAge:   int(MFAChallengeTTL.Seconds()),
+		SameSite: http.SameSiteStrictMode,
 	})
-
-	return ss, nil
+	return nil
 }
 
-func CreateAdminJWT(w http.ResponseWriter, r *http.Request, u db.User) (string, error) {
-	key := os.Getenv("JWT_KEY")
-	if key == "" {
-		log.Println("JWT_KEY environment variable is not set")
-		return "", fmt.Errorf("Permission denied")
+// MFAChallenge returns the user waiting on a two-factor code, and whether
+// they still have to set it up
+func (s *Sessions) MFAChallenge(r *http.Request) (userID int32, setup bool, err error) {
+	c, err := s.claims(r, TypeMFA, s.mfaCookieName())
+	if err != nil || c.UserID == 0 {
+		return 0, false, ErrPermissionDenied
 	}
-	cookieName := os.Getenv("ADMIN_COOKIE_NAME")
-	claims := jwt.MapClaims{
-		"expiresAt": time.Now().Add(24 * time.Hour),
-		"userID":    u.ID, // pass the user ID
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return c.UserID, c.MFASetup, nil
+}
 
-	ss, err := token.SignedString([]byte(key))
-	if err != nil {
-		log.Println("ERROR SIGNING TOKEN: ", err.Error())
-		return "", fmt.Errorf("Permission denied")
+// ClearMFAChallenge drops the challenge once it's been answered, revoking
+// it so it can't be answered again
+func (s *Sessions) ClearMFAChallenge(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(s.mfaCookieName()); err == nil {
+		if c, err := s.parse(r.Context(), cookie.Value, TypeMFA); err == nil {
+			err := db.New(s.Store).RevokeToken(r.Context(), db.RevokeTokenParams{
+				Jti:       c.ID,
+				ExpiresAt: pgtype.Timestamptz{Time: c.ExpiresAt.Time, Valid: true},
+			})
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Error revoking two-factor challenge", "err", err)
+			}
+		}
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     cookieName,
-		Value:    ss,
+		Name:     s.mfaCookieName(),
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
 		HttpOnly: true,
-		Secure:   false,
-		Expires:  time.Now().Add(24 * time.Hour),
-		MaxAge:   int(24 * time.Hour),
-		SameSite: http.SameSiteLaxMode,
+		Secure:   s.Config.SecureCookies,
 	})
-
-	return ss, nil
 }
 
-func ValidateJWT(tokenString string, r *http.Request) error {
-	key := os.Getenv("JWT_KEY")
+func (s *Sessions) ValidateJWT(ctx context.Context, tokenString string) error {
+	if _, err := s.parse(ctx, tokenString, TypeCart); err != nil {
+		slog.WarnContext(ctx, "Invalid session token", "err", err)
+		return ErrPermissionDenied
+	}
 
-	_, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		return []byte(key), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	return nil
+}
 
+func (s *Sessions) ValidateAdminJWT(ctx context.Context, tokenString string) error {
+	c, err := s.parse(ctx, tokenString, TypeAdmin)
 	if err != nil {
-		log.Println("ERROR PARSING TOKEN: ", err.Error())
-		return fmt.Errorf("Permission denied")
-
+		slog.WarnContext(ctx, "Invalid admin session token", "err", err)
+		return ErrPermissionDenied
 	}
+	return s.checkAdmin(ctx, c.UserID)
+}
 
+// checkAdmin makes sure the user behind an admin session is still an admin
+func (s *Sessions) checkAdmin(ctx context.Context, id int32) error {
+	user, err := db.New(s.Store).GetUser(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting user", "err", err)
+		return ErrPermissionDenied
+	}
+	if user.ID == 0 {
+		return ErrPermissionDenied
+	}
+	isAdmin := user.IsAdmin == pgtype.Bool{Bool: true, Valid: true}
+	if !isAdmin {
+		return ErrPermissionDenied
+	}
 	return nil
 }
 
-func ValidateAdminJWT(tokenString string, r *http.Request) error {
-	key := os.Getenv("JWT_KEY")
-	url := os.Getenv("DB_URL")
-
-	conn, err := pgx.Connect(r.Context(), url)
+// CartID returns the cart ID stored in the cart JWT
+func (s *Sessions) CartID(r *http.Request) (string, error) {
+	c, err := s.claims(r, TypeCart, s.Config.CookieName)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
+	if c.CartID == "" {
+		return "", ErrPermissionDenied
+	}
+	return c.CartID, nil
+}
 
-	defer conn.Close(r.Context())
-	q := db.New(conn)
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		return []byte(key), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
-
+// AdminID returns the user ID stored in the admin JWT
+func (s *Sessions) AdminID(r *http.Request) (int32, error) {
+	c, err := s.claims(r, TypeAdmin, s.Config.AdminCookieName)
 	if err != nil {
-		log.Println("ERROR PARSING TOKEN: ", err.Error())
-		return fmt.Errorf("Permission denied")
-
+		return 0, err
 	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		userID := claims["userID"]
-		if userID != nil {
-			id := userID.(float64)
-			user, err := q.GetUser(r.Context(), int32(id))
-			if err != nil {
-				log.Println("ERROR GETTING USER: ", err.Error())
-				return fmt.Errorf("Permission denied")
-			}
-			if user.ID == 0 {
-				return fmt.Errorf("Permission denied")
-			}
-			isAdmin := user.IsAdmin == pgtype.Bool{Bool: true, Valid: true}
-			if !isAdmin {
-				return fmt.Errorf("Permission denied")
-			}
-		}
+	if c.UserID == 0 {
+		return 0, ErrPermissionDenied
 	}
-	return nil
+	return c.UserID, nil
 }
-func CartMiddleware(next http.Handler) http.Handler {
+
+func (s *Sessions) CartMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookieName := os.Getenv("COOKIE_NAME")
-		// Get the token from the Authorization header
-		token, err := r.Cookie(cookieName)
+		// Check the token from the cart cookie once and keep it for the handler
+		c, err := s.claims(r, TypeCart, s.Config.CookieName)
 		if err != nil {
-			log.Println("COOKIE ERROR: ", err.Error())
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Permission denied"))
+			apperr.Write(w, r, ErrPermissionDenied)
 			return
 		}
 
-		if err := ValidateJWT(token.Value, r); err != nil {
-			log.Println("VALIDATE JWT ERROR: ", err.Error())
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Permission denied"))
-			return
-		}
-
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withClaims(r, c))
 	})
 }
 
-func AdminMiddleware(next http.Handler) http.Handler {
+func (s *Sessions) AdminMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookieName := os.Getenv("ADMIN_COOKIE_NAME")
-		token, err := r.Cookie(cookieName)
+		c, err := s.claims(r, TypeAdmin, s.Config.AdminCookieName)
 		if err != nil {
-			log.Println("ADMIN COOKIE ERROR: ", err.Error())
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Permission denied"))
+			apperr.Write(w, r, ErrPermissionDenied)
 			return
 		}
-
-		if err := ValidateAdminJWT(token.Value, r); err != nil {
-			log.Println("ADMIN VALIDATE JWT ERROR: ", err.Error())
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte("Permission denied"))
+		if err := s.checkAdmin(r.Context(), c.UserID); err != nil {
+			apperr.Write(w, r, ErrPermissionDenied)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withClaims(r, c))
 	})
 }
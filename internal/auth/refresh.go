@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Where the refresh cookies are sent. The admin one also reaches logout so
+// it can be revoked there.
+const (
+	CartRefreshPath  = "/api/cart/refresh"
+	AdminRefreshPath = "/api/auth"
+)
+
+const (
+	// ReuseGrace is how soon after a refresh the token before it may show up
+	// again without ending the session, for two tabs refreshing at once
+	ReuseGrace = 10 * time.Second
+
+	// PruneInterval is how often ended sessions and expired revocations
+	// are deleted
+	PruneInterval = time.Hour
+)
+
+var (
+	ErrSessionExpired = apperr.Unauthorized("Session expired, start a new one")
+	ErrRefreshReused  = apperr.Unauthorized("Session ended, start a new one")
+	// ErrRefreshRaced means another request refreshed the session within
+	// ReuseGrace. Its new cookies are left in place, so the client should
+	// retry with them.
+	ErrRefreshRaced = apperr.Conflict("Session was just refreshed, retry the request")
+)
+
+// sessionKind is where a session's cookies go and how long it lasts
+type sessionKind struct {
+	cookie      string
+	refreshPath string
+	ttl         time.Duration
+}
+
+func (s *Sessions) kind(typ string) sessionKind {
+	if typ == TypeAdmin {
+		return sessionKind{s.Config.AdminCookieName, AdminRefreshPath, s.Config.AdminSessionTTL}
+	}
+	return sessionKind{s.Config.CookieName, CartRefreshPath, s.Config.CartSessionTTL}
+}
+
+func refreshCookieName(cookie string) string {
+	return cookie + "-refresh"
+}
+
+// startSession stores a new session for the claims' cart or user and sets
+// its access and refresh cookies
+func (s *Sessions) startSession(w http.ResponseWriter, r *http.Request, c *Claims) (string, error) {
+	ctx := r.Context()
+	k := s.kind(c.Type)
+	now := time.Now()
+
+	id := uuid.New()
+	c.SessionID = id.String()
+	stamp(c, now, s.Config.AccessTTL)
+	ss, err := s.sign(c)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error signing token", "err", err)
+		return "", apperr.Internal("Error creating session")
+	}
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating refresh token", "err", err)
+		return "", apperr.Internal("Error creating session")
+	}
+
+	params := db.CreateSessionParams{
+		ID:              pgtype.UUID{Bytes: id, Valid: true},
+		Kind:            c.Type,
+		UserID:          pgtype.Int4{Int32: c.UserID, Valid: c.UserID != 0},
+		RefreshHash:     hash,
+		AccessJti:       c.ID,
+		AccessExpiresAt: pgtype.Timestamptz{Time: c.ExpiresAt.Time, Valid: true},
+		ExpiresAt:       pgtype.Timestamptz{Time: now.Add(k.ttl), Valid: true},
+	}
+	if c.CartID != "" {
+		if err := params.CartID.Scan(c.CartID); err != nil {
+			return "", apperr.Internal("Error creating session")
+		}
+	}
+	if err := db.New(s.Store).CreateSession(ctx, params); err != nil {
+		slog.ErrorContext(ctx, "Error storing session", "err", err)
+		return "", apperr.Internal("Error creating session")
+	}
+
+	s.setCookies(w, k, ss, refresh, now)
+	return ss, nil
+}
+
+// Refresh swaps the refresh cookie of a cart or admin session for a new
+// access token and refresh token. The old access token is revoked. A
+// refresh token that was already swapped means someone kept a copy, so the
+// session is ended for everyone and ErrRefreshReused is returned with the
+// session it ended. Within ReuseGrace of the swap it is another tab
+// refreshing at the same time and ErrRefreshRaced is returned instead.
+func (s *Sessions) Refresh(w http.ResponseWriter, r *http.Request, typ string) (db.Session, error) {
+	ctx := r.Context()
+	k := s.kind(typ)
+
+	cookie, err := r.Cookie(refreshCookieName(k.cookie))
+	if err != nil {
+		return db.Session{}, ErrSessionExpired
+	}
+	hash := hashToken(cookie.Value)
+
+	now := time.Now()
+	refresh, newHash, err := newRefreshToken()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error creating refresh token", "err", err)
+		return db.Session{}, apperr.Internal("Error refreshing session")
+	}
+	c := &Claims{Type: typ}
+	stamp(c, now, s.Config.AccessTTL)
+
+	q := db.New(s.Store)
+	session, err := q.RotateSession(ctx, db.RotateSessionParams{
+		RefreshHash:     hash,
+		Kind:            typ,
+		NewRefreshHash:  newHash,
+		AccessJti:       c.ID,
+		AccessExpiresAt: pgtype.Timestamptz{Time: c.ExpiresAt.Time, Valid: true},
+		ExpiresAt:       pgtype.Timestamptz{Time: now.Add(k.ttl), Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.refreshFailed(ctx, w, k, hash)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error rotating session", "err", err)
+		return db.Session{}, apperr.Internal("Error refreshing session")
+	}
+
+	c.SessionID = session.ID.String()
+	if session.CartID.Valid {
+		c.CartID = session.CartID.String()
+	}
+	c.UserID = session.UserID.Int32
+	ss, err := s.sign(c)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error signing token", "err", err)
+		return db.Session{}, apperr.Internal("Error refreshing session")
+	}
+
+	s.setCookies(w, k, ss, refresh, now)
+	return session, nil
+}
+
+// refreshFailed handles a refresh token that matches no live session. The
+// cookies are only cleared when the session is over; within the grace window
+// the request raced another refresh whose cookies must survive.
+func (s *Sessions) refreshFailed(ctx context.Context, w http.ResponseWriter, k sessionKind, hash string) (db.Session, error) {
+	q := db.New(s.Store)
+	previous := pgtype.Text{String: hash, Valid: true}
+
+	reused, err := q.RevokeReusedSession(ctx, db.RevokeReusedSessionParams{
+		PreviousHash: previous,
+		GraceSeconds: ReuseGrace.Seconds(),
+	})
+	if err == nil {
+		s.clearCookies(w, k)
+		slog.WarnContext(ctx, "Refresh token reused, session revoked", "session", reused.ID.String(), "kind", reused.Kind)
+		return db.Session{ID: reused.ID, Kind: reused.Kind, UserID: reused.UserID}, ErrRefreshReused
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.ErrorContext(ctx, "Error checking refresh token reuse", "err", err)
+		return db.Session{}, apperr.Internal("Error refreshing session")
+	}
+
+	raced, err := q.SessionRefreshedWithin(ctx, db.SessionRefreshedWithinParams{
+		PreviousHash: previous,
+		GraceSeconds: ReuseGrace.Seconds(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error checking concurrent refresh", "err", err)
+		return db.Session{}, apperr.Internal("Error refreshing session")
+	}
+	if raced {
+		return db.Session{}, ErrRefreshRaced
+	}
+
+	s.clearCookies(w, k)
+	return db.Session{}, ErrSessionExpired
+}
+
+// Logout ends the admin session behind the request's cookies, revoking its
+// refresh token and access token, and clears the cookies
+func (s *Sessions) Logout(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	k := s.kind(TypeAdmin)
+	defer s.clearCookies(w, k)
+
+	q := db.New(s.Store)
+	var params db.RevokeSessionParams
+	if c, err := s.claims(r, TypeAdmin, k.cookie); err == nil {
+		params.ID.Scan(c.SessionID)
+		err := q.RevokeToken(ctx, db.RevokeTokenParams{
+			Jti:       c.ID,
+			ExpiresAt: pgtype.Timestamptz{Time: c.ExpiresAt.Time, Valid: true},
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Error revoking token", "err", err)
+			return apperr.Internal("Error logging out")
+		}
+	}
+	if cookie, err := r.Cookie(refreshCookieName(k.cookie)); err == nil {
+		params.RefreshHash = hashToken(cookie.Value)
+	}
+	if !params.ID.Valid && params.RefreshHash == "" {
+		return nil
+	}
+
+	if _, err := q.RevokeSession(ctx, params); err != nil {
+		slog.ErrorContext(ctx, "Error revoking session", "err", err)
+		return apperr.Internal("Error logging out")
+	}
+	return nil
+}
+
+// Run deletes ended sessions and revocations of expired tokens every
+// PruneInterval until ctx is cancelled
+func (s *Sessions) Run(ctx context.Context) {
+	ticker := time.NewTicker(PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		q := db.New(s.Store)
+		sessions, err := q.DeleteExpiredSessions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Session prune error", "err", err)
+			continue
+		}
+		tokens, err := q.DeleteExpiredRevokedTokens(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Revoked token prune error", "err", err)
+			continue
+		}
+		if sessions > 0 || tokens > 0 {
+			slog.DebugContext(ctx, "Pruned sessions", "sessions", sessions, "revoked_tokens", tokens)
+		}
+	}
+}
+
+func (s *Sessions) setCookies(w http.ResponseWriter, k sessionKind, access, refresh string, now time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     k.cookie,
+		Value:    access,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.Config.SecureCookies,
+		Expires:  now.Add(s.Config.AccessTTL),
+		MaxAge:   int(s.Config.AccessTTL.Seconds()),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName(k.cookie),
+		Value:    refresh,
+		Path:     k.refreshPath,
+		HttpOnly: true,
+		Secure:   s.Config.SecureCookies,
+		Expires:  now.Add(k.ttl),
+		MaxAge:   int(k.ttl.Seconds()),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (s *Sessions) clearCookies(w http.ResponseWriter, k sessionKind) {
+	for _, c := range []struct{ name, path string }{
+		{k.cookie, "/"},
+		{refreshCookieName(k.cookie), k.refreshPath},
+	} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.name,
+			Value:    "",
+			Path:     c.path,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   s.Config.SecureCookies,
+		})
+	}
+}
+
+// newRefreshToken returns 256 random bits for the cookie and the hash that
+// is stored
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Token types. Every token is signed with the same key, so the type keeps a
+// cart session from passing as an admin one and the other way round.
+const (
+	TypeCart  = "cart"
+	TypeAdmin = "admin"
+	TypeMFA   = "mfa" // two-factor challenge between the password and the code
+)
+
+var (
+	errTokenType = errors.New("wrong token type")
+	errRevoked   = errors.New("token revoked")
+)
+
+// Claims are the access token's claims: the standard exp, iat and jti, the
+// session it belongs to and who it's for
+type Claims struct {
+	Type      string `json:"type"`
+	SessionID string `json:"sid,omitempty"`
+	CartID    string `json:"cartID,omitempty"`
+	UserID    int32  `json:"userID,omitempty"`
+	MFASetup  bool   `json:"mfaSetup,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// stamp gives the claims a new ID, the issue time and the expiry
+func stamp(c *Claims, now time.Time, ttl time.Duration) {
+	c.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+func (s *Sessions) sign(c *Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(s.Config.JWTKey))
+}
+
+// parse checks the signature, expiry, type and revocation of a token
+func (s *Sessions) parse(ctx context.Context, tokenString, typ string) (*Claims, error) {
+	c := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, c, func(token *jwt.Token) (any, error) {
+		return []byte(s.Config.JWTKey), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if c.Type != typ || c.ID == "" {
+		return nil, errTokenType
+	}
+
+	revoked, err := db.New(s.Store).IsTokenRevoked(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errRevoked
+	}
+	return c, nil
+}
+
+type contextKey string
+
+// claims returns the request's token of type typ, from the context when a
+// middleware already checked it, or else from its cookie
+func (s *Sessions) claims(r *http.Request, typ, cookieName string) (*Claims, error) {
+	if c, ok := r.Context().Value(contextKey(typ)).(*Claims); ok {
+		return c, nil
+	}
+
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Missing session cookie", "type", typ, "err", err)
+		return nil, ErrPermissionDenied
+	}
+
+	c, err := s.parse(r.Context(), cookie.Value, typ)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Invalid session token", "type", typ, "err", err)
+		return nil, ErrPermissionDenied
+	}
+	return c, nil
+}
+
+// withClaims keeps checked claims on the request for the handlers
+func withClaims(r *http.Request, c *Claims) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKey(c.Type), c))
+}
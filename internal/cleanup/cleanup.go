@@ -0,0 +1,136 @@
+// Package cleanup removes carts nobody has touched within the retention
+// period, unless a recovery link for them is still valid, together with
+// expired idempotency keys. Carts are deleted in small
+// batches so a large backlog never holds long locks on the carts table.
+package cleanup
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+)
+
+const (
+	DefaultInterval  = time.Hour
+	DefaultRetention = 30 * 24 * time.Hour
+	DefaultBatchSize = 500
+
+	// MinRetention outlives the 24 hour cart cookie and Stripe checkout sessions
+	MinRetention = 24 * time.Hour
+)
+
+type Config struct {
+	Interval  time.Duration // zero disables the worker
+	Retention time.Duration
+	BatchSize int
+	// LinkTTL keeps carts whose recovery link is still valid
+	LinkTTL time.Duration
+}
+
+// Stats are running totals since the process started
+type Stats struct {
+	Runs          int64     `json:"runs"`
+	Failures      int64     `json:"failures"`
+	CartsPurged   int64     `json:"cartsPurged"`
+	ItemsPurged   int64     `json:"itemsPurged"`
+	KeysPurged    int64     `json:"idempotencyKeysPurged"`
+	LastRun       time.Time `json:"lastRun"`
+	LastDuration  string    `json:"lastDuration"`
+	LastError     string    `json:"lastError,omitempty"`
+	RetentionDays float64   `json:"retentionDays"`
+}
+
+// Worker purges expired carts every Interval
+type Worker struct {
+	Config Config
+	Store  db.Store
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+func NewWorker(config Config, store db.Store) *Worker {
+	return &Worker{Config: config, Store: store}
+}
+
+// Stats returns a copy of the totals so far
+func (w *Worker) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.stats
+	s.RetentionDays = w.Config.Retention.Hours() / 24
+	return s
+}
+
+// Run purges until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	if w.Config.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes every expired cart in batches of BatchSize and records the
+// result in Stats
+func (w *Worker) RunOnce(ctx context.Context) {
+	start := time.Now()
+	carts, items, keys, err := w.purge(ctx, start.Add(-w.Config.Retention), start.Add(-w.Config.LinkTTL))
+
+	w.mu.Lock()
+	w.stats.Runs++
+	w.stats.CartsPurged += carts
+	w.stats.ItemsPurged += items
+	w.stats.KeysPurged += keys
+	w.stats.LastRun = start
+	w.stats.LastDuration = time.Since(start).String()
+	w.stats.LastError = ""
+	if err != nil {
+		w.stats.Failures++
+		w.stats.LastError = err.Error()
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Cart GC error", "err", err)
+	}
+	if carts > 0 || keys > 0 {
+		slog.InfoContext(ctx, "Cart GC purged expired carts", "carts", carts, "cart_items", items, "idempotency_keys", keys)
+	}
+}
+
+func (w *Worker) purge(ctx context.Context, before, linksAfter time.Time) (carts, items, keys int64, err error) {
+	keys, err = methods.PurgeExpiredIdempotencyKeys(ctx, w.Store)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	for ctx.Err() == nil {
+		c, i, err := methods.PurgeExpiredCarts(ctx, w.Store, before, linksAfter, w.Config.BatchSize)
+		if err != nil {
+			return carts, items, keys, err
+		}
+		carts += c
+		items += i
+		if c < int64(w.Config.BatchSize) {
+			break
+		}
+	}
+	return carts, items, keys, ctx.Err()
+}
@@ -0,0 +1,423 @@
+// Package config loads the API's settings at startup. Values come from the
+// process environment, .env files and an optional YAML file, picked by the
+// APP_ENV profile, and are checked before anything starts so a bad deploy
+// fails with every problem listed instead of on the first request.
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/cleanup"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+	"github.com/petermazzocco/go-ecommerce-api/internal/idempotency"
+	"github.com/petermazzocco/go-ecommerce-api/internal/lockout"
+	"github.com/petermazzocco/go-ecommerce-api/internal/logging"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+	"github.com/petermazzocco/go-ecommerce-api/internal/notifications"
+	"github.com/petermazzocco/go-ecommerce-api/internal/ratelimit"
+	"github.com/petermazzocco/go-ecommerce-api/internal/recovery"
+	"github.com/petermazzocco/go-ecommerce-api/internal/server"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tax"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tracing"
+	"github.com/petermazzocco/go-ecommerce-api/internal/webhooks"
+)
+
+// Profiles
+const (
+	Development = "development"
+	Test        = "test"
+	Production  = "production"
+)
+
+// MinProductionKeyLength is the shortest JWT_KEY accepted in production
+const MinProductionKeyLength = 32
+
+// Config holds every setting main passes to the subsystems it starts
+type Config struct {
+	Env                 string // APP_ENV profile
+	DatabaseURL         string
+	StripeKey           string
+	StripeWebhookSecret string
+	Auth                auth.Config
+	Log                 logging.Config
+	Metrics             metrics.Config
+	Tracing             tracing.Config
+	Server              server.Config
+	Recovery            recovery.Config
+	Cleanup             cleanup.Config
+	RateLimits          ratelimit.Config
+	Lockout             lockout.Config
+	IdempotencyTTL      time.Duration
+	Email               notifications.Config
+	Events              events.Config
+	Webhooks            webhooks.Config
+	Tax                 methods.TaxConfig
+	Checkout            methods.CheckoutConfig
+	StockLow            int32 // stock at or below which a size raises StockLow
+}
+
+// Error lists every problem found so they can be fixed in one go
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// Load reads the configuration files, builds the Config and validates it.
+// It is the only place settings are read, every subsystem is handed its part.
+func Load() (Config, error) {
+	e, err := loadEnv()
+	if err != nil {
+		return Config{}, err
+	}
+
+	c := Config{
+		Env:                 e.profile,
+		DatabaseURL:         e.get("DB_URL"),
+		StripeKey:           e.get("STRIPE_KEY"),
+		StripeWebhookSecret: e.get("STRIPE_WEBHOOK_SECRET"),
+		Auth: auth.Config{
+			JWTKey:          e.get("JWT_KEY"),
+			CookieName:      e.get("COOKIE_NAME"),
+			AdminCookieName: e.get("ADMIN_COOKIE_NAME"),
+			SecureCookies:   e.boolean("COOKIE_SECURE", e.profile == Production),
+			AccessTTL:       e.duration("ACCESS_TOKEN_TTL", auth.DefaultAccessTTL),
+			CartSessionTTL:  e.duration("CART_SESSION_TTL", auth.DefaultCartSessionTTL),
+			AdminSessionTTL: e.duration("ADMIN_SESSION_TTL", auth.DefaultAdminSessionTTL),
+			RequireMFA:      e.boolean("MFA_REQUIRED", false),
+		},
+		Log:     loadLogging(e),
+		Metrics: metrics.Config{Addr: e.get("METRICS_ADDR"), Token: e.get("METRICS_TOKEN")},
+		Tracing: loadTracing(e),
+		Server: server.Config{
+			Addr:              e.str("SERVER_ADDR", server.DefaultAddr),
+			ReadHeaderTimeout: e.duration("HTTP_READ_HEADER_TIMEOUT", server.DefaultReadHeaderTimeout),
+			ReadTimeout:       e.duration("HTTP_READ_TIMEOUT", server.DefaultReadTimeout),
+			WriteTimeout:      e.duration("HTTP_WRITE_TIMEOUT", server.DefaultWriteTimeout),
+			IdleTimeout:       e.duration("HTTP_IDLE_TIMEOUT", server.DefaultIdleTimeout),
+			ShutdownTimeout:   e.duration("SHUTDOWN_TIMEOUT", server.DefaultShutdownTimeout),
+			DrainDelay:        e.duration("SHUTDOWN_DRAIN_DELAY", 0),
+		},
+		Recovery: recovery.Config{
+			Interval:    e.interval("CART_RECOVERY_INTERVAL", recovery.DefaultInterval),
+			Idle:        e.duration("CART_RECOVERY_IDLE", recovery.DefaultIdle),
+			MaxAge:      e.duration("CART_RECOVERY_MAX_AGE", recovery.DefaultMaxAge),
+			LinkTTL:     e.duration("CART_RECOVERY_LINK_TTL", recovery.DefaultLinkTTL),
+			BatchSize:   e.integer("CART_RECOVERY_BATCH_SIZE", recovery.DefaultBatchSize, 1),
+			BaseURL:     e.str("CART_RECOVERY_BASE_URL", recovery.DefaultBaseURL),
+			RedirectURL: e.str("CART_RECOVERY_REDIRECT_URL", e.get("CHECKOUT_CANCEL_URL")),
+		},
+		Cleanup: cleanup.Config{
+			Interval:  e.interval("CART_GC_INTERVAL", cleanup.DefaultInterval),
+			Retention: e.duration("CART_RETENTION", cleanup.DefaultRetention),
+			BatchSize: e.integer("CART_GC_BATCH_SIZE", cleanup.DefaultBatchSize, 1),
+		},
+		RateLimits: loadRateLimits(e),
+		Lockout: lockout.Config{
+			FreeAttempts:  e.integer("LOGIN_FREE_ATTEMPTS", lockout.DefaultFreeAttempts, 0),
+			Delay:         e.duration("LOGIN_DELAY", lockout.DefaultDelay),
+			MaxAttempts:   e.integer("LOGIN_MAX_ATTEMPTS", lockout.DefaultMaxAttempts, 1),
+			IPMaxAttempts: e.integer("LOGIN_IP_MAX_ATTEMPTS", lockout.DefaultIPMaxAttempts, 1),
+			Lockout:       e.duration("LOGIN_LOCKOUT", lockout.DefaultLockout),
+			Window:        e.duration("LOGIN_FAILURE_WINDOW", lockout.DefaultWindow),
+		},
+		IdempotencyTTL: e.duration("IDEMPOTENCY_TTL", idempotency.DefaultTTL),
+		Email: notifications.Config{
+			StoreName: e.str("STORE_NAME", notifications.DefaultStoreName),
+			Transport: strings.ToLower(e.str("EMAIL_TRANSPORT", notifications.TransportLog)),
+			SMTP: notifications.SMTPTransport{
+				Host:     e.get("SMTP_HOST"),
+				Port:     e.integer("SMTP_PORT", notifications.DefaultSMTPPort, 1),
+				Username: e.get("SMTP_USERNAME"),
+				Password: e.get("SMTP_PASSWORD"),
+				From:     e.get("EMAIL_FROM"),
+			},
+			Interval:    e.duration("EMAIL_OUTBOX_INTERVAL", notifications.DefaultInterval),
+			MaxAttempts: e.integer("EMAIL_MAX_ATTEMPTS", notifications.DefaultMaxAttempts, 1),
+		},
+		Events: events.Config{
+			Interval:    e.duration("EVENT_DISPATCH_INTERVAL", events.DefaultInterval),
+			MaxAttempts: e.integer("EVENT_MAX_ATTEMPTS", events.DefaultMaxAttempts, 1),
+		},
+		Webhooks: webhooks.Config{
+			Interval:     e.duration("WEBHOOK_INTERVAL", webhooks.DefaultInterval),
+			Timeout:      e.duration("WEBHOOK_TIMEOUT", webhooks.DefaultTimeout),
+			MaxAttempts:  e.integer("WEBHOOK_MAX_ATTEMPTS", webhooks.DefaultMaxAttempts, 1),
+			DisableAfter: e.integer("WEBHOOK_DISABLE_AFTER", webhooks.DefaultDisableAfter, 1),
+		},
+		Tax: methods.TaxConfig{
+			Provider:  strings.ToLower(e.str("TAX_PROVIDER", tax.ProviderRules)),
+			Inclusive: e.boolean("TAX_PRICES_INCLUSIVE", false),
+		},
+		Checkout: methods.CheckoutConfig{
+			SuccessURL:         e.get("CHECKOUT_SUCCESS_URL"),
+			CancelURL:          e.get("CHECKOUT_CANCEL_URL"),
+			AllowedCountries:   e.list("CHECKOUT_ALLOWED_COUNTRIES"),
+			ReturnURLAllowlist: e.list("CHECKOUT_RETURN_URL_ALLOWLIST"),
+		},
+		StockLow: int32(e.integer("STOCK_LOW_THRESHOLD", methods.DefaultStockLowThreshold, 0)),
+	}
+	c.Auth.MFAIssuer = e.str("MFA_ISSUER", c.Email.StoreName)
+	c.Recovery.Key = c.Auth.JWTKey
+	c.Cleanup.LinkTTL = c.Recovery.LinkTTL
+	for i, country := range c.Checkout.AllowedCountries {
+		c.Checkout.AllowedCountries[i] = strings.ToUpper(country)
+	}
+
+	problems := e.problems
+	if err, ok := c.Validate().(*Error); ok {
+		problems = append(problems, err.Problems...)
+	}
+	if len(problems) > 0 {
+		return Config{}, &Error{Problems: problems}
+	}
+	return c, nil
+}
+
+// loadLogging reads LOG_LEVEL (debug, info, warn or error) and LOG_FORMAT
+// (text or json), JSON by default in production
+func loadLogging(e *env) logging.Config {
+	c := logging.Config{Level: slog.LevelInfo, Format: strings.ToLower(e.get("LOG_FORMAT"))}
+	if v := e.get("LOG_LEVEL"); v != "" {
+		if err := c.Level.UnmarshalText([]byte(v)); err != nil {
+			e.problem("LOG_LEVEL must be debug, info, warn or error, got %q", v)
+		}
+	}
+	switch c.Format {
+	case "":
+		c.Format = logging.FormatText
+		if e.profile == Production {
+			c.Format = logging.FormatJSON
+		}
+	case logging.FormatText, logging.FormatJSON:
+	default:
+		e.problem("LOG_FORMAT must be %s or %s, got %q", logging.FormatText, logging.FormatJSON, c.Format)
+	}
+	return c
+}
+
+// loadTracing reads the standard OTEL_TRACES_EXPORTER,
+// OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_SERVICE_NAME and OTEL_TRACES_SAMPLER_ARG
+// variables. Tracing is off unless the exporter is "otlp".
+func loadTracing(e *env) tracing.Config {
+	c := tracing.Config{
+		Exporter:    e.str("OTEL_TRACES_EXPORTER", tracing.ExporterNone),
+		Endpoint:    e.get("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		ServiceName: e.str("OTEL_SERVICE_NAME", tracing.DefaultServiceName),
+		SampleRatio: 1,
+	}
+	if v := e.get("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			e.problem("OTEL_TRACES_SAMPLER_ARG must be a ratio between 0 and 1, got %q", v)
+		} else {
+			c.SampleRatio = ratio
+		}
+	}
+	switch c.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP:
+	default:
+		e.problem("OTEL_TRACES_EXPORTER must be %s or %s, got %q", tracing.ExporterOTLP, tracing.ExporterNone, c.Exporter)
+	}
+	return c
+}
+
+// loadRateLimits reads RATE_LIMIT_BACKEND (memory or postgres) and the
+// RATE_LIMIT_* limits in ratelimit.ParseLimit's syntax. Unset limits keep
+// their defaults.
+func loadRateLimits(e *env) ratelimit.Config {
+	c := ratelimit.DefaultConfig
+	c.Backend = strings.ToLower(e.str("RATE_LIMIT_BACKEND", c.Backend))
+	switch c.Backend {
+	case ratelimit.BackendMemory, ratelimit.BackendPostgres:
+	default:
+		e.problem("RATE_LIMIT_BACKEND must be %s or %s, got %q", ratelimit.BackendMemory, ratelimit.BackendPostgres, c.Backend)
+	}
+
+	for _, v := range []struct {
+		name  string
+		limit *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_IP", &c.IP},
+		{"RATE_LIMIT_NEW_CART", &c.NewCart},
+		{"RATE_LIMIT_LOGIN", &c.Login},
+		{"RATE_LIMIT_LOGIN_ACCOUNT", &c.LoginAccount},
+		{"RATE_LIMIT_CART_ADD", &c.CartAdd},
+		{"RATE_LIMIT_ORDER_LOOKUP", &c.OrderLookup},
+	} {
+		s, ok := e.lookup(v.name)
+		if !ok {
+			continue
+		}
+		l, err := ratelimit.ParseLimit(s)
+		if err != nil {
+			e.problem("%s: %s", v.name, err)
+			continue
+		}
+		*v.limit = l
+	}
+	return c
+}
+
+// Validate checks the required settings, and in production the ones that
+// are only optional while developing
+func (c Config) Validate() error {
+	var problems []string
+	required := func(name, value string) bool {
+		if value == "" {
+			problems = append(problems, name+" is required")
+			return false
+		}
+		return true
+	}
+
+	if required("DB_URL", c.DatabaseURL) {
+		if _, err := pgxpool.ParseConfig(c.DatabaseURL); err != nil {
+			problems = append(problems, "DB_URL is not a valid connection string: "+err.Error())
+		}
+	}
+	if required("JWT_KEY", c.Auth.JWTKey) && c.Env == Production && len(c.Auth.JWTKey) < MinProductionKeyLength {
+		problems = append(problems, fmt.Sprintf("JWT_KEY must be at least %d bytes in production", MinProductionKeyLength))
+	}
+	for _, cookie := range []struct{ name, value string }{
+		{"COOKIE_NAME", c.Auth.CookieName},
+		{"ADMIN_COOKIE_NAME", c.Auth.AdminCookieName},
+	} {
+		if required(cookie.name, cookie.value) {
+			if err := (&http.Cookie{Name: cookie.value}).Valid(); err != nil {
+				problems = append(problems, cookie.name+" is not a valid cookie name")
+			}
+		}
+	}
+	if c.Auth.CookieName != "" && c.Auth.CookieName == c.Auth.AdminCookieName {
+		problems = append(problems, "COOKIE_NAME and ADMIN_COOKIE_NAME must differ")
+	}
+	if a := c.Auth; a.AccessTTL > 0 && (a.AccessTTL >= a.CartSessionTTL || a.AccessTTL >= a.AdminSessionTTL) {
+		problems = append(problems, "ACCESS_TOKEN_TTL must be shorter than CART_SESSION_TTL and ADMIN_SESSION_TTL")
+	}
+	if c.Server.Addr == "" {
+		problems = append(problems, "SERVER_ADDR is required")
+	}
+	if c.Cleanup.Retention < cleanup.MinRetention {
+		problems = append(problems, fmt.Sprintf("CART_RETENTION must be at least %s", cleanup.MinRetention))
+	}
+	switch c.Tax.Provider {
+	case tax.ProviderRules, tax.ProviderStripe:
+	default:
+		problems = append(problems, fmt.Sprintf("TAX_PROVIDER must be %s or %s, got %q", tax.ProviderRules, tax.ProviderStripe, c.Tax.Provider))
+	}
+	switch c.Email.Transport {
+	case notifications.TransportLog:
+	case notifications.TransportSMTP:
+		if c.Email.SMTP.Host == "" || c.Email.SMTP.From == "" {
+			problems = append(problems, "SMTP_HOST and EMAIL_FROM are required when EMAIL_TRANSPORT is smtp")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("EMAIL_TRANSPORT must be %s or %s, got %q", notifications.TransportLog, notifications.TransportSMTP, c.Email.Transport))
+	}
+
+	if c.Env == Production {
+		required("STRIPE_KEY", c.StripeKey)
+		required("STRIPE_WEBHOOK_SECRET", c.StripeWebhookSecret)
+		if err := c.Checkout.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+func (e *env) problem(format string, args ...any) {
+	e.problems = append(e.problems, fmt.Sprintf(format, args...))
+}
+
+// str returns the value of name, or def when it's unset
+func (e *env) str(name, def string) string {
+	if v := e.get(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// duration reads a positive duration in Go syntax, e.g. "30s", or returns
+// def when it's unset or zero
+func (e *env) duration(name string, def time.Duration) time.Duration {
+	if d := e.interval(name, def); d > 0 {
+		return d
+	}
+	return def
+}
+
+// interval reads a duration like duration, except zero is kept so it can
+// turn a worker off
+func (e *env) interval(name string, def time.Duration) time.Duration {
+	v := e.get(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.problem("%s must be a duration like 30s or 1h, got %q", name, v)
+		return def
+	}
+	if d < 0 {
+		e.problem("%s can't be negative, got %q", name, v)
+		return def
+	}
+	return d
+}
+
+// integer reads a whole number of at least min, or returns def when it's unset
+func (e *env) integer(name string, def, min int) int {
+	v := e.get(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.problem("%s must be a whole number, got %q", name, v)
+		return def
+	}
+	if n < min {
+		e.problem("%s must be at least %d, got %q", name, min, v)
+		return def
+	}
+	return n
+}
+
+func (e *env) boolean(name string, def bool) bool {
+	v := e.get(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.problem("%s must be true or false, got %q", name, v)
+		return def
+	}
+	return b
+}
+
+// list splits a comma separated value, YAML lists are flattened the same way
+func (e *env) list(name string) []string {
+	var list []string
+	for _, v := range strings.Split(e.get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
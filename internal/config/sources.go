@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is the YAML file read when CONFIG_FILE isn't set
+const DefaultFile = "config.yaml"
+
+// aliases are names the README used to document, still accepted so older
+// .env files keep working
+var aliases = map[string]string{
+	"JWT_SECRET":        "JWT_KEY",
+	"STRIPE_SECRET_KEY": "STRIPE_KEY",
+}
+
+// env holds the settings read from the configuration files. A variable set
+// in the process environment always wins, then .env.<profile>, .env, the
+// profile's YAML file (e.g. config.production.yaml) and finally the YAML
+// file itself. Nothing is written back to the environment.
+type env struct {
+	profile  string
+	files    map[string]string
+	problems []string // values that didn't parse, reported together by Load
+}
+
+// loadEnv reads the configuration files for the profile named by APP_ENV,
+// development by default. Missing files are skipped unless CONFIG_FILE names
+// one explicitly.
+func loadEnv() (*env, error) {
+	e := &env{files: map[string]string{}}
+
+	e.profile = os.Getenv("APP_ENV")
+	if e.profile == "" {
+		if vars, err := godotenv.Read(".env"); err == nil {
+			e.profile = vars["APP_ENV"]
+		}
+	}
+	if e.profile == "" {
+		e.profile = Development
+	}
+	switch e.profile {
+	case Development, Test, Production:
+	default:
+		return nil, fmt.Errorf("APP_ENV must be %s, %s or %s, got %q", Development, Test, Production, e.profile)
+	}
+
+	for _, name := range []string{".env." + e.profile, ".env"} {
+		vars, err := godotenv.Read(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		e.add(vars)
+		slog.Info("Loaded config", "file", name)
+	}
+
+	file, explicit := e.lookup("CONFIG_FILE")
+	if !explicit || file == "" {
+		file, explicit = DefaultFile, false
+	}
+	ext := filepath.Ext(file)
+	for _, name := range []string{strings.TrimSuffix(file, ext) + "." + e.profile + ext, file} {
+		vars, err := readYAML(name)
+		if errors.Is(err, fs.ErrNotExist) && !(explicit && name == file) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		e.add(vars)
+		slog.Info("Loaded config", "file", name)
+	}
+
+	for alias, name := range aliases {
+		if value := e.get(alias); value != "" && e.get(name) == "" {
+			slog.Warn("Deprecated config variable", "name", alias, "use", name)
+			e.files[name] = value
+		}
+	}
+	return e, nil
+}
+
+// add keeps the values already read, files are read most specific first
+func (e *env) add(vars map[string]string) {
+	for key, value := range vars {
+		if _, ok := e.files[key]; !ok {
+			e.files[key] = value
+		}
+	}
+}
+
+func (e *env) lookup(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := e.files[name]
+	return value, ok
+}
+
+func (e *env) get(name string) string {
+	value, _ := e.lookup(name)
+	return value
+}
+
+// DatabaseURL reads DB_URL from the same sources as Load, for commands that
+// need nothing else
+func DatabaseURL() (string, error) {
+	e, err := loadEnv()
+	if err != nil {
+		return "", err
+	}
+	url := e.get("DB_URL")
+	if url == "" {
+		return "", errors.New("DB_URL is required")
+	}
+	return url, nil
+}
+
+// readYAML flattens a YAML file into environment variables. Nested keys join
+// with underscores and lists with commas, so
+//
+//	cart_recovery:
+//	  interval: 15m
+//	checkout:
+//	  allowed_countries: [US, CA]
+//
+// sets CART_RECOVERY_INTERVAL=15m and CHECKOUT_ALLOWED_COUNTRIES=US,CA.
+func readYAML(name string) (map[string]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	return vars, flatten("", doc, vars)
+}
+
+func flatten(prefix string, value any, vars map[string]string) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			key = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			if err := flatten(key, child, vars); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]any, []any:
+				return fmt.Errorf("%s: lists may only hold plain values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		vars[prefix] = strings.Join(items, ",")
+	case nil:
+		vars[prefix] = ""
+	default:
+		vars[prefix] = fmt.Sprint(v)
+	}
+	return nil
+}
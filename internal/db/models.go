@@ -10,6 +10,7 @@ import (
 
 type Cart struct {
 	ID        pgtype.UUID        `json:"id"`
+	Email     pgtype.Text        `json:"email"`
 	CreatedAt pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
 }
@@ -24,6 +25,16 @@ type CartItem struct {
 	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
 }
 
+type CartRecovery struct {
+	ID          int32              `json:"id"`
+	CartID      pgtype.UUID        `json:"cartId"`
+	Email       string             `json:"email"`
+	SentAt      pgtype.Timestamptz `json:"sentAt"`
+	RestoredAt  pgtype.Timestamptz `json:"restoredAt"`
+	OrderID     pgtype.Int4        `json:"orderId"`
+	ConvertedAt pgtype.Timestamptz `json:"convertedAt"`
+}
+
 type Collection struct {
 	ID          int32              `json:"id"`
 	Name        string             `json:"name"`
@@ -48,6 +59,41 @@ type CollectionProduct struct {
 	UpdatedAt    pgtype.Timestamptz `json:"updatedAt"`
 }
 
+type DomainEvent struct {
+	ID            int32              `json:"id"`
+	EventType     string             `json:"eventType"`
+	AggregateType string             `json:"aggregateType"`
+	AggregateID   string             `json:"aggregateId"`
+	Payload       []byte             `json:"payload"`
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	LastError     pgtype.Text        `json:"lastError"`
+	NextAttemptAt pgtype.Timestamptz `json:"nextAttemptAt"`
+	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
+	DispatchedAt  pgtype.Timestamptz `json:"dispatchedAt"`
+}
+
+type DomainEventDelivery struct {
+	EventID     int32              `json:"eventId"`
+	Subscriber  string             `json:"subscriber"`
+	DeliveredAt pgtype.Timestamptz `json:"deliveredAt"`
+}
+
+type EmailOutbox struct {
+	ID            int32              `json:"id"`
+	Template      string             `json:"template"`
+	Recipient     string             `json:"recipient"`
+	Subject       string             `json:"subject"`
+	HtmlBody      string             `json:"htmlBody"`
+	TextBody      string             `json:"textBody"`
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	LastError     pgtype.Text        `json:"lastError"`
+	NextAttemptAt pgtype.Timestamptz `json:"nextAttemptAt"`
+	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
+	SentAt        pgtype.Timestamptz `json:"sentAt"`
+}
+
 type FitGuide struct {
 	ID            int32              `json:"id"`
 	ProductID     int32              `json:"productId"`
@@ -68,12 +114,114 @@ type FitGuide struct {
 	UpdatedAt     pgtype.Timestamptz `json:"updatedAt"`
 }
 
+type Fulfillment struct {
+	ID             int32              `json:"id"`
+	OrderID        int32              `json:"orderId"`
+	Carrier        pgtype.Text        `json:"carrier"`
+	TrackingNumber pgtype.Text        `json:"trackingNumber"`
+	TrackingUrl    pgtype.Text        `json:"trackingUrl"`
+	Status         string             `json:"status"`
+	ShippedAt      pgtype.Timestamptz `json:"shippedAt"`
+	DeliveredAt    pgtype.Timestamptz `json:"deliveredAt"`
+	CreatedAt      pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt      pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type FulfillmentItem struct {
+	ID            int32 `json:"id"`
+	FulfillmentID int32 `json:"fulfillmentId"`
+	OrderItemID   int32 `json:"orderItemId"`
+	Quantity      int32 `json:"quantity"`
+}
+
+type IdempotencyKey struct {
+	ID              int32              `json:"id"`
+	Key             string             `json:"key"`
+	Scope           string             `json:"scope"`
+	RequestHash     string             `json:"requestHash"`
+	StatusCode      pgtype.Int4        `json:"statusCode"`
+	ResponseHeaders []byte             `json:"responseHeaders"`
+	ResponseBody    []byte             `json:"responseBody"`
+	CreatedAt       pgtype.Timestamptz `json:"createdAt"`
+	ExpiresAt       pgtype.Timestamptz `json:"expiresAt"`
+}
+
+type LoginFailure struct {
+	Key           string             `json:"key"`
+	Failures      int32              `json:"failures"`
+	LockedUntil   pgtype.Timestamptz `json:"lockedUntil"`
+	LastFailureAt pgtype.Timestamptz `json:"lastFailureAt"`
+}
+
+type MfaRecoveryCode struct {
+	ID        int32              `json:"id"`
+	UserID    int32              `json:"userId"`
+	CodeHash  string             `json:"codeHash"`
+	UsedAt    pgtype.Timestamptz `json:"usedAt"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
+type Order struct {
+	ID                int32              `json:"id"`
+	CartID            pgtype.UUID        `json:"cartId"`
+	CheckoutSessionID pgtype.Text        `json:"checkoutSessionId"`
+	Email             pgtype.Text        `json:"email"`
+	Currency          string             `json:"currency"`
+	Subtotal          pgtype.Numeric     `json:"subtotal"`
+	TaxTotal          pgtype.Numeric     `json:"taxTotal"`
+	Total             pgtype.Numeric     `json:"total"`
+	TaxInclusive      bool               `json:"taxInclusive"`
+	ShippingMethod    pgtype.Text        `json:"shippingMethod"`
+	ShippingTotal     pgtype.Numeric     `json:"shippingTotal"`
+	Status            string             `json:"status"`
+	FulfillmentStatus string             `json:"fulfillmentStatus"`
+	CreatedAt         pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt         pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type OrderEvent struct {
+	ID         int32              `json:"id"`
+	OrderID    int32              `json:"orderId"`
+	FromStatus pgtype.Text        `json:"fromStatus"`
+	ToStatus   string             `json:"toStatus"`
+	Actor      string             `json:"actor"`
+	UserID     pgtype.Int4        `json:"userId"`
+	Note       pgtype.Text        `json:"note"`
+	CreatedAt  pgtype.Timestamptz `json:"createdAt"`
+}
+
+type OrderItem struct {
+	ID          int32              `json:"id"`
+	OrderID     int32              `json:"orderId"`
+	ProductID   pgtype.Int4        `json:"productId"`
+	Name        string             `json:"name"`
+	PriceID     string             `json:"priceId"`
+	Quantity    int32              `json:"quantity"`
+	UnitPrice   pgtype.Numeric     `json:"unitPrice"`
+	TaxCategory string             `json:"taxCategory"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt   pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type OrderTaxLine struct {
+	ID            int32              `json:"id"`
+	OrderID       int32              `json:"orderId"`
+	Name          string             `json:"name"`
+	Jurisdiction  string             `json:"jurisdiction"`
+	Rate          pgtype.Numeric     `json:"rate"`
+	TaxableAmount pgtype.Numeric     `json:"taxableAmount"`
+	Amount        pgtype.Numeric     `json:"amount"`
+	CreatedAt     pgtype.Timestamptz `json:"createdAt"`
+}
+
 type Product struct {
 	ID          int32              `json:"id"`
 	Name        string             `json:"name"`
 	Description pgtype.Text        `json:"description"`
 	Price       pgtype.Numeric     `json:"price"`
 	PriceID     string             `json:"priceId"`
+	TaxCategory string             `json:"taxCategory"`
+	WeightGrams int32              `json:"weightGrams"`
 	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt   pgtype.Timestamptz `json:"updatedAt"`
 }
@@ -96,11 +244,190 @@ type ProductSize struct {
 	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
 }
 
+type RateLimit struct {
+	Key       string             `json:"key"`
+	Tokens    float64            `json:"tokens"`
+	Allowed   bool               `json:"allowed"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type Refund struct {
+	ID               int32              `json:"id"`
+	OrderID          int32              `json:"orderId"`
+	ReturnID         pgtype.Int4        `json:"returnId"`
+	Amount           pgtype.Numeric     `json:"amount"`
+	Reason           string             `json:"reason"`
+	Status           string             `json:"status"`
+	ProviderRefundID pgtype.Text        `json:"providerRefundId"`
+	FailureReason    pgtype.Text        `json:"failureReason"`
+	CreatedBy        pgtype.Int4        `json:"createdBy"`
+	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt        pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type RefundEvent struct {
+	ID        int32              `json:"id"`
+	RefundID  int32              `json:"refundId"`
+	Status    string             `json:"status"`
+	Message   string             `json:"message"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
+type Return struct {
+	ID        int32              `json:"id"`
+	OrderID   int32              `json:"orderId"`
+	Email     string             `json:"email"`
+	Reason    string             `json:"reason"`
+	Status    string             `json:"status"`
+	Note      pgtype.Text        `json:"note"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type ReturnItem struct {
+	ID          int32              `json:"id"`
+	ReturnID    int32              `json:"returnId"`
+	OrderItemID int32              `json:"orderItemId"`
+	Quantity    int32              `json:"quantity"`
+	SizeName    string             `json:"sizeName"`
+	Reason      pgtype.Text        `json:"reason"`
+	Restocked   bool               `json:"restocked"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+}
+
+type RevokedToken struct {
+	Jti       string             `json:"jti"`
+	ExpiresAt pgtype.Timestamptz `json:"expiresAt"`
+}
+
+type SecurityEvent struct {
+	ID        int32              `json:"id"`
+	EventType string             `json:"eventType"`
+	UserID    pgtype.Int4        `json:"userId"`
+	Email     string             `json:"email"`
+	Ip        string             `json:"ip"`
+	UserAgent string             `json:"userAgent"`
+	Detail    string             `json:"detail"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
+type Session struct {
+	ID              pgtype.UUID        `json:"id"`
+	Kind            string             `json:"kind"`
+	UserID          pgtype.Int4        `json:"userId"`
+	CartID          pgtype.UUID        `json:"cartId"`
+	RefreshHash     string             `json:"refreshHash"`
+	PreviousHash    pgtype.Text        `json:"previousHash"`
+	AccessJti       string             `json:"accessJti"`
+	AccessExpiresAt pgtype.Timestamptz `json:"accessExpiresAt"`
+	ExpiresAt       pgtype.Timestamptz `json:"expiresAt"`
+	RevokedAt       pgtype.Timestamptz `json:"revokedAt"`
+	CreatedAt       pgtype.Timestamptz `json:"createdAt"`
+	RefreshedAt     pgtype.Timestamptz `json:"refreshedAt"`
+}
+
+type ShippingMethod struct {
+	ID        int32              `json:"id"`
+	ZoneID    int32              `json:"zoneId"`
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Rate      pgtype.Numeric     `json:"rate"`
+	FreeOver  pgtype.Numeric     `json:"freeOver"`
+	MinDays   pgtype.Int4        `json:"minDays"`
+	MaxDays   pgtype.Int4        `json:"maxDays"`
+	Active    bool               `json:"active"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type ShippingMethodTier struct {
+	ID        int32              `json:"id"`
+	MethodID  int32              `json:"methodId"`
+	MinValue  pgtype.Numeric     `json:"minValue"`
+	Rate      pgtype.Numeric     `json:"rate"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
+type ShippingZone struct {
+	ID        int32              `json:"id"`
+	Name      string             `json:"name"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type ShippingZoneRegion struct {
+	ID        int32              `json:"id"`
+	ZoneID    int32              `json:"zoneId"`
+	Country   string             `json:"country"`
+	State     string             `json:"state"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
+type TaxRate struct {
+	ID           int32              `json:"id"`
+	Name         string             `json:"name"`
+	Country      string             `json:"country"`
+	State        string             `json:"state"`
+	PostalPrefix string             `json:"postalPrefix"`
+	TaxCategory  string             `json:"taxCategory"`
+	Rate         pgtype.Numeric     `json:"rate"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt    pgtype.Timestamptz `json:"updatedAt"`
+}
+
 type User struct {
 	ID           int32              `json:"id"`
 	Email        string             `json:"email"`
-	PasswordHash string             `json:"passwordHash"`
+	PasswordHash string             `json:"-"`
 	IsAdmin      pgtype.Bool        `json:"isAdmin"`
 	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt    pgtype.Timestamptz `json:"updatedAt"`
 }
+
+type UserMfa struct {
+	UserID    int32              `json:"userId"`
+	Secret    string             `json:"secret"`
+	Enabled   bool               `json:"enabled"`
+	LastStep  int64              `json:"lastStep"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	EnabledAt pgtype.Timestamptz `json:"enabledAt"`
+}
+
+type WebhookDelivery struct {
+	ID             int32              `json:"id"`
+	EndpointID     int32              `json:"endpointId"`
+	EventID        int32              `json:"eventId"`
+	EventType      string             `json:"eventType"`
+	Payload        []byte             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	ResponseStatus pgtype.Int4        `json:"responseStatus"`
+	LastError      pgtype.Text        `json:"lastError"`
+	NextAttemptAt  pgtype.Timestamptz `json:"nextAttemptAt"`
+	CreatedAt      pgtype.Timestamptz `json:"createdAt"`
+	DeliveredAt    pgtype.Timestamptz `json:"deliveredAt"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID             int32              `json:"id"`
+	DeliveryID     int32              `json:"deliveryId"`
+	ResponseStatus pgtype.Int4        `json:"responseStatus"`
+	ResponseBody   pgtype.Text        `json:"responseBody"`
+	Error          pgtype.Text        `json:"error"`
+	DurationMs     int32              `json:"durationMs"`
+	CreatedAt      pgtype.Timestamptz `json:"createdAt"`
+}
+
+type WebhookEndpoint struct {
+	ID                  int32              `json:"id"`
+	Url                 string             `json:"url"`
+	Description         pgtype.Text        `json:"description"`
+	EventTypes          []string           `json:"eventTypes"`
+	Secret              string             `json:"secret"`
+	Enabled             bool               `json:"enabled"`
+	ConsecutiveFailures int32              `json:"consecutiveFailures"`
+	DisabledAt          pgtype.Timestamptz `json:"disabledAt"`
+	DisabledReason      pgtype.Text        `json:"disabledReason"`
+	CreatedAt           pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt           pgtype.Timestamptz `json:"updatedAt"`
+}
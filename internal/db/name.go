@@ -0,0 +1,16 @@
+package db
+
+import "regexp"
+
+// sqlcName matches the "-- name: GetCart :one" comment sqlc puts at the
+// start of every generated query
+var sqlcName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// QueryName returns the name of the sqlc query sql came from, e.g.
+// "GetCart", or "" for SQL written by hand
+func QueryName(sql string) string {
+	if m := sqlcName.FindStringSubmatch(sql); m != nil {
+		return m[1]
+	}
+	return ""
+}
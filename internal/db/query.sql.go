@@ -51,6 +51,196 @@ func (q *Queries) AddCollectionImage(ctx context.Context, arg AddCollectionImage
 	return err
 }
 
+const addDomainEvent = `-- name: AddDomainEvent :one
+INSERT INTO domain_events (
+  event_type, aggregate_type, aggregate_id, payload
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, event_type, aggregate_type, aggregate_id, payload, status, attempts, last_error, next_attempt_at, created_at, dispatched_at
+`
+
+type AddDomainEventParams struct {
+	EventType     string `json:"eventType"`
+	AggregateType string `json:"aggregateType"`
+	AggregateID   string `json:"aggregateId"`
+	Payload       []byte `json:"payload"`
+}
+
+// Domain Events
+func (q *Queries) AddDomainEvent(ctx context.Context, arg AddDomainEventParams) (DomainEvent, error) {
+	row := q.db.QueryRow(ctx, addDomainEvent,
+		arg.EventType,
+		arg.AggregateType,
+		arg.AggregateID,
+		arg.Payload,
+	)
+	var i DomainEvent
+	err := row.Scan(
+		&i.ID,
+		&i.EventType,
+		&i.AggregateType,
+		&i.AggregateID,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.CreatedAt,
+		&i.DispatchedAt,
+	)
+	return i, err
+}
+
+const addDomainEventDelivery = `-- name: AddDomainEventDelivery :exec
+INSERT INTO domain_event_deliveries (
+  event_id, subscriber
+) VALUES (
+  $1, $2
+)
+ON CONFLICT DO NOTHING
+`
+
+type AddDomainEventDeliveryParams struct {
+	EventID    int32  `json:"eventId"`
+	Subscriber string `json:"subscriber"`
+}
+
+func (q *Queries) AddDomainEventDelivery(ctx context.Context, arg AddDomainEventDeliveryParams) error {
+	_, err := q.db.Exec(ctx, addDomainEventDelivery, arg.EventID, arg.Subscriber)
+	return err
+}
+
+const addFulfillmentItem = `-- name: AddFulfillmentItem :exec
+INSERT INTO fulfillment_items (
+  fulfillment_id, order_item_id, quantity
+) VALUES (
+  $1, $2, $3
+)
+`
+
+type AddFulfillmentItemParams struct {
+	FulfillmentID int32 `json:"fulfillmentId"`
+	OrderItemID   int32 `json:"orderItemId"`
+	Quantity      int32 `json:"quantity"`
+}
+
+// Fulfillment Items
+func (q *Queries) AddFulfillmentItem(ctx context.Context, arg AddFulfillmentItemParams) error {
+	_, err := q.db.Exec(ctx, addFulfillmentItem, arg.FulfillmentID, arg.OrderItemID, arg.Quantity)
+	return err
+}
+
+const addMFARecoveryCode = `-- name: AddMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+  user_id, code_hash
+) VALUES (
+  $1, $2
+)
+`
+
+type AddMFARecoveryCodeParams struct {
+	UserID   int32  `json:"userId"`
+	CodeHash string `json:"codeHash"`
+}
+
+func (q *Queries) AddMFARecoveryCode(ctx context.Context, arg AddMFARecoveryCodeParams) error {
+	_, err := q.db.Exec(ctx, addMFARecoveryCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const addOrderEvent = `-- name: AddOrderEvent :exec
+INSERT INTO order_events (
+  order_id, from_status, to_status, actor, user_id, note
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+`
+
+type AddOrderEventParams struct {
+	OrderID    int32       `json:"orderId"`
+	FromStatus pgtype.Text `json:"fromStatus"`
+	ToStatus   string      `json:"toStatus"`
+	Actor      string      `json:"actor"`
+	UserID     pgtype.Int4 `json:"userId"`
+	Note       pgtype.Text `json:"note"`
+}
+
+// Order Events
+func (q *Queries) AddOrderEvent(ctx context.Context, arg AddOrderEventParams) error {
+	_, err := q.db.Exec(ctx, addOrderEvent,
+		arg.OrderID,
+		arg.FromStatus,
+		arg.ToStatus,
+		arg.Actor,
+		arg.UserID,
+		arg.Note,
+	)
+	return err
+}
+
+const addOrderItem = `-- name: AddOrderItem :exec
+INSERT INTO order_items (
+  order_id, product_id, name, price_id, quantity, unit_price, tax_category
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+`
+
+type AddOrderItemParams struct {
+	OrderID     int32          `json:"orderId"`
+	ProductID   pgtype.Int4    `json:"productId"`
+	Name        string         `json:"name"`
+	PriceID     string         `json:"priceId"`
+	Quantity    int32          `json:"quantity"`
+	UnitPrice   pgtype.Numeric `json:"unitPrice"`
+	TaxCategory string         `json:"taxCategory"`
+}
+
+// Order Items
+func (q *Queries) AddOrderItem(ctx context.Context, arg AddOrderItemParams) error {
+	_, err := q.db.Exec(ctx, addOrderItem,
+		arg.OrderID,
+		arg.ProductID,
+		arg.Name,
+		arg.PriceID,
+		arg.Quantity,
+		arg.UnitPrice,
+		arg.TaxCategory,
+	)
+	return err
+}
+
+const addOrderTaxLine = `-- name: AddOrderTaxLine :exec
+INSERT INTO order_tax_lines (
+  order_id, name, jurisdiction, rate, taxable_amount, amount
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+`
+
+type AddOrderTaxLineParams struct {
+	OrderID       int32          `json:"orderId"`
+	Name          string         `json:"name"`
+	Jurisdiction  string         `json:"jurisdiction"`
+	Rate          pgtype.Numeric `json:"rate"`
+	TaxableAmount pgtype.Numeric `json:"taxableAmount"`
+	Amount        pgtype.Numeric `json:"amount"`
+}
+
+// Order Tax Lines
+func (q *Queries) AddOrderTaxLine(ctx context.Context, arg AddOrderTaxLineParams) error {
+	_, err := q.db.Exec(ctx, addOrderTaxLine,
+		arg.OrderID,
+		arg.Name,
+		arg.Jurisdiction,
+		arg.Rate,
+		arg.TaxableAmount,
+		arg.Amount,
+	)
+	return err
+}
+
 const addProductImage = `-- name: AddProductImage :exec
 INSERT INTO product_images (
   product_id, image_url
@@ -110,301 +300,2821 @@ func (q *Queries) AddProductToCollection(ctx context.Context, arg AddProductToCo
 	return err
 }
 
-const clearCart = `-- name: ClearCart :exec
-DELETE FROM cart_items
-WHERE cart_id = $1
-`
-
-func (q *Queries) ClearCart(ctx context.Context, cartID pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, clearCart, cartID)
-	return err
-}
-
-const createCart = `-- name: CreateCart :one
-INSERT INTO carts (
-  id
+const addRefundEvent = `-- name: AddRefundEvent :exec
+INSERT INTO refund_events (
+  refund_id, status, message
 ) VALUES (
-  $1
+  $1, $2, $3
 )
-RETURNING id, created_at, updated_at
 `
 
-func (q *Queries) CreateCart(ctx context.Context, id pgtype.UUID) (Cart, error) {
-	row := q.db.QueryRow(ctx, createCart, id)
-	var i Cart
-	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
-	return i, err
+type AddRefundEventParams struct {
+	RefundID int32  `json:"refundId"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
 }
 
-const createCollection = `-- name: CreateCollection :one
-INSERT INTO collections (
-  name, description
+// Refund Events
+func (q *Queries) AddRefundEvent(ctx context.Context, arg AddRefundEventParams) error {
+	_, err := q.db.Exec(ctx, addRefundEvent, arg.RefundID, arg.Status, arg.Message)
+	return err
+}
+
+const addReturnItem = `-- name: AddReturnItem :exec
+INSERT INTO return_items (
+  return_id, order_item_id, quantity, size_name, reason
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4, $5
 )
-RETURNING id, name, description, created_at, updated_at
 `
 
-type CreateCollectionParams struct {
-	Name        string      `json:"name"`
-	Description pgtype.Text `json:"description"`
+type AddReturnItemParams struct {
+	ReturnID    int32       `json:"returnId"`
+	OrderItemID int32       `json:"orderItemId"`
+	Quantity    int32       `json:"quantity"`
+	SizeName    string      `json:"sizeName"`
+	Reason      pgtype.Text `json:"reason"`
 }
 
-func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionParams) (Collection, error) {
-	row := q.db.QueryRow(ctx, createCollection, arg.Name, arg.Description)
-	var i Collection
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+// Return Items
+func (q *Queries) AddReturnItem(ctx context.Context, arg AddReturnItemParams) error {
+	_, err := q.db.Exec(ctx, addReturnItem,
+		arg.ReturnID,
+		arg.OrderItemID,
+		arg.Quantity,
+		arg.SizeName,
+		arg.Reason,
 	)
-	return i, err
+	return err
 }
 
-const createProduct = `-- name: CreateProduct :one
-INSERT INTO products (
-  name, description, price, price_id
+const addSecurityEvent = `-- name: AddSecurityEvent :exec
+INSERT INTO security_events (
+  event_type, user_id, email, ip, user_agent, detail
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5, $6
 )
-RETURNING id, name, description, price, price_id, created_at, updated_at
 `
 
-type CreateProductParams struct {
-	Name        string         `json:"name"`
-	Description pgtype.Text    `json:"description"`
-	Price       pgtype.Numeric `json:"price"`
-	PriceID     string         `json:"priceId"`
+type AddSecurityEventParams struct {
+	EventType string      `json:"eventType"`
+	UserID    pgtype.Int4 `json:"userId"`
+	Email     string      `json:"email"`
+	Ip        string      `json:"ip"`
+	UserAgent string      `json:"userAgent"`
+	Detail    string      `json:"detail"`
 }
 
-func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
-	row := q.db.QueryRow(ctx, createProduct,
-		arg.Name,
-		arg.Description,
-		arg.Price,
-		arg.PriceID,
-	)
-	var i Product
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.Price,
-		&i.PriceID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+func (q *Queries) AddSecurityEvent(ctx context.Context, arg AddSecurityEventParams) error {
+	_, err := q.db.Exec(ctx, addSecurityEvent,
+		arg.EventType,
+		arg.UserID,
+		arg.Email,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Detail,
 	)
-	return i, err
+	return err
 }
 
-const createProductFitGuide = `-- name: CreateProductFitGuide :exec
-INSERT INTO fit_guides (
-  product_id, body_length, sleeve_length, chest_width, shoulder_width,
-  arm_hole, front_rise, inseam, hem, back_rise, waist, thigh, knee
+const addShippingMethodTier = `-- name: AddShippingMethodTier :exec
+INSERT INTO shipping_method_tiers (
+  method_id, min_value, rate
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+  $1, $2, $3
 )
 `
 
-type CreateProductFitGuideParams struct {
-	ProductID     int32          `json:"productId"`
-	BodyLength    pgtype.Numeric `json:"bodyLength"`
-	SleeveLength  pgtype.Numeric `json:"sleeveLength"`
-	ChestWidth    pgtype.Numeric `json:"chestWidth"`
-	ShoulderWidth pgtype.Numeric `json:"shoulderWidth"`
-	ArmHole       pgtype.Numeric `json:"armHole"`
-	FrontRise     pgtype.Numeric `json:"frontRise"`
-	Inseam        pgtype.Numeric `json:"inseam"`
-	Hem           pgtype.Numeric `json:"hem"`
-	BackRise      pgtype.Numeric `json:"backRise"`
-	Waist         pgtype.Numeric `json:"waist"`
-	Thigh         pgtype.Numeric `json:"thigh"`
-	Knee          pgtype.Numeric `json:"knee"`
+type AddShippingMethodTierParams struct {
+	MethodID int32          `json:"methodId"`
+	MinValue pgtype.Numeric `json:"minValue"`
+	Rate     pgtype.Numeric `json:"rate"`
 }
 
-func (q *Queries) CreateProductFitGuide(ctx context.Context, arg CreateProductFitGuideParams) error {
-	_, err := q.db.Exec(ctx, createProductFitGuide,
-		arg.ProductID,
-		arg.BodyLength,
-		arg.SleeveLength,
-		arg.ChestWidth,
-		arg.ShoulderWidth,
-		arg.ArmHole,
-		arg.FrontRise,
-		arg.Inseam,
-		arg.Hem,
-		arg.BackRise,
-		arg.Waist,
-		arg.Thigh,
-		arg.Knee,
-	)
+func (q *Queries) AddShippingMethodTier(ctx context.Context, arg AddShippingMethodTierParams) error {
+	_, err := q.db.Exec(ctx, addShippingMethodTier, arg.MethodID, arg.MinValue, arg.Rate)
 	return err
 }
 
-const createUser = `-- name: CreateUser :one
-INSERT INTO users (
-  email, password_hash, is_admin
+const addShippingZoneRegion = `-- name: AddShippingZoneRegion :exec
+INSERT INTO shipping_zone_regions (
+  zone_id, country, state
 ) VALUES (
   $1, $2, $3
 )
-RETURNING id, email, password_hash, is_admin, created_at, updated_at
+ON CONFLICT (zone_id, country, state) DO NOTHING
 `
 
-type CreateUserParams struct {
-	Email        string      `json:"email"`
-	PasswordHash string      `json:"passwordHash"`
-	IsAdmin      pgtype.Bool `json:"isAdmin"`
+type AddShippingZoneRegionParams struct {
+	ZoneID  int32  `json:"zoneId"`
+	Country string `json:"country"`
+	State   string `json:"state"`
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, createUser, arg.Email, arg.PasswordHash, arg.IsAdmin)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Email,
-		&i.PasswordHash,
-		&i.IsAdmin,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+func (q *Queries) AddShippingZoneRegion(ctx context.Context, arg AddShippingZoneRegionParams) error {
+	_, err := q.db.Exec(ctx, addShippingZoneRegion, arg.ZoneID, arg.Country, arg.State)
+	return err
 }
 
-const deleteCollection = `-- name: DeleteCollection :exec
-DELETE FROM collections
-WHERE id = $1
+const addWebhookDeliveryAttempt = `-- name: AddWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (
+  delivery_id, response_status, response_body, error, duration_ms
+) VALUES (
+  $1, $2, $3, $4, $5
+)
 `
 
-func (q *Queries) DeleteCollection(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteCollection, id)
+type AddWebhookDeliveryAttemptParams struct {
+	DeliveryID     int32       `json:"deliveryId"`
+	ResponseStatus pgtype.Int4 `json:"responseStatus"`
+	ResponseBody   pgtype.Text `json:"responseBody"`
+	Error          pgtype.Text `json:"error"`
+	DurationMs     int32       `json:"durationMs"`
+}
+
+func (q *Queries) AddWebhookDeliveryAttempt(ctx context.Context, arg AddWebhookDeliveryAttemptParams) error {
+	_, err := q.db.Exec(ctx, addWebhookDeliveryAttempt,
+		arg.DeliveryID,
+		arg.ResponseStatus,
+		arg.ResponseBody,
+		arg.Error,
+		arg.DurationMs,
+	)
 	return err
 }
 
-const deleteCollectionImage = `-- name: DeleteCollectionImage :exec
-DELETE FROM collection_images
-WHERE collection_id = $1 AND image_url = $2
+const claimDueDomainEvents = `-- name: ClaimDueDomainEvents :many
+UPDATE domain_events
+  SET attempts = attempts + 1,
+  next_attempt_at = NOW() + $1::interval
+WHERE id IN (
+  SELECT id FROM domain_events
+  WHERE status = 'pending' AND next_attempt_at <= NOW()
+  ORDER BY id
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, event_type, aggregate_type, aggregate_id, payload, status, attempts, last_error, next_attempt_at, created_at, dispatched_at
 `
 
-type DeleteCollectionImageParams struct {
-	CollectionID int32  `json:"collectionId"`
-	ImageUrl     string `json:"imageUrl"`
+type ClaimDueDomainEventsParams struct {
+	Lease     pgtype.Interval `json:"lease"`
+	BatchSize int32           `json:"batchSize"`
 }
 
-func (q *Queries) DeleteCollectionImage(ctx context.Context, arg DeleteCollectionImageParams) error {
-	_, err := q.db.Exec(ctx, deleteCollectionImage, arg.CollectionID, arg.ImageUrl)
-	return err
+func (q *Queries) ClaimDueDomainEvents(ctx context.Context, arg ClaimDueDomainEventsParams) ([]DomainEvent, error) {
+	rows, err := q.db.Query(ctx, claimDueDomainEvents, arg.Lease, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DomainEvent
+	for rows.Next() {
+		var i DomainEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.DispatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const deleteProduct = `-- name: DeleteProduct :exec
-DELETE FROM products
-WHERE id = $1
+const claimDueEmails = `-- name: ClaimDueEmails :many
+UPDATE email_outbox
+  SET attempts = attempts + 1,
+  next_attempt_at = NOW() + $1::interval
+WHERE id IN (
+  SELECT id FROM email_outbox
+  WHERE status = 'pending' AND next_attempt_at <= NOW()
+  ORDER BY next_attempt_at
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, template, recipient, subject, html_body, text_body, status, attempts, last_error, next_attempt_at, created_at, sent_at
 `
 
-func (q *Queries) DeleteProduct(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteProduct, id)
-	return err
+type ClaimDueEmailsParams struct {
+	Lease     pgtype.Interval `json:"lease"`
+	BatchSize int32           `json:"batchSize"`
 }
 
-const deleteProductImage = `-- name: DeleteProductImage :exec
-DELETE FROM product_images
-WHERE product_id = $1 AND image_url = $2
-`
-
+func (q *Queries) ClaimDueEmails(ctx context.Context, arg ClaimDueEmailsParams) ([]EmailOutbox, error) {
+	rows, err := q.db.Query(ctx, claimDueEmails, arg.Lease, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EmailOutbox
+	for rows.Next() {
+		var i EmailOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Template,
+			&i.Recipient,
+			&i.Subject,
+			&i.HtmlBody,
+			&i.TextBody,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+  SET attempts = attempts + 1,
+  next_attempt_at = NOW() + $1::interval
+WHERE id IN (
+  SELECT d.id FROM webhook_deliveries d
+  JOIN webhook_endpoints e ON e.id = d.endpoint_id
+  WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND e.enabled
+  ORDER BY d.next_attempt_at
+  LIMIT $2
+  FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING id, endpoint_id, event_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at
+`
+
+type ClaimDueWebhookDeliveriesParams struct {
+	Lease     pgtype.Interval `json:"lease"`
+	BatchSize int32           `json:"batchSize"`
+}
+
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, claimDueWebhookDeliveries, arg.Lease, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.EndpointID,
+			&i.EventID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clearCart = `-- name: ClearCart :exec
+DELETE FROM cart_items
+WHERE cart_id = $1
+`
+
+func (q *Queries) ClearCart(ctx context.Context, cartID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, clearCart, cartID)
+	return err
+}
+
+const clearLoginFailures = `-- name: ClearLoginFailures :exec
+DELETE FROM login_failures
+WHERE key = $1
+`
+
+func (q *Queries) ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, clearLoginFailures, key)
+	return err
+}
+
+const countMFARecoveryCodes = `-- name: CountMFARecoveryCodes :one
+SELECT COUNT(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) CountMFARecoveryCodes(ctx context.Context, userID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countMFARecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createCart = `-- name: CreateCart :one
+INSERT INTO carts (
+  id
+) VALUES (
+  $1
+)
+RETURNING id, email, created_at, updated_at
+`
+
+func (q *Queries) CreateCart(ctx context.Context, id pgtype.UUID) (Cart, error) {
+	row := q.db.QueryRow(ctx, createCart, id)
+	var i Cart
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createCartRecovery = `-- name: CreateCartRecovery :one
+INSERT INTO cart_recoveries (
+  cart_id, email
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (cart_id) DO NOTHING
+RETURNING id, cart_id, email, sent_at, restored_at, order_id, converted_at
+`
+
+type CreateCartRecoveryParams struct {
+	CartID pgtype.UUID `json:"cartId"`
+	Email  string      `json:"email"`
+}
+
+func (q *Queries) CreateCartRecovery(ctx context.Context, arg CreateCartRecoveryParams) (CartRecovery, error) {
+	row := q.db.QueryRow(ctx, createCartRecovery, arg.CartID, arg.Email)
+	var i CartRecovery
+	err := row.Scan(
+		&i.ID,
+		&i.CartID,
+		&i.Email,
+		&i.SentAt,
+		&i.RestoredAt,
+		&i.OrderID,
+		&i.ConvertedAt,
+	)
+	return i, err
+}
+
+const createCollection = `-- name: CreateCollection :one
+INSERT INTO collections (
+  name, description
+) VALUES (
+  $1, $2
+)
+RETURNING id, name, description, created_at, updated_at
+`
+
+type CreateCollectionParams struct {
+	Name        string      `json:"name"`
+	Description pgtype.Text `json:"description"`
+}
+
+func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionParams) (Collection, error) {
+	row := q.db.QueryRow(ctx, createCollection, arg.Name, arg.Description)
+	var i Collection
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createFulfillment = `-- name: CreateFulfillment :one
+INSERT INTO fulfillments (
+  order_id, carrier, tracking_number, tracking_url, status, shipped_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, order_id, carrier, tracking_number, tracking_url, status, shipped_at, delivered_at, created_at, updated_at
+`
+
+type CreateFulfillmentParams struct {
+	OrderID        int32              `json:"orderId"`
+	Carrier        pgtype.Text        `json:"carrier"`
+	TrackingNumber pgtype.Text        `json:"trackingNumber"`
+	TrackingUrl    pgtype.Text        `json:"trackingUrl"`
+	Status         string             `json:"status"`
+	ShippedAt      pgtype.Timestamptz `json:"shippedAt"`
+}
+
+// Fulfillments
+func (q *Queries) CreateFulfillment(ctx context.Context, arg CreateFulfillmentParams) (Fulfillment, error) {
+	row := q.db.QueryRow(ctx, createFulfillment,
+		arg.OrderID,
+		arg.Carrier,
+		arg.TrackingNumber,
+		arg.TrackingUrl,
+		arg.Status,
+		arg.ShippedAt,
+	)
+	var i Fulfillment
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.Carrier,
+		&i.TrackingNumber,
+		&i.TrackingUrl,
+		&i.Status,
+		&i.ShippedAt,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createIdempotencyKey = `-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+  key, scope, request_hash, expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (key, scope) DO NOTHING
+RETURNING id, key, scope, request_hash, status_code, response_headers, response_body, created_at, expires_at
+`
+
+type CreateIdempotencyKeyParams struct {
+	Key         string             `json:"key"`
+	Scope       string             `json:"scope"`
+	RequestHash string             `json:"requestHash"`
+	ExpiresAt   pgtype.Timestamptz `json:"expiresAt"`
+}
+
+// Idempotency Keys
+func (q *Queries) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, createIdempotencyKey,
+		arg.Key,
+		arg.Scope,
+		arg.RequestHash,
+		arg.ExpiresAt,
+	)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.ID,
+		&i.Key,
+		&i.Scope,
+		&i.RequestHash,
+		&i.StatusCode,
+		&i.ResponseHeaders,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const createOrder = `-- name: CreateOrder :one
+INSERT INTO orders (
+  cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive,
+  shipping_method, shipping_total
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING id, cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive, shipping_method, shipping_total, status, fulfillment_status, created_at, updated_at
+`
+
+type CreateOrderParams struct {
+	CartID            pgtype.UUID    `json:"cartId"`
+	CheckoutSessionID pgtype.Text    `json:"checkoutSessionId"`
+	Email             pgtype.Text    `json:"email"`
+	Currency          string         `json:"currency"`
+	Subtotal          pgtype.Numeric `json:"subtotal"`
+	TaxTotal          pgtype.Numeric `json:"taxTotal"`
+	Total             pgtype.Numeric `json:"total"`
+	TaxInclusive      bool           `json:"taxInclusive"`
+	ShippingMethod    pgtype.Text    `json:"shippingMethod"`
+	ShippingTotal     pgtype.Numeric `json:"shippingTotal"`
+}
+
+// Orders
+func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order, error) {
+	row := q.db.QueryRow(ctx, createOrder,
+		arg.CartID,
+		arg.CheckoutSessionID,
+		arg.Email,
+		arg.Currency,
+		arg.Subtotal,
+		arg.TaxTotal,
+		arg.Total,
+		arg.TaxInclusive,
+		arg.ShippingMethod,
+		arg.ShippingTotal,
+	)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CartID,
+		&i.CheckoutSessionID,
+		&i.Email,
+		&i.Currency,
+		&i.Subtotal,
+		&i.TaxTotal,
+		&i.Total,
+		&i.TaxInclusive,
+		&i.ShippingMethod,
+		&i.ShippingTotal,
+		&i.Status,
+		&i.FulfillmentStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createProduct = `-- name: CreateProduct :one
+INSERT INTO products (
+  name, description, price, price_id
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, name, description, price, price_id, tax_category, weight_grams, created_at, updated_at
+`
+
+type CreateProductParams struct {
+	Name        string         `json:"name"`
+	Description pgtype.Text    `json:"description"`
+	Price       pgtype.Numeric `json:"price"`
+	PriceID     string         `json:"priceId"`
+}
+
+func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
+	row := q.db.QueryRow(ctx, createProduct,
+		arg.Name,
+		arg.Description,
+		arg.Price,
+		arg.PriceID,
+	)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.PriceID,
+		&i.TaxCategory,
+		&i.WeightGrams,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createProductFitGuide = `-- name: CreateProductFitGuide :exec
+INSERT INTO fit_guides (
+  product_id, body_length, sleeve_length, chest_width, shoulder_width,
+  arm_hole, front_rise, inseam, hem, back_rise, waist, thigh, knee
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+`
+
+type CreateProductFitGuideParams struct {
+	ProductID     int32          `json:"productId"`
+	BodyLength    pgtype.Numeric `json:"bodyLength"`
+	SleeveLength  pgtype.Numeric `json:"sleeveLength"`
+	ChestWidth    pgtype.Numeric `json:"chestWidth"`
+	ShoulderWidth pgtype.Numeric `json:"shoulderWidth"`
+	ArmHole       pgtype.Numeric `json:"armHole"`
+	FrontRise     pgtype.Numeric `json:"frontRise"`
+	Inseam        pgtype.Numeric `json:"inseam"`
+	Hem           pgtype.Numeric `json:"hem"`
+	BackRise      pgtype.Numeric `json:"backRise"`
+	Waist         pgtype.Numeric `json:"waist"`
+	Thigh         pgtype.Numeric `json:"thigh"`
+	Knee          pgtype.Numeric `json:"knee"`
+}
+
+func (q *Queries) CreateProductFitGuide(ctx context.Context, arg CreateProductFitGuideParams) error {
+	_, err := q.db.Exec(ctx, createProductFitGuide,
+		arg.ProductID,
+		arg.BodyLength,
+		arg.SleeveLength,
+		arg.ChestWidth,
+		arg.ShoulderWidth,
+		arg.ArmHole,
+		arg.FrontRise,
+		arg.Inseam,
+		arg.Hem,
+		arg.BackRise,
+		arg.Waist,
+		arg.Thigh,
+		arg.Knee,
+	)
+	return err
+}
+
+const createRefund = `-- name: CreateRefund :one
+INSERT INTO refunds (
+  order_id, return_id, amount, reason, created_by
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, order_id, return_id, amount, reason, status, provider_refund_id, failure_reason, created_by, created_at, updated_at
+`
+
+type CreateRefundParams struct {
+	OrderID   int32          `json:"orderId"`
+	ReturnID  pgtype.Int4    `json:"returnId"`
+	Amount    pgtype.Numeric `json:"amount"`
+	Reason    string         `json:"reason"`
+	CreatedBy pgtype.Int4    `json:"createdBy"`
+}
+
+// Refunds
+func (q *Queries) CreateRefund(ctx context.Context, arg CreateRefundParams) (Refund, error) {
+	row := q.db.QueryRow(ctx, createRefund,
+		arg.OrderID,
+		arg.ReturnID,
+		arg.Amount,
+		arg.Reason,
+		arg.CreatedBy,
+	)
+	var i Refund
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.ReturnID,
+		&i.Amount,
+		&i.Reason,
+		&i.Status,
+		&i.ProviderRefundID,
+		&i.FailureReason,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createReturn = `-- name: CreateReturn :one
+INSERT INTO returns (
+  order_id, email, reason
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, order_id, email, reason, status, note, created_at, updated_at
+`
+
+type CreateReturnParams struct {
+	OrderID int32  `json:"orderId"`
+	Email   string `json:"email"`
+	Reason  string `json:"reason"`
+}
+
+// Returns
+func (q *Queries) CreateReturn(ctx context.Context, arg CreateReturnParams) (Return, error) {
+	row := q.db.QueryRow(ctx, createReturn, arg.OrderID, arg.Email, arg.Reason)
+	var i Return
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.Email,
+		&i.Reason,
+		&i.Status,
+		&i.Note,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createSession = `-- name: CreateSession :exec
+INSERT INTO sessions (
+  id, kind, user_id, cart_id, refresh_hash, access_jti, access_expires_at, expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+`
+
+type CreateSessionParams struct {
+	ID              pgtype.UUID        `json:"id"`
+	Kind            string             `json:"kind"`
+	UserID          pgtype.Int4        `json:"userId"`
+	CartID          pgtype.UUID        `json:"cartId"`
+	RefreshHash     string             `json:"refreshHash"`
+	AccessJti       string             `json:"accessJti"`
+	AccessExpiresAt pgtype.Timestamptz `json:"accessExpiresAt"`
+	ExpiresAt       pgtype.Timestamptz `json:"expiresAt"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) error {
+	_, err := q.db.Exec(ctx, createSession,
+		arg.ID,
+		arg.Kind,
+		arg.UserID,
+		arg.CartID,
+		arg.RefreshHash,
+		arg.AccessJti,
+		arg.AccessExpiresAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createShippingMethod = `-- name: CreateShippingMethod :one
+INSERT INTO shipping_methods (
+  zone_id, name, type, rate, free_over, min_days, max_days
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, zone_id, name, type, rate, free_over, min_days, max_days, active, created_at, updated_at
+`
+
+type CreateShippingMethodParams struct {
+	ZoneID   int32          `json:"zoneId"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Rate     pgtype.Numeric `json:"rate"`
+	FreeOver pgtype.Numeric `json:"freeOver"`
+	MinDays  pgtype.Int4    `json:"minDays"`
+	MaxDays  pgtype.Int4    `json:"maxDays"`
+}
+
+func (q *Queries) CreateShippingMethod(ctx context.Context, arg CreateShippingMethodParams) (ShippingMethod, error) {
+	row := q.db.QueryRow(ctx, createShippingMethod,
+		arg.ZoneID,
+		arg.Name,
+		arg.Type,
+		arg.Rate,
+		arg.FreeOver,
+		arg.MinDays,
+		arg.MaxDays,
+	)
+	var i ShippingMethod
+	err := row.Scan(
+		&i.ID,
+		&i.ZoneID,
+		&i.Name,
+		&i.Type,
+		&i.Rate,
+		&i.FreeOver,
+		&i.MinDays,
+		&i.MaxDays,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createShippingZone = `-- name: CreateShippingZone :one
+INSERT INTO shipping_zones (
+  name
+) VALUES (
+  $1
+)
+RETURNING id, name, created_at, updated_at
+`
+
+func (q *Queries) CreateShippingZone(ctx context.Context, name string) (ShippingZone, error) {
+	row := q.db.QueryRow(ctx, createShippingZone, name)
+	var i ShippingZone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createTaxRate = `-- name: CreateTaxRate :one
+INSERT INTO tax_rates (
+  name, country, state, postal_prefix, tax_category, rate
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, name, country, state, postal_prefix, tax_category, rate, created_at, updated_at
+`
+
+type CreateTaxRateParams struct {
+	Name         string         `json:"name"`
+	Country      string         `json:"country"`
+	State        string         `json:"state"`
+	PostalPrefix string         `json:"postalPrefix"`
+	TaxCategory  string         `json:"taxCategory"`
+	Rate         pgtype.Numeric `json:"rate"`
+}
+
+func (q *Queries) CreateTaxRate(ctx context.Context, arg CreateTaxRateParams) (TaxRate, error) {
+	row := q.db.QueryRow(ctx, createTaxRate,
+		arg.Name,
+		arg.Country,
+		arg.State,
+		arg.PostalPrefix,
+		arg.TaxCategory,
+		arg.Rate,
+	)
+	var i TaxRate
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Country,
+		&i.State,
+		&i.PostalPrefix,
+		&i.TaxCategory,
+		&i.Rate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (
+  email, password_hash, is_admin
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, email, password_hash, is_admin, created_at, updated_at
+`
+
+type CreateUserParams struct {
+	Email        string      `json:"email"`
+	PasswordHash string      `json:"passwordHash"`
+	IsAdmin      pgtype.Bool `json:"isAdmin"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createUser, arg.Email, arg.PasswordHash, arg.IsAdmin)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (
+  endpoint_id, event_id, event_type, payload
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (endpoint_id, event_id) DO NOTHING
+`
+
+type CreateWebhookDeliveryParams struct {
+	EndpointID int32  `json:"endpointId"`
+	EventID    int32  `json:"eventId"`
+	EventType  string `json:"eventType"`
+	Payload    []byte `json:"payload"`
+}
+
+// Webhook Deliveries
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, createWebhookDelivery,
+		arg.EndpointID,
+		arg.EventID,
+		arg.EventType,
+		arg.Payload,
+	)
+	return err
+}
+
+const createWebhookEndpoint = `-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (
+  url, description, event_types, secret
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, url, description, event_types, secret, enabled, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at
+`
+
+type CreateWebhookEndpointParams struct {
+	Url         string      `json:"url"`
+	Description pgtype.Text `json:"description"`
+	EventTypes  []string    `json:"eventTypes"`
+	Secret      string      `json:"secret"`
+}
+
+// Webhook Endpoints
+func (q *Queries) CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error) {
+	row := q.db.QueryRow(ctx, createWebhookEndpoint,
+		arg.Url,
+		arg.Description,
+		arg.EventTypes,
+		arg.Secret,
+	)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Description,
+		&i.EventTypes,
+		&i.Secret,
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deactivateUser = `-- name: DeactivateUser :execrows
+UPDATE users
+  SET is_admin = FALSE,
+  updated_at = NOW()
+WHERE id = $1 AND is_admin = TRUE
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deactivateUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCartItemsByCarts = `-- name: DeleteCartItemsByCarts :execrows
+DELETE FROM cart_items
+WHERE cart_id = ANY($1::uuid[])
+`
+
+// Cart Items
+func (q *Queries) DeleteCartItemsByCarts(ctx context.Context, cartIds []pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCartItemsByCarts, cartIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCartRecovery = `-- name: DeleteCartRecovery :exec
+DELETE FROM cart_recoveries
+WHERE id = $1
+`
+
+func (q *Queries) DeleteCartRecovery(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteCartRecovery, id)
+	return err
+}
+
+const deleteCarts = `-- name: DeleteCarts :execrows
+DELETE FROM carts
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteCarts(ctx context.Context, ids []pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCarts, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCollection = `-- name: DeleteCollection :exec
+DELETE FROM collections
+WHERE id = $1
+`
+
+func (q *Queries) DeleteCollection(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteCollection, id)
+	return err
+}
+
+const deleteCollectionImage = `-- name: DeleteCollectionImage :exec
+DELETE FROM collection_images
+WHERE collection_id = $1 AND image_url = $2
+`
+
+type DeleteCollectionImageParams struct {
+	CollectionID int32  `json:"collectionId"`
+	ImageUrl     string `json:"imageUrl"`
+}
+
+func (q *Queries) DeleteCollectionImage(ctx context.Context, arg DeleteCollectionImageParams) error {
+	_, err := q.db.Exec(ctx, deleteCollectionImage, arg.CollectionID, arg.ImageUrl)
+	return err
+}
+
+const deleteExpiredIdempotencyKeys = `-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at < NOW()
+`
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredIdempotencyKeys)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredRevokedTokens = `-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < NOW()
+`
+
+func (q *Queries) DeleteExpiredRevokedTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredRevokedTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at < NOW() OR revoked_at IS NOT NULL
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteIdempotencyKey = `-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE id = $1
+`
+
+func (q *Queries) DeleteIdempotencyKey(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteIdempotencyKey, id)
+	return err
+}
+
+const deleteMFARecoveryCodes = `-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteMFARecoveryCodes(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, deleteMFARecoveryCodes, userID)
+	return err
+}
+
+const deleteProduct = `-- name: DeleteProduct :exec
+DELETE FROM products
+WHERE id = $1
+`
+
+func (q *Queries) DeleteProduct(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteProduct, id)
+	return err
+}
+
+const deleteProductImage = `-- name: DeleteProductImage :exec
+DELETE FROM product_images
+WHERE product_id = $1 AND image_url = $2
+`
+
 type DeleteProductImageParams struct {
 	ProductID int32  `json:"productId"`
-	ImageUrl  string `json:"imageUrl"`
+	ImageUrl  string `json:"imageUrl"`
+}
+
+func (q *Queries) DeleteProductImage(ctx context.Context, arg DeleteProductImageParams) error {
+	_, err := q.db.Exec(ctx, deleteProductImage, arg.ProductID, arg.ImageUrl)
+	return err
+}
+
+const deleteShippingMethod = `-- name: DeleteShippingMethod :exec
+DELETE FROM shipping_methods
+WHERE id = $1
+`
+
+func (q *Queries) DeleteShippingMethod(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteShippingMethod, id)
+	return err
+}
+
+const deleteShippingZone = `-- name: DeleteShippingZone :exec
+DELETE FROM shipping_zones
+WHERE id = $1
+`
+
+func (q *Queries) DeleteShippingZone(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteShippingZone, id)
+	return err
+}
+
+const deleteStaleLoginFailures = `-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < $1
+  AND (locked_until IS NULL OR locked_until < NOW())
+`
+
+func (q *Queries) DeleteStaleLoginFailures(ctx context.Context, lastFailureAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleLoginFailures, lastFailureAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteStaleRateLimits = `-- name: DeleteStaleRateLimits :execrows
+DELETE FROM rate_limits
+WHERE updated_at < $1
+`
+
+func (q *Queries) DeleteStaleRateLimits(ctx context.Context, updatedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleRateLimits, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTaxRate = `-- name: DeleteTaxRate :exec
+DELETE FROM tax_rates
+WHERE id = $1
+`
+
+func (q *Queries) DeleteTaxRate(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteTaxRate, id)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :one
+DELETE FROM users
+WHERE id = $1 RETURNING id, email, password_hash, is_admin, created_at, updated_at
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, deleteUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteUserMFA = `-- name: DeleteUserMFA :execrows
+DELETE FROM user_mfa
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserMFA(ctx context.Context, userID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserMFA, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWebhookEndpoint = `-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookEndpoint(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookEndpoint, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enableUserMFA = `-- name: EnableUserMFA :execrows
+UPDATE user_mfa
+  SET enabled = TRUE,
+  enabled_at = NOW(),
+  last_step = $2
+WHERE user_id = $1 AND enabled = FALSE
+`
+
+type EnableUserMFAParams struct {
+	UserID   int32 `json:"userId"`
+	LastStep int64 `json:"lastStep"`
+}
+
+func (q *Queries) EnableUserMFA(ctx context.Context, arg EnableUserMFAParams) (int64, error) {
+	result, err := q.db.Exec(ctx, enableUserMFA, arg.UserID, arg.LastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enqueueEmail = `-- name: EnqueueEmail :one
+INSERT INTO email_outbox (
+  template, recipient, subject, html_body, text_body
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, template, recipient, subject, html_body, text_body, status, attempts, last_error, next_attempt_at, created_at, sent_at
+`
+
+type EnqueueEmailParams struct {
+	Template  string `json:"template"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	HtmlBody  string `json:"htmlBody"`
+	TextBody  string `json:"textBody"`
+}
+
+// Email Outbox
+func (q *Queries) EnqueueEmail(ctx context.Context, arg EnqueueEmailParams) (EmailOutbox, error) {
+	row := q.db.QueryRow(ctx, enqueueEmail,
+		arg.Template,
+		arg.Recipient,
+		arg.Subject,
+		arg.HtmlBody,
+		arg.TextBody,
+	)
+	var i EmailOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.Template,
+		&i.Recipient,
+		&i.Subject,
+		&i.HtmlBody,
+		&i.TextBody,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.CreatedAt,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const getCart = `-- name: GetCart :one
+SELECT id, email, created_at, updated_at FROM carts
+WHERE id = $1 LIMIT 1
+`
+
+// Carts
+func (q *Queries) GetCart(ctx context.Context, id pgtype.UUID) (Cart, error) {
+	row := q.db.QueryRow(ctx, getCart, id)
+	var i Cart
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getCartItemCount = `-- name: GetCartItemCount :one
+SELECT COUNT(*) FROM cart_items
+WHERE cart_id = $1
+`
+
+func (q *Queries) GetCartItemCount(ctx context.Context, cartID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, getCartItemCount, cartID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getCartItems = `-- name: GetCartItems :many
+SELECT 
+    ci.product_id, 
+    ci.quantity, 
+    ci.price_id,
+    p.name, 
+    p.description, 
+    p.price,
+    p.tax_category,
+    p.weight_grams
+FROM cart_items ci
+JOIN products p ON ci.product_id = p.id
+WHERE ci.cart_id = $1
+`
+
+type GetCartItemsRow struct {
+	ProductID   int32          `json:"productId"`
+	Quantity    int32          `json:"quantity"`
+	PriceID     string         `json:"priceId"`
+	Name        string         `json:"name"`
+	Description pgtype.Text    `json:"description"`
+	Price       pgtype.Numeric `json:"price"`
+	TaxCategory string         `json:"taxCategory"`
+	WeightGrams int32          `json:"weightGrams"`
+}
+
+func (q *Queries) GetCartItems(ctx context.Context, cartID pgtype.UUID) ([]GetCartItemsRow, error) {
+	rows, err := q.db.Query(ctx, getCartItems, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCartItemsRow
+	for rows.Next() {
+		var i GetCartItemsRow
+		if err := rows.Scan(
+			&i.ProductID,
+			&i.Quantity,
+			&i.PriceID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.TaxCategory,
+			&i.WeightGrams,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCartRecoveryStats = `-- name: GetCartRecoveryStats :one
+SELECT
+  COUNT(*)::int AS sent,
+  COUNT(cr.restored_at)::int AS restored,
+  COUNT(cr.converted_at)::int AS converted,
+  COALESCE(SUM(o.total), 0)::decimal AS recovered_revenue
+FROM cart_recoveries cr
+LEFT JOIN orders o ON o.id = cr.order_id
+WHERE cr.sent_at >= $1 AND cr.sent_at < $2
+`
+
+type GetCartRecoveryStatsParams struct {
+	SentFrom pgtype.Timestamptz `json:"sentFrom"`
+	SentTo   pgtype.Timestamptz `json:"sentTo"`
+}
+
+type GetCartRecoveryStatsRow struct {
+	Sent             int32          `json:"sent"`
+	Restored         int32          `json:"restored"`
+	Converted        int32          `json:"converted"`
+	RecoveredRevenue pgtype.Numeric `json:"recoveredRevenue"`
+}
+
+func (q *Queries) GetCartRecoveryStats(ctx context.Context, arg GetCartRecoveryStatsParams) (GetCartRecoveryStatsRow, error) {
+	row := q.db.QueryRow(ctx, getCartRecoveryStats, arg.SentFrom, arg.SentTo)
+	var i GetCartRecoveryStatsRow
+	err := row.Scan(
+		&i.Sent,
+		&i.Restored,
+		&i.Converted,
+		&i.RecoveredRevenue,
+	)
+	return i, err
+}
+
+const getCollection = `-- name: GetCollection :one
+SELECT id, name, description, created_at, updated_at FROM collections
+WHERE id = $1 LIMIT 1
+`
+
+// Collections
+func (q *Queries) GetCollection(ctx context.Context, id int32) (Collection, error) {
+	row := q.db.QueryRow(ctx, getCollection, id)
+	var i Collection
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getCollectionImages = `-- name: GetCollectionImages :many
+SELECT image_url FROM collection_images
+WHERE collection_id = $1
+`
+
+func (q *Queries) GetCollectionImages(ctx context.Context, collectionID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, getCollectionImages, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var image_url string
+		if err := rows.Scan(&image_url); err != nil {
+			return nil, err
+		}
+		items = append(items, image_url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCollectionProducts = `-- name: GetCollectionProducts :many
+SELECT p.id, p.name, p.description, p.price, p.price_id, p.tax_category, p.weight_grams, p.created_at, p.updated_at FROM products p
+JOIN collection_products cp ON p.id = cp.product_id
+WHERE cp.collection_id = $1
+`
+
+func (q *Queries) GetCollectionProducts(ctx context.Context, collectionID int32) ([]Product, error) {
+	rows, err := q.db.Query(ctx, getCollectionProducts, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.PriceID,
+			&i.TaxCategory,
+			&i.WeightGrams,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFulfillment = `-- name: GetFulfillment :one
+SELECT id, order_id, carrier, tracking_number, tracking_url, status, shipped_at, delivered_at, created_at, updated_at FROM fulfillments
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetFulfillment(ctx context.Context, id int32) (Fulfillment, error) {
+	row := q.db.QueryRow(ctx, getFulfillment, id)
+	var i Fulfillment
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.Carrier,
+		&i.TrackingNumber,
+		&i.TrackingUrl,
+		&i.Status,
+		&i.ShippedAt,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT id, key, scope, request_hash, status_code, response_headers, response_body, created_at, expires_at FROM idempotency_keys
+WHERE key = $1 AND scope = $2 LIMIT 1
+`
+
+type GetIdempotencyKeyParams struct {
+	Key   string `json:"key"`
+	Scope string `json:"scope"`
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, arg.Key, arg.Scope)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.ID,
+		&i.Key,
+		&i.Scope,
+		&i.RequestHash,
+		&i.StatusCode,
+		&i.ResponseHeaders,
+		&i.ResponseBody,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getLoginLockout = `-- name: GetLoginLockout :one
+SELECT COALESCE(MAX(EXTRACT(EPOCH FROM locked_until - NOW())), 0)::float8 AS seconds
+FROM login_failures
+WHERE key = ANY($1::text[]) AND locked_until > NOW()
+`
+
+func (q *Queries) GetLoginLockout(ctx context.Context, keys []string) (float64, error) {
+	row := q.db.QueryRow(ctx, getLoginLockout, keys)
+	var seconds float64
+	err := row.Scan(&seconds)
+	return seconds, err
+}
+
+const getOrder = `-- name: GetOrder :one
+SELECT id, cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive, shipping_method, shipping_total, status, fulfillment_status, created_at, updated_at FROM orders
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOrder(ctx context.Context, id int32) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrder, id)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CartID,
+		&i.CheckoutSessionID,
+		&i.Email,
+		&i.Currency,
+		&i.Subtotal,
+		&i.TaxTotal,
+		&i.Total,
+		&i.TaxInclusive,
+		&i.ShippingMethod,
+		&i.ShippingTotal,
+		&i.Status,
+		&i.FulfillmentStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getOrderByCheckoutSession = `-- name: GetOrderByCheckoutSession :one
+SELECT id, cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive, shipping_method, shipping_total, status, fulfillment_status, created_at, updated_at FROM orders
+WHERE checkout_session_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOrderByCheckoutSession(ctx context.Context, checkoutSessionID pgtype.Text) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderByCheckoutSession, checkoutSessionID)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CartID,
+		&i.CheckoutSessionID,
+		&i.Email,
+		&i.Currency,
+		&i.Subtotal,
+		&i.TaxTotal,
+		&i.Total,
+		&i.TaxInclusive,
+		&i.ShippingMethod,
+		&i.ShippingTotal,
+		&i.Status,
+		&i.FulfillmentStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getOrderForUpdate = `-- name: GetOrderForUpdate :one
+SELECT id, cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive, shipping_method, shipping_total, status, fulfillment_status, created_at, updated_at FROM orders
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetOrderForUpdate(ctx context.Context, id int32) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderForUpdate, id)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.CartID,
+		&i.CheckoutSessionID,
+		&i.Email,
+		&i.Currency,
+		&i.Subtotal,
+		&i.TaxTotal,
+		&i.Total,
+		&i.TaxInclusive,
+		&i.ShippingMethod,
+		&i.ShippingTotal,
+		&i.Status,
+		&i.FulfillmentStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getOrderItems = `-- name: GetOrderItems :many
+SELECT id, order_id, product_id, name, price_id, quantity, unit_price, tax_category, created_at, updated_at FROM order_items
+WHERE order_id = $1
+ORDER BY id
+`
+
+func (q *Queries) GetOrderItems(ctx context.Context, orderID int32) ([]OrderItem, error) {
+	rows, err := q.db.Query(ctx, getOrderItems, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderItem
+	for rows.Next() {
+		var i OrderItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ProductID,
+			&i.Name,
+			&i.PriceID,
+			&i.Quantity,
+			&i.UnitPrice,
+			&i.TaxCategory,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrderRefund = `-- name: GetOrderRefund :one
+SELECT id, order_id, return_id, amount, reason, status, provider_refund_id, failure_reason, created_by, created_at, updated_at FROM refunds
+WHERE id = $1 AND order_id = $2 LIMIT 1
+`
+
+type GetOrderRefundParams struct {
+	ID      int32 `json:"id"`
+	OrderID int32 `json:"orderId"`
+}
+
+func (q *Queries) GetOrderRefund(ctx context.Context, arg GetOrderRefundParams) (Refund, error) {
+	row := q.db.QueryRow(ctx, getOrderRefund, arg.ID, arg.OrderID)
+	var i Refund
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.ReturnID,
+		&i.Amount,
+		&i.Reason,
+		&i.Status,
+		&i.ProviderRefundID,
+		&i.FailureReason,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getOrderTaxLines = `-- name: GetOrderTaxLines :many
+SELECT id, order_id, name, jurisdiction, rate, taxable_amount, amount, created_at FROM order_tax_lines
+WHERE order_id = $1
+ORDER BY id
+`
+
+func (q *Queries) GetOrderTaxLines(ctx context.Context, orderID int32) ([]OrderTaxLine, error) {
+	rows, err := q.db.Query(ctx, getOrderTaxLines, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderTaxLine
+	for rows.Next() {
+		var i OrderTaxLine
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.Name,
+			&i.Jurisdiction,
+			&i.Rate,
+			&i.TaxableAmount,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProduct = `-- name: GetProduct :one
+SELECT id, name, description, price, price_id, tax_category, weight_grams, created_at, updated_at FROM products
+WHERE id = $1 LIMIT 1
+`
+
+// Products
+func (q *Queries) GetProduct(ctx context.Context, id int32) (Product, error) {
+	row := q.db.QueryRow(ctx, getProduct, id)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.PriceID,
+		&i.TaxCategory,
+		&i.WeightGrams,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProductFitGuide = `-- name: GetProductFitGuide :one
+SELECT id, product_id, body_length, sleeve_length, chest_width, shoulder_width, arm_hole, front_rise, inseam, hem, back_rise, waist, thigh, knee, leg_opening, created_at, updated_at FROM fit_guides
+WHERE product_id = $1 LIMIT 1
+`
+
+// Fit Guide
+func (q *Queries) GetProductFitGuide(ctx context.Context, productID int32) (FitGuide, error) {
+	row := q.db.QueryRow(ctx, getProductFitGuide, productID)
+	var i FitGuide
+	err := row.Scan(
+		&i.ID,
+		&i.ProductID,
+		&i.BodyLength,
+		&i.SleeveLength,
+		&i.ChestWidth,
+		&i.ShoulderWidth,
+		&i.ArmHole,
+		&i.FrontRise,
+		&i.Inseam,
+		&i.Hem,
+		&i.BackRise,
+		&i.Waist,
+		&i.Thigh,
+		&i.Knee,
+		&i.LegOpening,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProductImages = `-- name: GetProductImages :many
+SELECT image_url FROM product_images
+WHERE product_id = $1
+`
+
+func (q *Queries) GetProductImages(ctx context.Context, productID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, getProductImages, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var image_url string
+		if err := rows.Scan(&image_url); err != nil {
+			return nil, err
+		}
+		items = append(items, image_url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProductSizes = `-- name: GetProductSizes :many
+SELECT size_name, stock FROM product_sizes
+WHERE product_id = $1
+`
+
+type GetProductSizesRow struct {
+	SizeName string `json:"sizeName"`
+	Stock    int32  `json:"stock"`
+}
+
+func (q *Queries) GetProductSizes(ctx context.Context, productID int32) ([]GetProductSizesRow, error) {
+	rows, err := q.db.Query(ctx, getProductSizes, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProductSizesRow
+	for rows.Next() {
+		var i GetProductSizesRow
+		if err := rows.Scan(&i.SizeName, &i.Stock); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefundedTotal = `-- name: GetRefundedTotal :one
+SELECT COALESCE(SUM(amount), 0)::decimal AS refunded
+FROM refunds
+WHERE order_id = $1 AND status <> 'failed'
+`
+
+func (q *Queries) GetRefundedTotal(ctx context.Context, orderID int32) (pgtype.Numeric, error) {
+	row := q.db.QueryRow(ctx, getRefundedTotal, orderID)
+	var refunded pgtype.Numeric
+	err := row.Scan(&refunded)
+	return refunded, err
+}
+
+const getReturn = `-- name: GetReturn :one
+SELECT id, order_id, email, reason, status, note, created_at, updated_at FROM returns
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetReturn(ctx context.Context, id int32) (Return, error) {
+	row := q.db.QueryRow(ctx, getReturn, id)
+	var i Return
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.Email,
+		&i.Reason,
+		&i.Status,
+		&i.Note,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getReturnedQuantity = `-- name: GetReturnedQuantity :one
+SELECT COALESCE(SUM(ri.quantity), 0)::int AS returned
+FROM return_items ri
+JOIN returns r ON r.id = ri.return_id
+WHERE ri.order_item_id = $1 AND r.status <> 'rejected'
+`
+
+func (q *Queries) GetReturnedQuantity(ctx context.Context, orderItemID int32) (int32, error) {
+	row := q.db.QueryRow(ctx, getReturnedQuantity, orderItemID)
+	var returned int32
+	err := row.Scan(&returned)
+	return returned, err
+}
+
+const getReturnForUpdate = `-- name: GetReturnForUpdate :one
+SELECT id, order_id, email, reason, status, note, created_at, updated_at FROM returns
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetReturnForUpdate(ctx context.Context, id int32) (Return, error) {
+	row := q.db.QueryRow(ctx, getReturnForUpdate, id)
+	var i Return
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.Email,
+		&i.Reason,
+		&i.Status,
+		&i.Note,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getReturnItems = `-- name: GetReturnItems :many
+SELECT id, return_id, order_item_id, quantity, size_name, reason, restocked, created_at FROM return_items
+WHERE return_id = $1
+ORDER BY id
+`
+
+func (q *Queries) GetReturnItems(ctx context.Context, returnID int32) ([]ReturnItem, error) {
+	rows, err := q.db.Query(ctx, getReturnItems, returnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReturnItem
+	for rows.Next() {
+		var i ReturnItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReturnID,
+			&i.OrderItemID,
+			&i.Quantity,
+			&i.SizeName,
+			&i.Reason,
+			&i.Restocked,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getShippingMethod = `-- name: GetShippingMethod :one
+SELECT id, zone_id, name, type, rate, free_over, min_days, max_days, active, created_at, updated_at FROM shipping_methods
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetShippingMethod(ctx context.Context, id int32) (ShippingMethod, error) {
+	row := q.db.QueryRow(ctx, getShippingMethod, id)
+	var i ShippingMethod
+	err := row.Scan(
+		&i.ID,
+		&i.ZoneID,
+		&i.Name,
+		&i.Type,
+		&i.Rate,
+		&i.FreeOver,
+		&i.MinDays,
+		&i.MaxDays,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getShippingZone = `-- name: GetShippingZone :one
+SELECT id, name, created_at, updated_at FROM shipping_zones
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetShippingZone(ctx context.Context, id int32) (ShippingZone, error) {
+	row := q.db.QueryRow(ctx, getShippingZone, id)
+	var i ShippingZone
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, email, password_hash, is_admin, created_at, updated_at FROM users
+WHERE id = $1 LIMIT 1
+`
+
+// Users
+func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, password_hash, is_admin, created_at, updated_at FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserMFA = `-- name: GetUserMFA :one
+SELECT user_id, secret, enabled, last_step, created_at, enabled_at FROM user_mfa
+WHERE user_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserMFA(ctx context.Context, userID int32) (UserMfa, error) {
+	row := q.db.QueryRow(ctx, getUserMFA, userID)
+	var i UserMfa
+	err := row.Scan(
+		&i.UserID,
+		&i.Secret,
+		&i.Enabled,
+		&i.LastStep,
+		&i.CreatedAt,
+		&i.EnabledAt,
+	)
+	return i, err
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, endpoint_id, event_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at FROM webhook_deliveries
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id int32) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.EndpointID,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.ResponseStatus,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const getWebhookEndpoint = `-- name: GetWebhookEndpoint :one
+SELECT id, url, description, event_types, secret, enabled, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at FROM webhook_endpoints
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookEndpoint(ctx context.Context, id int32) (WebhookEndpoint, error) {
+	row := q.db.QueryRow(ctx, getWebhookEndpoint, id)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Description,
+		&i.EventTypes,
+		&i.Secret,
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const incrementProductStock = `-- name: IncrementProductStock :execrows
+UPDATE product_sizes
+  SET stock = stock + $3,
+  updated_at = NOW()
+WHERE product_id = $1 AND size_name = $2
+`
+
+type IncrementProductStockParams struct {
+	ProductID int32  `json:"productId"`
+	SizeName  string `json:"sizeName"`
+	Quantity  int32  `json:"quantity"`
+}
+
+func (q *Queries) IncrementProductStock(ctx context.Context, arg IncrementProductStockParams) (int64, error) {
+	result, err := q.db.Exec(ctx, incrementProductStock, arg.ProductID, arg.SizeName, arg.Quantity)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const isTokenRevoked = `-- name: IsTokenRevoked :one
+SELECT EXISTS (
+  SELECT 1 FROM revoked_tokens WHERE jti = $1
+)
+`
+
+func (q *Queries) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	row := q.db.QueryRow(ctx, isTokenRevoked, jti)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listAbandonedCarts = `-- name: ListAbandonedCarts :many
+SELECT c.id, c.email, c.updated_at FROM carts c
+WHERE c.email IS NOT NULL
+  AND c.updated_at < $1
+  AND c.updated_at > $2
+  AND EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = c.id)
+  AND NOT EXISTS (SELECT 1 FROM cart_recoveries cr WHERE cr.cart_id = c.id)
+  AND NOT EXISTS (
+    SELECT 1 FROM orders o
+    WHERE o.cart_id = c.id AND o.status NOT IN ('pending_payment', 'cancelled')
+  )
+ORDER BY c.updated_at
+LIMIT $3
+`
+
+type ListAbandonedCartsParams struct {
+	IdleBefore pgtype.Timestamptz `json:"idleBefore"`
+	IdleAfter  pgtype.Timestamptz `json:"idleAfter"`
+	BatchSize  int32              `json:"batchSize"`
+}
+
+type ListAbandonedCartsRow struct {
+	ID        pgtype.UUID        `json:"id"`
+	Email     pgtype.Text        `json:"email"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+}
+
+// Cart Recoveries
+func (q *Queries) ListAbandonedCarts(ctx context.Context, arg ListAbandonedCartsParams) ([]ListAbandonedCartsRow, error) {
+	rows, err := q.db.Query(ctx, listAbandonedCarts, arg.IdleBefore, arg.IdleAfter, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAbandonedCartsRow
+	for rows.Next() {
+		var i ListAbandonedCartsRow
+		if err := rows.Scan(&i.ID, &i.Email, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCollections = `-- name: ListCollections :many
+SELECT id, name, description, created_at, updated_at FROM collections
+ORDER BY name
+`
+
+func (q *Queries) ListCollections(ctx context.Context) ([]Collection, error) {
+	rows, err := q.db.Query(ctx, listCollections)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Collection
+	for rows.Next() {
+		var i Collection
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDomainEventDeliveries = `-- name: ListDomainEventDeliveries :many
+SELECT subscriber FROM domain_event_deliveries
+WHERE event_id = $1
+`
+
+func (q *Queries) ListDomainEventDeliveries(ctx context.Context, eventID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, listDomainEventDeliveries, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var subscriber string
+		if err := rows.Scan(&subscriber); err != nil {
+			return nil, err
+		}
+		items = append(items, subscriber)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDomainEvents = `-- name: ListDomainEvents :many
+SELECT id, event_type, aggregate_type, aggregate_id, payload, status, attempts, last_error, next_attempt_at, created_at, dispatched_at FROM domain_events
+ORDER BY created_at DESC
+LIMIT $1
+`
+
+func (q *Queries) ListDomainEvents(ctx context.Context, limit int32) ([]DomainEvent, error) {
+	rows, err := q.db.Query(ctx, listDomainEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DomainEvent
+	for rows.Next() {
+		var i DomainEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.DispatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEmails = `-- name: ListEmails :many
+SELECT id, template, recipient, subject, html_body, text_body, status, attempts, last_error, next_attempt_at, created_at, sent_at FROM email_outbox
+ORDER BY created_at DESC
+LIMIT $1
+`
+
+func (q *Queries) ListEmails(ctx context.Context, limit int32) ([]EmailOutbox, error) {
+	rows, err := q.db.Query(ctx, listEmails, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EmailOutbox
+	for rows.Next() {
+		var i EmailOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Template,
+			&i.Recipient,
+			&i.Subject,
+			&i.HtmlBody,
+			&i.TextBody,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredCarts = `-- name: ListExpiredCarts :many
+SELECT id FROM carts
+WHERE updated_at < $1
+  AND NOT EXISTS (
+    SELECT 1 FROM cart_recoveries cr
+    WHERE cr.cart_id = carts.id AND cr.sent_at > $3
+  )
+ORDER BY updated_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED
+`
+
+type ListExpiredCartsParams struct {
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+	Limit     int32              `json:"limit"`
+	SentAt    pgtype.Timestamptz `json:"sentAt"`
+}
+
+func (q *Queries) ListExpiredCarts(ctx context.Context, arg ListExpiredCartsParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listExpiredCarts, arg.UpdatedAt, arg.Limit, arg.SentAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderEvents = `-- name: ListOrderEvents :many
+SELECT id, order_id, from_status, to_status, actor, user_id, note, created_at FROM order_events
+WHERE order_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListOrderEvents(ctx context.Context, orderID int32) ([]OrderEvent, error) {
+	rows, err := q.db.Query(ctx, listOrderEvents, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OrderEvent
+	for rows.Next() {
+		var i OrderEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.FromStatus,
+			&i.ToStatus,
+			&i.Actor,
+			&i.UserID,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderFulfillmentItems = `-- name: ListOrderFulfillmentItems :many
+SELECT fi.id, fi.fulfillment_id, fi.order_item_id, fi.quantity FROM fulfillment_items fi
+JOIN fulfillments f ON f.id = fi.fulfillment_id
+WHERE f.order_id = $1
+ORDER BY fi.id
+`
+
+func (q *Queries) ListOrderFulfillmentItems(ctx context.Context, orderID int32) ([]FulfillmentItem, error) {
+	rows, err := q.db.Query(ctx, listOrderFulfillmentItems, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FulfillmentItem
+	for rows.Next() {
+		var i FulfillmentItem
+		if err := rows.Scan(
+			&i.ID,
+			&i.FulfillmentID,
+			&i.OrderItemID,
+			&i.Quantity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderFulfillments = `-- name: ListOrderFulfillments :many
+SELECT id, order_id, carrier, tracking_number, tracking_url, status, shipped_at, delivered_at, created_at, updated_at FROM fulfillments
+WHERE order_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListOrderFulfillments(ctx context.Context, orderID int32) ([]Fulfillment, error) {
+	rows, err := q.db.Query(ctx, listOrderFulfillments, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Fulfillment
+	for rows.Next() {
+		var i Fulfillment
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.Carrier,
+			&i.TrackingNumber,
+			&i.TrackingUrl,
+			&i.Status,
+			&i.ShippedAt,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderRefundEvents = `-- name: ListOrderRefundEvents :many
+SELECT re.id, re.refund_id, re.status, re.message, re.created_at FROM refund_events re
+JOIN refunds rf ON rf.id = re.refund_id
+WHERE rf.order_id = $1
+ORDER BY re.created_at, re.id
+`
+
+func (q *Queries) ListOrderRefundEvents(ctx context.Context, orderID int32) ([]RefundEvent, error) {
+	rows, err := q.db.Query(ctx, listOrderRefundEvents, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefundEvent
+	for rows.Next() {
+		var i RefundEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.RefundID,
+			&i.Status,
+			&i.Message,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrderRefunds = `-- name: ListOrderRefunds :many
+SELECT id, order_id, return_id, amount, reason, status, provider_refund_id, failure_reason, created_by, created_at, updated_at FROM refunds
+WHERE order_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListOrderRefunds(ctx context.Context, orderID int32) ([]Refund, error) {
+	rows, err := q.db.Query(ctx, listOrderRefunds, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Refund
+	for rows.Next() {
+		var i Refund
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ReturnID,
+			&i.Amount,
+			&i.Reason,
+			&i.Status,
+			&i.ProviderRefundID,
+			&i.FailureReason,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrders = `-- name: ListOrders :many
+SELECT id, cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive, shipping_method, shipping_total, status, fulfillment_status, created_at, updated_at FROM orders
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListOrders(ctx context.Context) ([]Order, error) {
+	rows, err := q.db.Query(ctx, listOrders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Order
+	for rows.Next() {
+		var i Order
+		if err := rows.Scan(
+			&i.ID,
+			&i.CartID,
+			&i.CheckoutSessionID,
+			&i.Email,
+			&i.Currency,
+			&i.Subtotal,
+			&i.TaxTotal,
+			&i.Total,
+			&i.TaxInclusive,
+			&i.ShippingMethod,
+			&i.ShippingTotal,
+			&i.Status,
+			&i.FulfillmentStatus,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProducts = `-- name: ListProducts :many
+SELECT id, name, description, price, price_id, tax_category, weight_grams, created_at, updated_at FROM products
+ORDER BY name
+`
+
+func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
+	rows, err := q.db.Query(ctx, listProducts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.PriceID,
+			&i.TaxCategory,
+			&i.WeightGrams,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) DeleteProductImage(ctx context.Context, arg DeleteProductImageParams) error {
-	_, err := q.db.Exec(ctx, deleteProductImage, arg.ProductID, arg.ImageUrl)
-	return err
+const listReturns = `-- name: ListReturns :many
+SELECT id, order_id, email, reason, status, note, created_at, updated_at FROM returns
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListReturns(ctx context.Context) ([]Return, error) {
+	rows, err := q.db.Query(ctx, listReturns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Return
+	for rows.Next() {
+		var i Return
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.Email,
+			&i.Reason,
+			&i.Status,
+			&i.Note,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const deleteUser = `-- name: DeleteUser :one
-DELETE FROM users
-WHERE id = $1 RETURNING id, email, password_hash, is_admin, created_at, updated_at
+const listSchemaColumns = `-- name: ListSchemaColumns :many
+SELECT table_name::text, column_name::text FROM information_schema.columns
+WHERE table_schema = current_schema()
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id int32) (User, error) {
-	row := q.db.QueryRow(ctx, deleteUser, id)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Email,
-		&i.PasswordHash,
-		&i.IsAdmin,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+type ListSchemaColumnsRow struct {
+	TableName  string `json:"tableName"`
+	ColumnName string `json:"columnName"`
 }
 
-const getCart = `-- name: GetCart :one
-SELECT id, created_at, updated_at FROM carts
-WHERE id = $1 LIMIT 1
+func (q *Queries) ListSchemaColumns(ctx context.Context) ([]ListSchemaColumnsRow, error) {
+	rows, err := q.db.Query(ctx, listSchemaColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSchemaColumnsRow
+	for rows.Next() {
+		var i ListSchemaColumnsRow
+		if err := rows.Scan(&i.TableName, &i.ColumnName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSecurityEvents = `-- name: ListSecurityEvents :many
+SELECT id, event_type, user_id, email, ip, user_agent, detail, created_at FROM security_events
+WHERE $1::text = '' OR email = $1
+ORDER BY created_at DESC
+LIMIT $2
 `
 
-// Carts
-func (q *Queries) GetCart(ctx context.Context, id pgtype.UUID) (Cart, error) {
-	row := q.db.QueryRow(ctx, getCart, id)
-	var i Cart
-	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
-	return i, err
+type ListSecurityEventsParams struct {
+	Email   string `json:"email"`
+	MaxRows int32  `json:"maxRows"`
 }
 
-const getCartItemCount = `-- name: GetCartItemCount :one
-SELECT COUNT(*) FROM cart_items
-WHERE cart_id = $1
+func (q *Queries) ListSecurityEvents(ctx context.Context, arg ListSecurityEventsParams) ([]SecurityEvent, error) {
+	rows, err := q.db.Query(ctx, listSecurityEvents, arg.Email, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SecurityEvent
+	for rows.Next() {
+		var i SecurityEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.UserID,
+			&i.Email,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Detail,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShippingMethods = `-- name: ListShippingMethods :many
+SELECT id, zone_id, name, type, rate, free_over, min_days, max_days, active, created_at, updated_at FROM shipping_methods
+ORDER BY zone_id, rate
 `
 
-func (q *Queries) GetCartItemCount(ctx context.Context, cartID pgtype.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, getCartItemCount, cartID)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+// Shipping Methods
+func (q *Queries) ListShippingMethods(ctx context.Context) ([]ShippingMethod, error) {
+	rows, err := q.db.Query(ctx, listShippingMethods)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ShippingMethod
+	for rows.Next() {
+		var i ShippingMethod
+		if err := rows.Scan(
+			&i.ID,
+			&i.ZoneID,
+			&i.Name,
+			&i.Type,
+			&i.Rate,
+			&i.FreeOver,
+			&i.MinDays,
+			&i.MaxDays,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getCartItems = `-- name: GetCartItems :many
-SELECT 
-    ci.product_id, 
-    ci.quantity, 
-    ci.price_id,
-    p.name, 
-    p.description, 
-    p.price
-FROM cart_items ci
-JOIN products p ON ci.product_id = p.id
-WHERE ci.cart_id = $1
+const listShippingMethodTiers = `-- name: ListShippingMethodTiers :many
+SELECT id, method_id, min_value, rate, created_at FROM shipping_method_tiers
+ORDER BY method_id, min_value
 `
 
-type GetCartItemsRow struct {
-	ProductID   int32          `json:"productId"`
-	Quantity    int32          `json:"quantity"`
-	PriceID     string         `json:"priceId"`
-	Name        string         `json:"name"`
-	Description pgtype.Text    `json:"description"`
-	Price       pgtype.Numeric `json:"price"`
+func (q *Queries) ListShippingMethodTiers(ctx context.Context) ([]ShippingMethodTier, error) {
+	rows, err := q.db.Query(ctx, listShippingMethodTiers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ShippingMethodTier
+	for rows.Next() {
+		var i ShippingMethodTier
+		if err := rows.Scan(
+			&i.ID,
+			&i.MethodID,
+			&i.MinValue,
+			&i.Rate,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-// Cart Items
-func (q *Queries) GetCartItems(ctx context.Context, cartID pgtype.UUID) ([]GetCartItemsRow, error) {
-	rows, err := q.db.Query(ctx, getCartItems, cartID)
+const listShippingZoneRegions = `-- name: ListShippingZoneRegions :many
+SELECT id, zone_id, country, state, created_at FROM shipping_zone_regions
+ORDER BY zone_id, country, state
+`
+
+func (q *Queries) ListShippingZoneRegions(ctx context.Context) ([]ShippingZoneRegion, error) {
+	rows, err := q.db.Query(ctx, listShippingZoneRegions)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetCartItemsRow
+	var items []ShippingZoneRegion
 	for rows.Next() {
-		var i GetCartItemsRow
+		var i ShippingZoneRegion
 		if err := rows.Scan(
-			&i.ProductID,
-			&i.Quantity,
-			&i.PriceID,
+			&i.ID,
+			&i.ZoneID,
+			&i.Country,
+			&i.State,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShippingZones = `-- name: ListShippingZones :many
+SELECT id, name, created_at, updated_at FROM shipping_zones
+ORDER BY name
+`
+
+// Shipping Zones
+func (q *Queries) ListShippingZones(ctx context.Context) ([]ShippingZone, error) {
+	rows, err := q.db.Query(ctx, listShippingZones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ShippingZone
+	for rows.Next() {
+		var i ShippingZone
+		if err := rows.Scan(
+			&i.ID,
 			&i.Name,
-			&i.Description,
-			&i.Price,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -416,43 +3126,70 @@ func (q *Queries) GetCartItems(ctx context.Context, cartID pgtype.UUID) ([]GetCa
 	return items, nil
 }
 
-const getCollection = `-- name: GetCollection :one
-SELECT id, name, description, created_at, updated_at FROM collections
-WHERE id = $1 LIMIT 1
+const listTaxRates = `-- name: ListTaxRates :many
+SELECT id, name, country, state, postal_prefix, tax_category, rate, created_at, updated_at FROM tax_rates
+ORDER BY country, state, postal_prefix, tax_category
 `
 
-// Collections
-func (q *Queries) GetCollection(ctx context.Context, id int32) (Collection, error) {
-	row := q.db.QueryRow(ctx, getCollection, id)
-	var i Collection
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+// Tax Rates
+func (q *Queries) ListTaxRates(ctx context.Context) ([]TaxRate, error) {
+	rows, err := q.db.Query(ctx, listTaxRates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TaxRate
+	for rows.Next() {
+		var i TaxRate
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Country,
+			&i.State,
+			&i.PostalPrefix,
+			&i.TaxCategory,
+			&i.Rate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getCollectionImages = `-- name: GetCollectionImages :many
-SELECT image_url FROM collection_images
-WHERE collection_id = $1
+const listTaxRatesByCountry = `-- name: ListTaxRatesByCountry :many
+SELECT id, name, country, state, postal_prefix, tax_category, rate, created_at, updated_at FROM tax_rates
+WHERE country = $1
 `
 
-func (q *Queries) GetCollectionImages(ctx context.Context, collectionID int32) ([]string, error) {
-	rows, err := q.db.Query(ctx, getCollectionImages, collectionID)
+func (q *Queries) ListTaxRatesByCountry(ctx context.Context, country string) ([]TaxRate, error) {
+	rows, err := q.db.Query(ctx, listTaxRatesByCountry, country)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []string
+	var items []TaxRate
 	for rows.Next() {
-		var image_url string
-		if err := rows.Scan(&image_url); err != nil {
+		var i TaxRate
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Country,
+			&i.State,
+			&i.PostalPrefix,
+			&i.TaxCategory,
+			&i.Rate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
 			return nil, err
 		}
-		items = append(items, image_url)
+		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -460,27 +3197,25 @@ func (q *Queries) GetCollectionImages(ctx context.Context, collectionID int32) (
 	return items, nil
 }
 
-const getCollectionProducts = `-- name: GetCollectionProducts :many
-SELECT p.id, p.name, p.description, p.price, p.price_id, p.created_at, p.updated_at FROM products p
-JOIN collection_products cp ON p.id = cp.product_id
-WHERE cp.collection_id = $1
+const listUsers = `-- name: ListUsers :many
+SELECT id, email, password_hash, is_admin, created_at, updated_at FROM users 
+ORDER BY created_at DESC
 `
 
-func (q *Queries) GetCollectionProducts(ctx context.Context, collectionID int32) ([]Product, error) {
-	rows, err := q.db.Query(ctx, getCollectionProducts, collectionID)
+func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsers)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Product
+	var items []User
 	for rows.Next() {
-		var i Product
+		var i User
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.Price,
-			&i.PriceID,
+			&i.Email,
+			&i.PasswordHash,
+			&i.IsAdmin,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -494,76 +3229,44 @@ func (q *Queries) GetCollectionProducts(ctx context.Context, collectionID int32)
 	return items, nil
 }
 
-const getProduct = `-- name: GetProduct :one
-SELECT id, name, description, price, price_id, created_at, updated_at FROM products
-WHERE id = $1 LIMIT 1
-`
-
-// Products
-func (q *Queries) GetProduct(ctx context.Context, id int32) (Product, error) {
-	row := q.db.QueryRow(ctx, getProduct, id)
-	var i Product
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.Price,
-		&i.PriceID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const getProductFitGuide = `-- name: GetProductFitGuide :one
-SELECT id, product_id, body_length, sleeve_length, chest_width, shoulder_width, arm_hole, front_rise, inseam, hem, back_rise, waist, thigh, knee, leg_opening, created_at, updated_at FROM fit_guides
-WHERE product_id = $1 LIMIT 1
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, endpoint_id, event_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2
 `
 
-// Fit Guide
-func (q *Queries) GetProductFitGuide(ctx context.Context, productID int32) (FitGuide, error) {
-	row := q.db.QueryRow(ctx, getProductFitGuide, productID)
-	var i FitGuide
-	err := row.Scan(
-		&i.ID,
-		&i.ProductID,
-		&i.BodyLength,
-		&i.SleeveLength,
-		&i.ChestWidth,
-		&i.ShoulderWidth,
-		&i.ArmHole,
-		&i.FrontRise,
-		&i.Inseam,
-		&i.Hem,
-		&i.BackRise,
-		&i.Waist,
-		&i.Thigh,
-		&i.Knee,
-		&i.LegOpening,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+type ListWebhookDeliveriesParams struct {
+	EndpointID int32 `json:"endpointId"`
+	Limit      int32 `json:"limit"`
 }
 
-const getProductImages = `-- name: GetProductImages :many
-SELECT image_url FROM product_images
-WHERE product_id = $1
-`
-
-func (q *Queries) GetProductImages(ctx context.Context, productID int32) ([]string, error) {
-	rows, err := q.db.Query(ctx, getProductImages, productID)
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveries, arg.EndpointID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []string
+	var items []WebhookDelivery
 	for rows.Next() {
-		var image_url string
-		if err := rows.Scan(&image_url); err != nil {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.EndpointID,
+			&i.EventID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
 			return nil, err
 		}
-		items = append(items, image_url)
+		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -571,26 +3274,30 @@ func (q *Queries) GetProductImages(ctx context.Context, productID int32) ([]stri
 	return items, nil
 }
 
-const getProductSizes = `-- name: GetProductSizes :many
-SELECT size_name, stock FROM product_sizes
-WHERE product_id = $1
+const listWebhookDeliveryAttempts = `-- name: ListWebhookDeliveryAttempts :many
+SELECT id, delivery_id, response_status, response_body, error, duration_ms, created_at FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY created_at
 `
 
-type GetProductSizesRow struct {
-	SizeName string `json:"sizeName"`
-	Stock    int32  `json:"stock"`
-}
-
-func (q *Queries) GetProductSizes(ctx context.Context, productID int32) ([]GetProductSizesRow, error) {
-	rows, err := q.db.Query(ctx, getProductSizes, productID)
+func (q *Queries) ListWebhookDeliveryAttempts(ctx context.Context, deliveryID int32) ([]WebhookDeliveryAttempt, error) {
+	rows, err := q.db.Query(ctx, listWebhookDeliveryAttempts, deliveryID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetProductSizesRow
+	var items []WebhookDeliveryAttempt
 	for rows.Next() {
-		var i GetProductSizesRow
-		if err := rows.Scan(&i.SizeName, &i.Stock); err != nil {
+		var i WebhookDeliveryAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeliveryID,
+			&i.ResponseStatus,
+			&i.ResponseBody,
+			&i.Error,
+			&i.DurationMs,
+			&i.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -601,68 +3308,30 @@ func (q *Queries) GetProductSizes(ctx context.Context, productID int32) ([]GetPr
 	return items, nil
 }
 
-const getUser = `-- name: GetUser :one
-SELECT id, email, password_hash, is_admin, created_at, updated_at FROM users
-WHERE id = $1 LIMIT 1
-`
-
-// Users
-func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
-	row := q.db.QueryRow(ctx, getUser, id)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Email,
-		&i.PasswordHash,
-		&i.IsAdmin,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const getUserByEmailAndPassword = `-- name: GetUserByEmailAndPassword :one
-SELECT id, email, password_hash, is_admin, created_at, updated_at FROM users
-WHERE email = $1 and password_hash = $2 LIMIT 1
-`
-
-type GetUserByEmailAndPasswordParams struct {
-	Email        string `json:"email"`
-	PasswordHash string `json:"passwordHash"`
-}
-
-func (q *Queries) GetUserByEmailAndPassword(ctx context.Context, arg GetUserByEmailAndPasswordParams) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByEmailAndPassword, arg.Email, arg.PasswordHash)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Email,
-		&i.PasswordHash,
-		&i.IsAdmin,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const listCollections = `-- name: ListCollections :many
-SELECT id, name, description, created_at, updated_at FROM collections
-ORDER BY name
+const listWebhookEndpoints = `-- name: ListWebhookEndpoints :many
+SELECT id, url, description, event_types, secret, enabled, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at FROM webhook_endpoints
+ORDER BY id
 `
 
-func (q *Queries) ListCollections(ctx context.Context) ([]Collection, error) {
-	rows, err := q.db.Query(ctx, listCollections)
+func (q *Queries) ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	rows, err := q.db.Query(ctx, listWebhookEndpoints)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Collection
+	var items []WebhookEndpoint
 	for rows.Next() {
-		var i Collection
+		var i WebhookEndpoint
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
+			&i.Url,
 			&i.Description,
+			&i.EventTypes,
+			&i.Secret,
+			&i.Enabled,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+			&i.DisabledReason,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -676,26 +3345,30 @@ func (q *Queries) ListCollections(ctx context.Context) ([]Collection, error) {
 	return items, nil
 }
 
-const listProducts = `-- name: ListProducts :many
-SELECT id, name, description, price, price_id, created_at, updated_at FROM products
-ORDER BY name
+const listWebhookEndpointsForEvent = `-- name: ListWebhookEndpointsForEvent :many
+SELECT id, url, description, event_types, secret, enabled, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at FROM webhook_endpoints
+WHERE enabled AND ($1::text = ANY(event_types) OR '*' = ANY(event_types))
 `
 
-func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
-	rows, err := q.db.Query(ctx, listProducts)
+func (q *Queries) ListWebhookEndpointsForEvent(ctx context.Context, eventType string) ([]WebhookEndpoint, error) {
+	rows, err := q.db.Query(ctx, listWebhookEndpointsForEvent, eventType)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Product
+	var items []WebhookEndpoint
 	for rows.Next() {
-		var i Product
+		var i WebhookEndpoint
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
+			&i.Url,
 			&i.Description,
-			&i.Price,
-			&i.PriceID,
+			&i.EventTypes,
+			&i.Secret,
+			&i.Enabled,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+			&i.DisabledReason,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -709,36 +3382,268 @@ func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
 	return items, nil
 }
 
-const listUsers = `-- name: ListUsers :many
-SELECT id, email, password_hash, is_admin, created_at, updated_at FROM users 
-ORDER BY created_at DESC
+const lockLogin = `-- name: LockLogin :exec
+UPDATE login_failures
+  SET locked_until = NOW() + make_interval(secs => $1::float8)
+WHERE key = $2
+`
+
+type LockLoginParams struct {
+	Seconds float64 `json:"seconds"`
+	Key     string  `json:"key"`
+}
+
+func (q *Queries) LockLogin(ctx context.Context, arg LockLoginParams) error {
+	_, err := q.db.Exec(ctx, lockLogin, arg.Seconds, arg.Key)
+	return err
+}
+
+const markCartRecoveryConverted = `-- name: MarkCartRecoveryConverted :exec
+UPDATE cart_recoveries
+  SET order_id = $2,
+  converted_at = NOW()
+WHERE cart_id = $1 AND converted_at IS NULL
+`
+
+type MarkCartRecoveryConvertedParams struct {
+	CartID  pgtype.UUID `json:"cartId"`
+	OrderID pgtype.Int4 `json:"orderId"`
+}
+
+func (q *Queries) MarkCartRecoveryConverted(ctx context.Context, arg MarkCartRecoveryConvertedParams) error {
+	_, err := q.db.Exec(ctx, markCartRecoveryConverted, arg.CartID, arg.OrderID)
+	return err
+}
+
+const markCartRecoveryRestored = `-- name: MarkCartRecoveryRestored :exec
+UPDATE cart_recoveries
+  SET restored_at = NOW()
+WHERE cart_id = $1 AND restored_at IS NULL
+`
+
+func (q *Queries) MarkCartRecoveryRestored(ctx context.Context, cartID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markCartRecoveryRestored, cartID)
+	return err
+}
+
+const markDomainEventDispatched = `-- name: MarkDomainEventDispatched :exec
+UPDATE domain_events
+  SET status = 'dispatched',
+  dispatched_at = NOW(),
+  last_error = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkDomainEventDispatched(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markDomainEventDispatched, id)
+	return err
+}
+
+const markDomainEventFailed = `-- name: MarkDomainEventFailed :exec
+UPDATE domain_events
+  SET status = $2,
+  last_error = $3,
+  next_attempt_at = $4
+WHERE id = $1
+`
+
+type MarkDomainEventFailedParams struct {
+	ID            int32              `json:"id"`
+	Status        string             `json:"status"`
+	LastError     pgtype.Text        `json:"lastError"`
+	NextAttemptAt pgtype.Timestamptz `json:"nextAttemptAt"`
+}
+
+func (q *Queries) MarkDomainEventFailed(ctx context.Context, arg MarkDomainEventFailedParams) error {
+	_, err := q.db.Exec(ctx, markDomainEventFailed,
+		arg.ID,
+		arg.Status,
+		arg.LastError,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const markEmailFailed = `-- name: MarkEmailFailed :exec
+UPDATE email_outbox
+  SET status = $2,
+  last_error = $3,
+  next_attempt_at = $4
+WHERE id = $1
+`
+
+type MarkEmailFailedParams struct {
+	ID            int32              `json:"id"`
+	Status        string             `json:"status"`
+	LastError     pgtype.Text        `json:"lastError"`
+	NextAttemptAt pgtype.Timestamptz `json:"nextAttemptAt"`
+}
+
+func (q *Queries) MarkEmailFailed(ctx context.Context, arg MarkEmailFailedParams) error {
+	_, err := q.db.Exec(ctx, markEmailFailed,
+		arg.ID,
+		arg.Status,
+		arg.LastError,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const markEmailSent = `-- name: MarkEmailSent :exec
+UPDATE email_outbox
+  SET status = 'sent',
+  sent_at = NOW(),
+  last_error = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkEmailSent(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markEmailSent, id)
+	return err
+}
+
+const markReturnItemRestocked = `-- name: MarkReturnItemRestocked :exec
+UPDATE return_items
+  SET restocked = TRUE
+WHERE id = $1
+`
+
+func (q *Queries) MarkReturnItemRestocked(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markReturnItemRestocked, id)
+	return err
+}
+
+const markWebhookDeliveryFailed = `-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+  SET status = $2,
+  response_status = $3,
+  last_error = $4,
+  next_attempt_at = $5
+WHERE id = $1
+`
+
+type MarkWebhookDeliveryFailedParams struct {
+	ID             int32              `json:"id"`
+	Status         string             `json:"status"`
+	ResponseStatus pgtype.Int4        `json:"responseStatus"`
+	LastError      pgtype.Text        `json:"lastError"`
+	NextAttemptAt  pgtype.Timestamptz `json:"nextAttemptAt"`
+}
+
+func (q *Queries) MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliveryFailed,
+		arg.ID,
+		arg.Status,
+		arg.ResponseStatus,
+		arg.LastError,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const markWebhookDeliverySucceeded = `-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+  SET status = 'succeeded',
+  response_status = $2,
+  last_error = NULL,
+  delivered_at = NOW()
+WHERE id = $1
+`
+
+type MarkWebhookDeliverySucceededParams struct {
+	ID             int32       `json:"id"`
+	ResponseStatus pgtype.Int4 `json:"responseStatus"`
+}
+
+func (q *Queries) MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliverySucceeded, arg.ID, arg.ResponseStatus)
+	return err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_failures (
+  key, failures
+) VALUES (
+  $1, 1
+)
+ON CONFLICT (key) DO UPDATE
+  SET failures = CASE WHEN login_failures.last_failure_at < NOW() - make_interval(secs => $2::float8) THEN 1 ELSE login_failures.failures + 1 END,
+  last_failure_at = NOW()
+RETURNING failures
+`
+
+type RecordLoginFailureParams struct {
+	Key           string  `json:"key"`
+	WindowSeconds float64 `json:"windowSeconds"`
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error) {
+	row := q.db.QueryRow(ctx, recordLoginFailure, arg.Key, arg.WindowSeconds)
+	var failures int32
+	err := row.Scan(&failures)
+	return failures, err
+}
+
+const recordWebhookFailure = `-- name: RecordWebhookFailure :one
+UPDATE webhook_endpoints
+  SET consecutive_failures = consecutive_failures + 1,
+  enabled = enabled AND consecutive_failures + 1 < $1::int,
+  disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= $1::int THEN NOW() ELSE disabled_at END,
+  disabled_reason = CASE WHEN enabled AND consecutive_failures + 1 >= $1::int THEN $2::text ELSE disabled_reason END
+WHERE id = $3
+RETURNING id, url, description, event_types, secret, enabled, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at
+`
+
+type RecordWebhookFailureParams struct {
+	DisableAfter int32  `json:"disableAfter"`
+	Reason       string `json:"reason"`
+	ID           int32  `json:"id"`
+}
+
+func (q *Queries) RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (WebhookEndpoint, error) {
+	row := q.db.QueryRow(ctx, recordWebhookFailure, arg.DisableAfter, arg.Reason, arg.ID)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Description,
+		&i.EventTypes,
+		&i.Secret,
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const recordWebhookSuccess = `-- name: RecordWebhookSuccess :exec
+UPDATE webhook_endpoints
+  SET consecutive_failures = 0
+WHERE id = $1
+`
+
+func (q *Queries) RecordWebhookSuccess(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, recordWebhookSuccess, id)
+	return err
+}
+
+const redeliverWebhookDelivery = `-- name: RedeliverWebhookDelivery :execrows
+UPDATE webhook_deliveries
+  SET status = 'pending',
+  attempts = 0,
+  next_attempt_at = NOW()
+WHERE id = $1
 `
 
-func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.Query(ctx, listUsers)
+func (q *Queries) RedeliverWebhookDelivery(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, redeliverWebhookDelivery, id)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []User
-	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.Email,
-			&i.PasswordHash,
-			&i.IsAdmin,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
 const removeCartItem = `-- name: RemoveCartItem :exec
@@ -771,6 +3676,305 @@ func (q *Queries) RemoveProductFromCollection(ctx context.Context, arg RemovePro
 	return err
 }
 
+const revokeReusedSession = `-- name: RevokeReusedSession :one
+WITH s AS (
+  UPDATE sessions
+    SET revoked_at = NOW()
+  WHERE previous_hash = $1 AND revoked_at IS NULL
+    AND refreshed_at < NOW() - make_interval(secs => $2::float8)
+  RETURNING id, kind, user_id, access_jti, access_expires_at
+), revoked AS (
+  INSERT INTO revoked_tokens (jti, expires_at)
+  SELECT access_jti, access_expires_at FROM s
+  ON CONFLICT (jti) DO NOTHING
+)
+SELECT id, kind, user_id FROM s
+`
+
+type RevokeReusedSessionParams struct {
+	PreviousHash pgtype.Text `json:"previousHash"`
+	GraceSeconds float64     `json:"graceSeconds"`
+}
+
+type RevokeReusedSessionRow struct {
+	ID     pgtype.UUID `json:"id"`
+	Kind   string      `json:"kind"`
+	UserID pgtype.Int4 `json:"userId"`
+}
+
+func (q *Queries) RevokeReusedSession(ctx context.Context, arg RevokeReusedSessionParams) (RevokeReusedSessionRow, error) {
+	row := q.db.QueryRow(ctx, revokeReusedSession, arg.PreviousHash, arg.GraceSeconds)
+	var i RevokeReusedSessionRow
+	err := row.Scan(&i.ID, &i.Kind, &i.UserID)
+	return i, err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+WITH s AS (
+  UPDATE sessions
+    SET revoked_at = NOW()
+  WHERE (id = $1 OR refresh_hash = $2) AND revoked_at IS NULL
+  RETURNING access_jti, access_expires_at
+)
+INSERT INTO revoked_tokens (jti, expires_at)
+SELECT access_jti, access_expires_at FROM s
+ON CONFLICT (jti) DO NOTHING
+`
+
+type RevokeSessionParams struct {
+	ID          pgtype.UUID `json:"id"`
+	RefreshHash string      `json:"refreshHash"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeSession, arg.ID, arg.RefreshHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokeToken = `-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (
+  jti, expires_at
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (jti) DO NOTHING
+`
+
+type RevokeTokenParams struct {
+	Jti       string             `json:"jti"`
+	ExpiresAt pgtype.Timestamptz `json:"expiresAt"`
+}
+
+func (q *Queries) RevokeToken(ctx context.Context, arg RevokeTokenParams) error {
+	_, err := q.db.Exec(ctx, revokeToken, arg.Jti, arg.ExpiresAt)
+	return err
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :execrows
+WITH s AS (
+  UPDATE sessions
+    SET revoked_at = NOW()
+  WHERE user_id = $1 AND revoked_at IS NULL
+  RETURNING access_jti, access_expires_at
+)
+INSERT INTO revoked_tokens (jti, expires_at)
+SELECT access_jti, access_expires_at FROM s
+ON CONFLICT (jti) DO NOTHING
+`
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, userID pgtype.Int4) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeUserSessions, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const rotateSession = `-- name: RotateSession :one
+WITH old AS (
+  SELECT id, access_jti, access_expires_at FROM sessions
+  WHERE refresh_hash = $1 AND kind = $2 AND revoked_at IS NULL AND expires_at > NOW()
+  FOR UPDATE
+), revoked AS (
+  INSERT INTO revoked_tokens (jti, expires_at)
+  SELECT access_jti, access_expires_at FROM old
+  ON CONFLICT (jti) DO NOTHING
+)
+UPDATE sessions s
+  SET refresh_hash = $3,
+  previous_hash = $1,
+  access_jti = $4,
+  access_expires_at = $5,
+  expires_at = $6,
+  refreshed_at = NOW()
+FROM old
+WHERE s.id = old.id
+RETURNING s.id, s.kind, s.user_id, s.cart_id, s.refresh_hash, s.previous_hash, s.access_jti, s.access_expires_at, s.expires_at, s.revoked_at, s.created_at, s.refreshed_at
+`
+
+type RotateSessionParams struct {
+	RefreshHash     string             `json:"refreshHash"`
+	Kind            string             `json:"kind"`
+	NewRefreshHash  string             `json:"newRefreshHash"`
+	AccessJti       string             `json:"accessJti"`
+	AccessExpiresAt pgtype.Timestamptz `json:"accessExpiresAt"`
+	ExpiresAt       pgtype.Timestamptz `json:"expiresAt"`
+}
+
+func (q *Queries) RotateSession(ctx context.Context, arg RotateSessionParams) (Session, error) {
+	row := q.db.QueryRow(ctx, rotateSession,
+		arg.RefreshHash,
+		arg.Kind,
+		arg.NewRefreshHash,
+		arg.AccessJti,
+		arg.AccessExpiresAt,
+		arg.ExpiresAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.UserID,
+		&i.CartID,
+		&i.RefreshHash,
+		&i.PreviousHash,
+		&i.AccessJti,
+		&i.AccessExpiresAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.RefreshedAt,
+	)
+	return i, err
+}
+
+const saveIdempotencyResponse = `-- name: SaveIdempotencyResponse :exec
+UPDATE idempotency_keys
+  SET status_code = $2,
+  response_headers = $3,
+  response_body = $4
+WHERE id = $1
+`
+
+type SaveIdempotencyResponseParams struct {
+	ID              int32       `json:"id"`
+	StatusCode      pgtype.Int4 `json:"statusCode"`
+	ResponseHeaders []byte      `json:"responseHeaders"`
+	ResponseBody    []byte      `json:"responseBody"`
+}
+
+func (q *Queries) SaveIdempotencyResponse(ctx context.Context, arg SaveIdempotencyResponseParams) error {
+	_, err := q.db.Exec(ctx, saveIdempotencyResponse,
+		arg.ID,
+		arg.StatusCode,
+		arg.ResponseHeaders,
+		arg.ResponseBody,
+	)
+	return err
+}
+
+const sessionRefreshedWithin = `-- name: SessionRefreshedWithin :one
+SELECT EXISTS (
+  SELECT 1 FROM sessions
+  WHERE previous_hash = $1 AND revoked_at IS NULL
+    AND refreshed_at >= NOW() - make_interval(secs => $2::float8)
+)
+`
+
+type SessionRefreshedWithinParams struct {
+	PreviousHash pgtype.Text `json:"previousHash"`
+	GraceSeconds float64     `json:"graceSeconds"`
+}
+
+func (q *Queries) SessionRefreshedWithin(ctx context.Context, arg SessionRefreshedWithinParams) (bool, error) {
+	row := q.db.QueryRow(ctx, sessionRefreshedWithin, arg.PreviousHash, arg.GraceSeconds)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const setCartEmail = `-- name: SetCartEmail :exec
+UPDATE carts
+  SET email = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type SetCartEmailParams struct {
+	ID    pgtype.UUID `json:"id"`
+	Email pgtype.Text `json:"email"`
+}
+
+func (q *Queries) SetCartEmail(ctx context.Context, arg SetCartEmailParams) error {
+	_, err := q.db.Exec(ctx, setCartEmail, arg.ID, arg.Email)
+	return err
+}
+
+const setShippingMethodActive = `-- name: SetShippingMethodActive :exec
+UPDATE shipping_methods
+  SET active = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type SetShippingMethodActiveParams struct {
+	ID     int32 `json:"id"`
+	Active bool  `json:"active"`
+}
+
+func (q *Queries) SetShippingMethodActive(ctx context.Context, arg SetShippingMethodActiveParams) error {
+	_, err := q.db.Exec(ctx, setShippingMethodActive, arg.ID, arg.Active)
+	return err
+}
+
+const startUserMFA = `-- name: StartUserMFA :one
+INSERT INTO user_mfa (
+  user_id, secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (user_id) DO UPDATE
+  SET secret = EXCLUDED.secret,
+  last_step = 0,
+  created_at = NOW()
+WHERE user_mfa.enabled = FALSE
+RETURNING user_id, secret, enabled, last_step, created_at, enabled_at
+`
+
+type StartUserMFAParams struct {
+	UserID int32  `json:"userId"`
+	Secret string `json:"secret"`
+}
+
+func (q *Queries) StartUserMFA(ctx context.Context, arg StartUserMFAParams) (UserMfa, error) {
+	row := q.db.QueryRow(ctx, startUserMFA, arg.UserID, arg.Secret)
+	var i UserMfa
+	err := row.Scan(
+		&i.UserID,
+		&i.Secret,
+		&i.Enabled,
+		&i.LastStep,
+		&i.CreatedAt,
+		&i.EnabledAt,
+	)
+	return i, err
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits AS r (
+  key, tokens, allowed
+) VALUES (
+  $1, $2::float8 - 1, TRUE
+)
+ON CONFLICT (key) DO UPDATE
+  SET tokens = LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM NOW() - r.updated_at)::float8 * $3::float8)
+    - CASE WHEN LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM NOW() - r.updated_at)::float8 * $3::float8) >= 1 THEN 1 ELSE 0 END,
+  allowed = LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM NOW() - r.updated_at)::float8 * $3::float8) >= 1,
+  updated_at = NOW()
+RETURNING tokens, allowed
+`
+
+type TakeRateLimitTokenParams struct {
+	Key   string  `json:"key"`
+	Burst float64 `json:"burst"`
+	Rate  float64 `json:"rate"`
+}
+
+type TakeRateLimitTokenRow struct {
+	Tokens  float64 `json:"tokens"`
+	Allowed bool    `json:"allowed"`
+}
+
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error) {
+	row := q.db.QueryRow(ctx, takeRateLimitToken, arg.Key, arg.Burst, arg.Rate)
+	var i TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.Allowed)
+	return i, err
+}
+
 const updateCartItemQuantity = `-- name: UpdateCartItemQuantity :exec
 UPDATE cart_items
   SET quantity = $3,
@@ -819,6 +4023,75 @@ func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionPara
 	return err
 }
 
+const updateFulfillment = `-- name: UpdateFulfillment :exec
+UPDATE fulfillments
+  SET carrier = $2,
+  tracking_number = $3,
+  tracking_url = $4,
+  status = $5,
+  shipped_at = $6,
+  delivered_at = $7,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFulfillmentParams struct {
+	ID             int32              `json:"id"`
+	Carrier        pgtype.Text        `json:"carrier"`
+	TrackingNumber pgtype.Text        `json:"trackingNumber"`
+	TrackingUrl    pgtype.Text        `json:"trackingUrl"`
+	Status         string             `json:"status"`
+	ShippedAt      pgtype.Timestamptz `json:"shippedAt"`
+	DeliveredAt    pgtype.Timestamptz `json:"deliveredAt"`
+}
+
+func (q *Queries) UpdateFulfillment(ctx context.Context, arg UpdateFulfillmentParams) error {
+	_, err := q.db.Exec(ctx, updateFulfillment,
+		arg.ID,
+		arg.Carrier,
+		arg.TrackingNumber,
+		arg.TrackingUrl,
+		arg.Status,
+		arg.ShippedAt,
+		arg.DeliveredAt,
+	)
+	return err
+}
+
+const updateOrderFulfillmentStatus = `-- name: UpdateOrderFulfillmentStatus :exec
+UPDATE orders
+  SET fulfillment_status = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateOrderFulfillmentStatusParams struct {
+	ID                int32  `json:"id"`
+	FulfillmentStatus string `json:"fulfillmentStatus"`
+}
+
+func (q *Queries) UpdateOrderFulfillmentStatus(ctx context.Context, arg UpdateOrderFulfillmentStatusParams) error {
+	_, err := q.db.Exec(ctx, updateOrderFulfillmentStatus, arg.ID, arg.FulfillmentStatus)
+	return err
+}
+
+const updateOrderStatus = `-- name: UpdateOrderStatus :exec
+UPDATE orders
+  SET status = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateOrderStatusParams struct {
+	ID     int32  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) error {
+	_, err := q.db.Exec(ctx, updateOrderStatus, arg.ID, arg.Status)
+	return err
+}
+
 const updateProduct = `-- name: UpdateProduct :exec
 UPDATE products
   SET name = $2,
@@ -901,7 +4174,7 @@ func (q *Queries) UpdateProductFitGuide(ctx context.Context, arg UpdateProductFi
 	return err
 }
 
-const updateProductStock = `-- name: UpdateProductStock :exec
+const updateProductStock = `-- name: UpdateProductStock :execrows
 UPDATE product_sizes
   SET stock = $3,
   updated_at = NOW()
@@ -914,7 +4187,191 @@ type UpdateProductStockParams struct {
 	Stock     int32  `json:"stock"`
 }
 
-func (q *Queries) UpdateProductStock(ctx context.Context, arg UpdateProductStockParams) error {
-	_, err := q.db.Exec(ctx, updateProductStock, arg.ProductID, arg.SizeName, arg.Stock)
+func (q *Queries) UpdateProductStock(ctx context.Context, arg UpdateProductStockParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateProductStock, arg.ProductID, arg.SizeName, arg.Stock)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateProductTaxCategory = `-- name: UpdateProductTaxCategory :exec
+UPDATE products
+  SET tax_category = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateProductTaxCategoryParams struct {
+	ID          int32  `json:"id"`
+	TaxCategory string `json:"taxCategory"`
+}
+
+func (q *Queries) UpdateProductTaxCategory(ctx context.Context, arg UpdateProductTaxCategoryParams) error {
+	_, err := q.db.Exec(ctx, updateProductTaxCategory, arg.ID, arg.TaxCategory)
+	return err
+}
+
+const updateProductWeight = `-- name: UpdateProductWeight :exec
+UPDATE products
+  SET weight_grams = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateProductWeightParams struct {
+	ID          int32 `json:"id"`
+	WeightGrams int32 `json:"weightGrams"`
+}
+
+func (q *Queries) UpdateProductWeight(ctx context.Context, arg UpdateProductWeightParams) error {
+	_, err := q.db.Exec(ctx, updateProductWeight, arg.ID, arg.WeightGrams)
+	return err
+}
+
+const updateRefundStatus = `-- name: UpdateRefundStatus :exec
+UPDATE refunds
+  SET status = $2,
+  provider_refund_id = $3,
+  failure_reason = $4,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateRefundStatusParams struct {
+	ID               int32       `json:"id"`
+	Status           string      `json:"status"`
+	ProviderRefundID pgtype.Text `json:"providerRefundId"`
+	FailureReason    pgtype.Text `json:"failureReason"`
+}
+
+func (q *Queries) UpdateRefundStatus(ctx context.Context, arg UpdateRefundStatusParams) error {
+	_, err := q.db.Exec(ctx, updateRefundStatus,
+		arg.ID,
+		arg.Status,
+		arg.ProviderRefundID,
+		arg.FailureReason,
+	)
+	return err
+}
+
+const updateReturnStatus = `-- name: UpdateReturnStatus :exec
+UPDATE returns
+  SET status = $2,
+  note = COALESCE($3, note),
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateReturnStatusParams struct {
+	ID     int32       `json:"id"`
+	Status string      `json:"status"`
+	Note   pgtype.Text `json:"note"`
+}
+
+func (q *Queries) UpdateReturnStatus(ctx context.Context, arg UpdateReturnStatusParams) error {
+	_, err := q.db.Exec(ctx, updateReturnStatus, arg.ID, arg.Status, arg.Note)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+  SET password_hash = $2,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           int32  `json:"id"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
 	return err
 }
+
+const updateWebhookEndpoint = `-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+  SET url = $1,
+  description = $2,
+  event_types = $3,
+  consecutive_failures = CASE WHEN $4::boolean AND NOT enabled THEN 0 ELSE consecutive_failures END,
+  disabled_at = CASE WHEN $4::boolean THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+  disabled_reason = CASE WHEN $4::boolean THEN NULL ELSE COALESCE(disabled_reason, 'Disabled by an admin') END,
+  enabled = $4::boolean,
+  updated_at = NOW()
+WHERE id = $5
+RETURNING id, url, description, event_types, secret, enabled, consecutive_failures, disabled_at, disabled_reason, created_at, updated_at
+`
+
+type UpdateWebhookEndpointParams struct {
+	Url         string      `json:"url"`
+	Description pgtype.Text `json:"description"`
+	EventTypes  []string    `json:"eventTypes"`
+	Enabled     bool        `json:"enabled"`
+	ID          int32       `json:"id"`
+}
+
+func (q *Queries) UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (WebhookEndpoint, error) {
+	row := q.db.QueryRow(ctx, updateWebhookEndpoint,
+		arg.Url,
+		arg.Description,
+		arg.EventTypes,
+		arg.Enabled,
+		arg.ID,
+	)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Description,
+		&i.EventTypes,
+		&i.Secret,
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const useMFARecoveryCode = `-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+  SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+type UseMFARecoveryCodeParams struct {
+	UserID   int32  `json:"userId"`
+	CodeHash string `json:"codeHash"`
+}
+
+func (q *Queries) UseMFARecoveryCode(ctx context.Context, arg UseMFARecoveryCodeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useMFARecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const useUserMFAStep = `-- name: UseUserMFAStep :execrows
+UPDATE user_mfa
+  SET last_step = $2
+WHERE user_id = $1 AND last_step < $2
+`
+
+type UseUserMFAStepParams struct {
+	UserID   int32 `json:"userId"`
+	LastStep int64 `json:"lastStep"`
+}
+
+func (q *Queries) UseUserMFAStep(ctx context.Context, arg UseUserMFAStepParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useUserMFAStep, arg.UserID, arg.LastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
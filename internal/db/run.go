@@ -3,14 +3,11 @@ package db
 import (
 	"context"
 	"log"
-	"os"
 
 	"github.com/jackc/pgx/v5"
 )
 
-func RunDB(ctx context.Context) (*pgx.Conn, error) {
-	url := os.Getenv("DB_URL")
-	
+func RunDB(ctx context.Context, url string) (*pgx.Conn, error) {
 	conn, err := pgx.Connect(ctx, url)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,14 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Store runs queries and starts transactions. A *pgxpool.Pool, a *pgx.Conn
+// and a pgx.Tx all satisfy it.
+type Store interface {
+	DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Handler reacts to a delivered event. conn is the dispatcher's store for
+// handlers that write to the database. Returning an error
+// makes the dispatcher retry the event for this subscriber later, so handlers
+// must be safe to run more than once for the same event ID.
+type Handler func(ctx context.Context, conn db.Store, e Envelope) error
+
+type subscription struct {
+	name    string
+	types   map[string]bool
+	handler Handler
+}
+
+func (s subscription) wants(eventType string) bool {
+	return len(s.types) == 0 || s.types[eventType]
+}
+
+// Bus holds the in-process subscribers events are delivered to
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers handler for the given event types, or every event when
+// none are given. Deliveries are recorded by name, so it must be unique and
+// stay the same across restarts.
+func (b *Bus) Subscribe(name string, handler Handler, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.subscriptions {
+		if s.name == name {
+			panic(fmt.Sprintf("events: subscriber %q registered twice", name))
+		}
+	}
+
+	s := subscription{name: name, types: make(map[string]bool, len(types)), handler: handler}
+	for _, t := range types {
+		s.types[t] = true
+	}
+	b.subscriptions = append(b.subscriptions, s)
+}
+
+func (b *Bus) subscribers(eventType string) []subscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var subs []subscription
+	for _, s := range b.subscriptions {
+		if s.wants(eventType) {
+			subs = append(subs, s)
+		}
+	}
+	return subs
+}
+
+// deliver runs one subscriber, turning a panic into an error so a bad
+// handler can't take the dispatcher down
+func (s subscription) deliver(ctx context.Context, conn db.Store, e Envelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.handler(ctx, conn, e)
+}
+
+// LogHandler writes every event it receives to the log
+func LogHandler(ctx context.Context, conn db.Store, e Envelope) error {
+	slog.InfoContext(ctx, "Event", "event_id", e.ID, "type", e.Type, "aggregate_type", e.AggregateType, "aggregate_id", e.AggregateID, "payload", string(e.Payload))
+	return nil
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+const (
+	DefaultInterval    = 5 * time.Second
+	DefaultBatchSize   = 50
+	DefaultMaxAttempts = 10
+	DefaultBaseBackoff = 10 * time.Second
+	MaxBackoff         = 6 * time.Hour
+
+	// dispatchLease hides a claimed event from other dispatchers while its
+	// subscribers run. If the process dies mid-run the event is retried after it.
+	dispatchLease = 5 * time.Minute
+)
+
+// Event statuses
+const (
+	StatusPending    = "pending"
+	StatusDispatched = "dispatched"
+	StatusFailed     = "failed"
+)
+
+// Dispatcher delivers events from the outbox to the subscribers on its Bus.
+// Each subscriber that handles an event is recorded, so a retry only runs
+// the subscribers that failed. Delivery is at least once: a subscriber may see
+// an event again if the process stops before its delivery is recorded.
+type Dispatcher struct {
+	Bus         *Bus
+	Store       db.Store
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+}
+
+// Config holds the dispatcher settings
+type Config struct {
+	Interval    time.Duration // how often due events are dispatched
+	MaxAttempts int           // deliveries before an event is marked failed
+}
+
+// NewDispatcher delivers to the subscribers on bus every c.Interval
+func NewDispatcher(bus *Bus, c Config, store db.Store) *Dispatcher {
+	return &Dispatcher{
+		Bus:         bus,
+		Store:       store,
+		Interval:    c.Interval,
+		BatchSize:   DefaultBatchSize,
+		MaxAttempts: c.MaxAttempts,
+		BaseBackoff: DefaultBaseBackoff,
+	}
+}
+
+// Run dispatches events every Interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "Event dispatch error", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce dispatches one batch of due events and returns how many reached
+// all of their subscribers
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	q := db.New(d.Store)
+	claimed, err := q.ClaimDueDomainEvents(ctx, db.ClaimDueDomainEventsParams{
+		Lease:     pgtype.Interval{Microseconds: dispatchLease.Microseconds(), Valid: true},
+		BatchSize: int32(d.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	for _, event := range claimed {
+		err := d.dispatch(ctx, q, event)
+		if err == nil {
+			if err := q.MarkDomainEventDispatched(ctx, event.ID); err != nil {
+				return dispatched, err
+			}
+			dispatched++
+			continue
+		}
+
+		status := StatusPending
+		if int(event.Attempts) >= d.MaxAttempts {
+			status = StatusFailed
+		}
+		slog.WarnContext(ctx, "Event delivery failed", "event_id", event.ID, "type", event.EventType, "attempt", event.Attempts, "status", status, "err", err)
+
+		if err := q.MarkDomainEventFailed(ctx, db.MarkDomainEventFailedParams{
+			ID:            event.ID,
+			Status:        status,
+			LastError:     pgtype.Text{String: err.Error(), Valid: true},
+			NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(d.Backoff(int(event.Attempts))), Valid: true},
+		}); err != nil {
+			return dispatched, err
+		}
+	}
+	return dispatched, nil
+}
+
+// dispatch runs every subscriber that has not handled the event yet
+func (d *Dispatcher) dispatch(ctx context.Context, q *db.Queries, event db.DomainEvent) error {
+	delivered, err := q.ListDomainEventDeliveries(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	done := make(map[string]bool, len(delivered))
+	for _, name := range delivered {
+		done[name] = true
+	}
+
+	e := envelope(event)
+	var errs []error
+	for _, s := range d.Bus.subscribers(event.EventType) {
+		if done[s.name] {
+			continue
+		}
+		if err := s.deliver(ctx, d.Store, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		if err := q.AddDomainEventDelivery(ctx, db.AddDomainEventDeliveryParams{
+			EventID:    event.ID,
+			Subscriber: s.name,
+		}); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Backoff doubles the wait after every failed attempt, capped at MaxBackoff
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	wait := float64(d.BaseBackoff) * math.Pow(2, float64(attempts-1))
+	if wait > float64(MaxBackoff) {
+		return MaxBackoff
+	}
+	return time.Duration(wait)
+}
@@ -0,0 +1,177 @@
+// Package events defines the store's domain events and a transactional
+// outbox for them. Events are written to the domain_events table with the
+// same transaction as the change they describe, so an event is recorded if
+// and only if the change commits. A Dispatcher then delivers them to the
+// subscribers registered on a Bus, at least once.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Event types
+const (
+	ProductCreated     = "product.created"
+	ProductUpdated     = "product.updated"
+	ProductDeleted     = "product.deleted"
+	StockLow           = "product.stock_low"
+	CartCheckedOut     = "cart.checked_out"
+	OrderPaid          = "order.paid"
+	OrderStatusChanged = "order.status_changed"
+	ReturnRequested    = "return.requested"
+)
+
+// Types lists every event type subscribers can ask for
+var Types = []string{
+	ProductCreated,
+	ProductUpdated,
+	ProductDeleted,
+	StockLow,
+	CartCheckedOut,
+	OrderPaid,
+	OrderStatusChanged,
+	ReturnRequested,
+}
+
+func ValidType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the data carried by an event. Each payload knows its event type
+// and the aggregate (product, cart, order...) it belongs to.
+type Payload interface {
+	EventType() string
+	Aggregate() (kind, id string)
+}
+
+type ProductCreatedData struct {
+	ProductID int32   `json:"productId"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	PriceID   string  `json:"priceId"`
+}
+
+type ProductUpdatedData struct {
+	ProductID   int32   `json:"productId"`
+	Name        string  `json:"name"`
+	Price       float64 `json:"price"`
+	Description string  `json:"description"`
+}
+
+type ProductDeletedData struct {
+	ProductID int32 `json:"productId"`
+}
+
+// StockLowData is raised when a size's stock is set at or below Threshold
+type StockLowData struct {
+	ProductID int32  `json:"productId"`
+	Size      string `json:"size"`
+	Stock     int32  `json:"stock"`
+	Threshold int32  `json:"threshold"`
+}
+
+type CartCheckedOutData struct {
+	CartID            string  `json:"cartId"`
+	OrderID           int32   `json:"orderId"`
+	CheckoutSessionID string  `json:"checkoutSessionId"`
+	Email             string  `json:"email"`
+	Total             float64 `json:"total"`
+	Currency          string  `json:"currency"`
+}
+
+type OrderPaidData struct {
+	OrderID  int32   `json:"orderId"`
+	Email    string  `json:"email"`
+	Total    float64 `json:"total"`
+	Currency string  `json:"currency"`
+}
+
+type OrderStatusChangedData struct {
+	OrderID int32  `json:"orderId"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Actor   string `json:"actor"`
+	Note    string `json:"note,omitempty"`
+}
+
+type ReturnRequestedData struct {
+	ReturnID int32  `json:"returnId"`
+	OrderID  int32  `json:"orderId"`
+	Email    string `json:"email"`
+	Reason   string `json:"reason"`
+}
+
+func (ProductCreatedData) EventType() string     { return ProductCreated }
+func (ProductUpdatedData) EventType() string     { return ProductUpdated }
+func (ProductDeletedData) EventType() string     { return ProductDeleted }
+func (StockLowData) EventType() string           { return StockLow }
+func (CartCheckedOutData) EventType() string     { return CartCheckedOut }
+func (OrderPaidData) EventType() string          { return OrderPaid }
+func (OrderStatusChangedData) EventType() string { return OrderStatusChanged }
+func (ReturnRequestedData) EventType() string    { return ReturnRequested }
+
+func (d ProductCreatedData) Aggregate() (string, string)     { return "product", itoa(d.ProductID) }
+func (d ProductUpdatedData) Aggregate() (string, string)     { return "product", itoa(d.ProductID) }
+func (d ProductDeletedData) Aggregate() (string, string)     { return "product", itoa(d.ProductID) }
+func (d StockLowData) Aggregate() (string, string)           { return "product", itoa(d.ProductID) }
+func (d CartCheckedOutData) Aggregate() (string, string)     { return "cart", d.CartID }
+func (d OrderPaidData) Aggregate() (string, string)          { return "order", itoa(d.OrderID) }
+func (d OrderStatusChangedData) Aggregate() (string, string) { return "order", itoa(d.OrderID) }
+func (d ReturnRequestedData) Aggregate() (string, string)    { return "return", itoa(d.ReturnID) }
+
+// Envelope is a stored event as handed to subscribers
+type Envelope struct {
+	ID            int32           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateID   string          `json:"aggregateId"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+}
+
+// Decode unmarshals the payload into one of the *Data types
+func (e Envelope) Decode(v any) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+func envelope(e db.DomainEvent) Envelope {
+	return Envelope{
+		ID:            e.ID,
+		Type:          e.EventType,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		Payload:       e.Payload,
+		OccurredAt:    e.CreatedAt.Time,
+	}
+}
+
+// Publish writes an event to the outbox. Pass queries bound to the
+// transaction making the change so the event commits or rolls back with it.
+func Publish(ctx context.Context, q *db.Queries, p Payload) (db.DomainEvent, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return db.DomainEvent{}, err
+	}
+
+	kind, id := p.Aggregate()
+	return q.AddDomainEvent(ctx, db.AddDomainEventParams{
+		EventType:     p.EventType(),
+		AggregateType: kind,
+		AggregateID:   id,
+		Payload:       payload,
+	})
+}
+
+func itoa(id int32) string {
+	return strconv.Itoa(int(id))
+}
@@ -0,0 +1,115 @@
+// Package fulfillment holds the shipment status rules: which transitions a
+// fulfillment may make, and what fulfillment status an order has given the
+// quantities shipped so far.
+package fulfillment
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Fulfillment statuses
+const (
+	StatusPending   = "pending"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+)
+
+// Order fulfillment statuses
+const (
+	OrderUnfulfilled        = "unfulfilled"
+	OrderPartiallyFulfilled = "partially_fulfilled"
+	OrderShipped            = "shipped"
+	OrderDelivered          = "delivered"
+)
+
+var rank = map[string]int{
+	StatusPending:   0,
+	StatusShipped:   1,
+	StatusDelivered: 2,
+}
+
+func ValidStatus(s string) bool {
+	_, ok := rank[s]
+	return ok
+}
+
+// CanTransition only lets a fulfillment move forward, e.g. pending to shipped
+// or shipped to delivered. Setting the current status again is allowed.
+func CanTransition(from, to string) bool {
+	f, ok := rank[from]
+	if !ok {
+		return false
+	}
+	t, ok := rank[to]
+	return ok && t >= f
+}
+
+// Shipment is the status of one fulfillment and the order item quantities it
+// covers, keyed by order item ID
+type Shipment struct {
+	Status string
+	Items  map[int32]int32
+}
+
+// Remaining returns the quantity of each order item not yet in a fulfillment
+func Remaining(ordered map[int32]int32, shipments []Shipment) map[int32]int32 {
+	remaining := make(map[int32]int32, len(ordered))
+	for id, qty := range ordered {
+		remaining[id] = qty
+	}
+	for _, s := range shipments {
+		for id, qty := range s.Items {
+			remaining[id] -= qty
+		}
+	}
+	return remaining
+}
+
+// OrderStatus summarises the fulfillments of an order. An order is shipped
+// once every item is in a shipped fulfillment and delivered once all of them
+// are delivered; anything in between is partially fulfilled.
+func OrderStatus(ordered map[int32]int32, shipments []Shipment) string {
+	if len(shipments) == 0 {
+		return OrderUnfulfilled
+	}
+
+	for _, qty := range Remaining(ordered, shipments) {
+		if qty > 0 {
+			return OrderPartiallyFulfilled
+		}
+	}
+
+	lowest := StatusDelivered
+	for _, s := range shipments {
+		if rank[s.Status] < rank[lowest] {
+			lowest = s.Status
+		}
+	}
+
+	switch lowest {
+	case StatusDelivered:
+		return OrderDelivered
+	case StatusShipped:
+		return OrderShipped
+	default:
+		return OrderPartiallyFulfilled
+	}
+}
+
+var trackingURLs = map[string]string{
+	"ups":   "https://www.ups.com/track?tracknum=",
+	"usps":  "https://tools.usps.com/go/TrackConfirmAction?tLabels=",
+	"fedex": "https://www.fedex.com/fedextrack/?trknbr=",
+	"dhl":   "https://www.dhl.com/en/express/tracking.html?AWB=",
+}
+
+// TrackingURL builds a tracking link for well known carriers, or returns an
+// empty string when the carrier is unknown
+func TrackingURL(carrier, number string) string {
+	base, ok := trackingURLs[strings.ToLower(strings.TrimSpace(carrier))]
+	if !ok || number == "" {
+		return ""
+	}
+	return base + url.QueryEscape(number)
+}
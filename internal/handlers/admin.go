@@ -1,86 +1,340 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
-	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/lockout"
 	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/ratelimit"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
 )
 
-func LoginHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+// LoginResponse says whether the admin cookie was set, or which two-factor
+// step comes first
+type LoginResponse struct {
+	LoggedIn         bool `json:"loggedIn"`
+	MFARequired      bool `json:"mfaRequired,omitempty"`      // send a code to /api/auth/mfa/verify
+	MFASetupRequired bool `json:"mfaSetupRequired,omitempty"` // set it up with /api/auth/mfa/setup and /enable
+}
+
+// UserRequest is the body for creating an admin user
+type UserRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// ChangePasswordRequest is the body for changing the logged in admin's
+// password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,max=72"`
+}
+
+func (app *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	email := r.FormValue("email")
-	password := r.FormValue("password")
+	var body LoginRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	// Attempts per account, on top of the per IP limit on the route
+	account := strings.ToLower(strings.TrimSpace(body.Email))
+	if !ratelimit.Allow(w, r, app.Limits, "login_account", app.Config.RateLimits.LoginAccount, account) {
+		return
+	}
+
+	// Turn the attempt down while the account or IP is waiting out failures
+	ip := ratelimit.ClientIP(r)
+	wait, err := app.Lockout.Check(ctx, account, ip)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error checking login lockout", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	if wait > 0 {
+		app.Logger.WarnContext(ctx, "Login attempt while locked out", "email", account, "ip", ip, "retry_after", wait)
+		setRetryAfter(w, wait)
+		apperr.Write(w, r, lockout.ErrLocked)
+		return
+	}
 
-	user, err := methods.Login(ctx, conn, email, password)
+	user, err := methods.Login(ctx, app.Store, body.Email, body.Password)
+	if errors.Is(err, methods.ErrInvalidLogin) {
+		app.loginFailed(w, r, methods.SecurityEvent{Type: methods.SecurityLoginFailed, Email: account, Detail: "wrong email or password"}, methods.ErrInvalidLogin)
+		return
+	}
 	if err != nil {
-		log.Println("ERROR LOGGING IN: ", err.Error())
-		http.Error(w, "An error occurred authenticating", http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Error logging in", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	ok, err := methods.CheckUserAdmin(ctx, conn, user.ID)
+	ok, err := methods.CheckUserAdmin(ctx, app.Store, user.ID)
 	if err != nil {
-		log.Println("ERROR ADMIN CHECK: ", err.Error())
-		http.Error(w, "An error occurred authenticating", http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Error admin check", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	if !ok {
+		app.loginFailed(w, r, methods.SecurityEvent{Type: methods.SecurityLoginFailed, UserID: user.ID, Email: account, Detail: "not an admin"}, methods.ErrInvalidLogin)
 		return
 	}
 
-	if ok {
-		_, err := auth.CreateAdminJWT(w, r, user)
-		if err != nil {
-			log.Println("ERROR CREATING JWT: ", err.Error())
-			http.Error(w, "An error occurred authenticating", http.StatusInternalServerError)
+	// Admins with two-factor authentication, or who have to set it up, get
+	// a challenge to answer instead of a session
+	enabled, err := methods.MFAEnabled(ctx, app.Store, user.ID)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error checking two-factor authentication", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	if enabled || app.Config.Auth.RequireMFA {
+		if err := app.Auth.CreateMFAChallenge(w, r, user.ID, !enabled); err != nil {
+			apperr.Write(w, r, err)
 			return
 		}
+		writeLoginResponse(w, r, LoginResponse{MFARequired: enabled, MFASetupRequired: !enabled})
+		return
+	}
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Logged in!"))
+	if err := app.completeLogin(w, r, user, ""); err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
+	writeLoginResponse(w, r, LoginResponse{LoggedIn: true})
 }
 
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	cookie := &http.Cookie{
-		Name:    "dam-nation-shop-admin",
-		Value:   "",
-		Path:    "/",
-		Expires: time.Unix(0, 0),
-		MaxAge:  -1,
-		HttpOnly: true,
+// completeLogin starts the admin session once every check has passed and
+// forgets the account's failed attempts
+func (app *App) completeLogin(w http.ResponseWriter, r *http.Request, user db.User, detail string) error {
+	ctx := r.Context()
+	account := strings.ToLower(user.Email)
+
+	if err := app.Lockout.Succeed(ctx, account); err != nil {
+		app.Logger.ErrorContext(ctx, "Error clearing login failures", "err", err)
 	}
 
-	http.SetCookie(w, cookie)
+	if _, err := app.Auth.CreateAdminJWT(w, r, user); err != nil {
+		app.Logger.ErrorContext(ctx, "Error creating JWT", "err", err)
+		return err
+	}
+	app.Auth.ClearMFAChallenge(w, r)
+
+	app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityLoginSucceeded, UserID: user.ID, Email: account, Detail: detail})
+	return nil
+}
+
+func writeLoginResponse(w http.ResponseWriter, r *http.Request, res LoginResponse) {
+	j, err := json.Marshal(res)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// loginFailed records e, counts the failure against the account and client
+// IP, logs any lockout it starts and answers with err
+func (app *App) loginFailed(w http.ResponseWriter, r *http.Request, e methods.SecurityEvent, err error) {
+	ctx := r.Context()
+	ip := ratelimit.ClientIP(r)
+
+	app.securityEvent(r, e)
+
+	account, addr, ferr := app.Lockout.Fail(ctx, e.Email, ip)
+	if ferr != nil {
+		app.Logger.ErrorContext(ctx, "Error recording login failure", "err", ferr)
+	}
+	if account.Locked {
+		app.securityEvent(r, methods.SecurityEvent{
+			Type:   methods.SecurityAccountLocked,
+			UserID: e.UserID,
+			Email:  e.Email,
+			Detail: fmt.Sprintf("%d failed logins, locked for %s", account.Failures, account.Wait),
+		})
+	}
+	if addr.Locked {
+		app.securityEvent(r, methods.SecurityEvent{
+			Type:   methods.SecurityIPLocked,
+			Email:  e.Email,
+			Detail: fmt.Sprintf("%d failed logins, locked for %s", addr.Failures, addr.Wait),
+		})
+	}
+
+	if wait := max(account.Wait, addr.Wait); wait > 0 {
+		setRetryAfter(w, wait)
+	}
+	apperr.Write(w, r, err)
+}
+
+// securityEvent records e with the request's client IP and user agent.
+// Failures are logged, the request carries on.
+func (app *App) securityEvent(r *http.Request, e methods.SecurityEvent) {
+	e.IP = ratelimit.ClientIP(r)
+	e.UserAgent = r.UserAgent()
+	methods.RecordSecurityEvent(r.Context(), app.Store, e)
+}
+
+// setRetryAfter sets Retry-After in whole seconds, rounded up
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
+
+// ChangePasswordHandler changes the logged in admin's password
+func (app *App) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	var body ChangePasswordRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	id, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if err := methods.ChangePassword(ctx, app.Store, id, body.CurrentPassword, body.NewPassword); err != nil {
+		app.Logger.ErrorContext(ctx, "Change password error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	user, err := methods.GetUser(ctx, app.Store, id)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Get user error", "err", err)
+	}
+	app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityPasswordChanged, UserID: id, Email: strings.ToLower(user.Email)})
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Password changed"))
+}
+
+// ListSecurityEventsHandler returns recent logins, failures, lockouts and
+// password changes, newest first
+func (app *App) ListSecurityEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			apperr.Write(w, r, apperr.Validation("Limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+	email := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("email")))
+
+	list, err := methods.GetSecurityEvents(ctx, app.Store, email, limit)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(list)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// LogoutHandler revokes the admin session, so its tokens stop working right
+// away, and clears the cookies
+func (app *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	app.Auth.ClearMFAChallenge(w, r)
+	if err := app.Auth.Logout(w, r); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Logged out."))
 }
 
-func RegisterAdminUserHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) RegisterAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	email := r.FormValue("email")
-	password := r.FormValue("password")
- 
-	user, err := methods.CreateUser(ctx, conn, email, password)
+	var body UserRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	user, err := methods.CreateUser(ctx, app.Store, body.Email, body.Password)
 	if err != nil {
-		log.Println("CREATE USER ERROR: ", err.Error())
-		http.Error(w, "An error occurred creating user", http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Create user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	json, err := json.Marshal(user)
 	if err != nil {
-		log.Println("MARSHAL USER ERROR: ", err.Error())
-		http.Error(w, "An error occurred creating user", http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Marshal user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write(json)
 }
+
+// DeactivateUserHandler takes away another admin's role and revokes their
+// sessions, so they're logged out on their next request
+func (app *App) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Validation("Invalid user ID"))
+		return
+	}
+
+	adminID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if int32(id) == adminID {
+		apperr.Write(w, r, apperr.Conflict("You can't deactivate your own account"))
+		return
+	}
+
+	if err := methods.DeactivateUser(ctx, app.Store, int32(id)); err != nil {
+		app.Logger.ErrorContext(ctx, "Deactivate user error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityAdminDeactivated, UserID: int32(id), Detail: fmt.Sprintf("by admin %d", adminID)})
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("User deactivated"))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log/slog"
+	"time"
+
+	ecommerce "github.com/petermazzocco/go-ecommerce-api"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/cleanup"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/health"
+	"github.com/petermazzocco/go-ecommerce-api/internal/lockout"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+	"github.com/petermazzocco/go-ecommerce-api/internal/ratelimit"
+)
+
+// Config holds the settings the handlers read on every request
+type Config struct {
+	Auth                auth.Config
+	StripeWebhookSecret string        // signing secret of the Stripe webhook endpoint
+	IdempotencyTTL      time.Duration // how long responses are kept for retried writes
+	CartRestoreURL      string        // where a restored cart lands, empty to answer with plain text
+	Metrics             metrics.Config
+	RateLimits          ratelimit.Config
+	Lockout             lockout.Config
+	Tax                 methods.TaxConfig
+	Checkout            methods.CheckoutConfig
+	StockLow            int32 // stock at or below which a size raises StockLow
+}
+
+// App holds everything the handlers need. Each handler is a method on App so
+// it can be registered on a router directly, and uses the request's context
+// so a client that goes away cancels its queries.
+type App struct {
+	Config   Config
+	Store    db.Store
+	Payments payments.Provider
+	Auth     *auth.Sessions
+	Logger   *slog.Logger
+	Health   *health.Checker
+	CartGC   *cleanup.Worker
+	Limits   ratelimit.Store
+	Lockout  *lockout.Guard
+}
+
+func NewApp(config Config, store db.Store, provider payments.Provider, logger *slog.Logger) *App {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &App{
+		Config:   config,
+		Store:    store,
+		Payments: provider,
+		Auth:     auth.NewSessions(config.Auth, store),
+		Logger:   logger,
+		Health:   health.NewChecker(store, provider, ecommerce.Schema),
+		Limits:   ratelimit.NewStore(config.RateLimits, store),
+		Lockout:  lockout.NewGuard(config.Lockout, store),
+	}
+}
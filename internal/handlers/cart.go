@@ -1,110 +1,78 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
-	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
 )
 
 type NewProduct struct {
-	Product db.GetCartItemsRow      `json:"product"`
-	Images  []string                `json:"images"`
+	Product db.GetCartItemsRow `json:"product"`
+	Images  []string           `json:"images"`
 }
 
-func GetCartIDFromCookie(r *http.Request) (string, error) {
-	key := os.Getenv("JWT_KEY")
-	if key == "" {
-		log.Println("JWT_KEY environment variable is not set")
-		return "", fmt.Errorf("Permission denied")
-	}
-
-	cookieName := os.Getenv("COOKIE_NAME")
-	cookie, err := r.Cookie(cookieName)
-	if err != nil {
-		log.Println(err.Error())
-		return "", err
-	}
-
-	token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (any, error) {
-		return []byte(key), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
-	if err != nil {
-		log.Println(err.Error())
-		return "", fmt.Errorf("An unknown error occurred")
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		cartID := claims["cartID"]
-		if cartID != nil {
-			return cartID.(string), nil
-		}
-	}
+type AddItemRequest struct {
+	ProductID int32 `json:"productID" validate:"required"`
+	Quantity  int32 `json:"quantity" validate:"min=1"`
+}
 
-	return "", fmt.Errorf("An unknown error occurred")
+type QuantityRequest struct {
+	Quantity int32 `json:"quantity" validate:"min=1"`
 }
 
-func NewCartHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) NewCartHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	cart, err := methods.NewCart(ctx, conn)
+	cart, err := methods.NewCart(ctx, app.Store)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	_, err = auth.CreateJWT(w, r, cart)
+	_, err = app.Auth.CreateJWT(w, r, cart)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("New cart created: " + cart.ID.String()))
 }
 
-func GetCartProductsHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) GetCartProductsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	id, err := GetCartIDFromCookie(r)
+	id, err := app.Auth.CartID(r)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	p, err := uuid.Parse(id)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, auth.ErrPermissionDenied)
 		return
 	}
-	items, err := methods.GetItems(ctx, conn, p)
+	items, err := methods.GetItems(ctx, app.Store, p)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	products := make([]NewProduct, len(items))
 	for i := range len(items) {
-		images, err := methods.GetProductImagesByID(ctx, conn, items[i].ProductID)
+		images, err := methods.GetProductImagesByID(ctx, app.Store, items[i].ProductID)
 		if err != nil {
-			log.Println(err.Error())
-			http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+			apperr.Write(w, r, err)
 			return
 		}
 		products[i] = NewProduct{
@@ -115,8 +83,7 @@ func GetCartProductsHandler(w http.ResponseWriter, r *http.Request, ctx context.
 
 	j, err := json.Marshal(products)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -125,85 +92,63 @@ func GetCartProductsHandler(w http.ResponseWriter, r *http.Request, ctx context.
 
 }
 
-func ClearCartHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) ClearCartHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	id, err := GetCartIDFromCookie(r)
+	id, err := app.Auth.CartID(r)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	p, err := uuid.Parse(id)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, auth.ErrPermissionDenied)
 		return
 	}
 
-	if err := methods.ClearAll(ctx, conn, p); err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+	if err := methods.ClearAll(ctx, app.Store, p); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	cookie := &http.Cookie{
-		Name:    "dam_nation_shop",
-		Value:   "",
-		Path:    "/",
-		Expires: time.Unix(0, 0),
-		MaxAge:  -1, // or 0
-	}
-
-	http.SetCookie(w, cookie)
+	app.Auth.ClearCartCookie(w)
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Cart has been cleared"))
 }
 
-func AddItemHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) AddItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	id, err := GetCartIDFromCookie(r)
+	id, err := app.Auth.CartID(r)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	p, err := uuid.Parse(id)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, auth.ErrPermissionDenied)
 		return
 	}
 
-	prod := r.PostFormValue("productID")
-	prodID, err := strconv.Atoi(prod)
-	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+	var body AddItemRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	quan := r.PostFormValue("quantity")
-	q, err := strconv.Atoi(quan)
-	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
-		return
-	}
-	_, err = methods.GetProductByID(ctx, conn, int32(prodID))
+	_, err = methods.GetProductByID(ctx, app.Store, body.ProductID)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if err := methods.AddItem(ctx, conn, p, prodID, q); err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+	if err := methods.AddItem(ctx, app.Store, p, int(body.ProductID), int(body.Quantity)); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -211,34 +156,31 @@ func AddItemHandler(w http.ResponseWriter, r *http.Request, ctx context.Context,
 	w.Write([]byte("Item has been added to cart"))
 }
 
-func RemoveItemHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) RemoveItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	id, err := GetCartIDFromCookie(r)
+	id, err := app.Auth.CartID(r)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	p, err := uuid.Parse(id)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, auth.ErrPermissionDenied)
 		return
 	}
 
 	prod := chi.URLParam(r, "productID")
 	prodID, err := strconv.Atoi(prod)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, apperr.Invalid("productID", "Product ID must be a number"))
 		return
 	}
 
-	if err := methods.RemoveItem(ctx, conn, p, prodID); err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+	if err := methods.RemoveItem(ctx, app.Store, p, prodID); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -246,45 +188,49 @@ func RemoveItemHandler(w http.ResponseWriter, r *http.Request, ctx context.Conte
 	w.Write([]byte("Item has been removed from cart"))
 }
 
-func UpdateItemQuantityHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) UpdateItemQuantityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	id, err := GetCartIDFromCookie(r)
+	id, err := app.Auth.CartID(r)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	q := r.PostFormValue("quantity")
-	quan, err := strconv.Atoi(q)
-	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+	var body QuantityRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
 	prod := chi.URLParam(r, "productID")
 	prodID, err := strconv.Atoi(prod)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, apperr.Invalid("productID", "Product ID must be a number"))
 		return
 	}
 
 	p, err := uuid.Parse(id)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, auth.ErrPermissionDenied)
 		return
 	}
 
-	if err := methods.UpdateItemQuantity(ctx, conn, p, prodID, quan); err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+	if err := methods.UpdateItemQuantity(ctx, app.Store, p, prodID, int(body.Quantity)); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Item has been updated in the cart"))
 }
+
+// CartScope scopes idempotency keys to the cart in the request's cookie
+func (app *App) CartScope(r *http.Request) (string, error) {
+	id, err := app.Auth.CartID(r)
+	if err != nil {
+		return "", err
+	}
+	return "cart:" + id, nil
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+)
+
+// CartGCStatsHandler reports how many carts the cleanup worker has purged
+func (app *App) CartGCStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	j, err := json.Marshal(app.CartGC.Stats())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
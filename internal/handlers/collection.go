@@ -1,25 +1,30 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
 )
 
-func GetCollectionsHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+type CollectionRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description"`
+}
+
+func (app *App) GetCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	collections, err := methods.GetCollections(ctx, conn)
+	collections, err := methods.GetCollections(ctx, app.Store)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -27,7 +32,7 @@ func GetCollectionsHandler(w http.ResponseWriter, r *http.Request, ctx context.C
 		empty := make([]db.Collection, 0)
 		json, err := json.Marshal(empty)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			apperr.Write(w, r, err)
 			return
 		}
 
@@ -38,70 +43,75 @@ func GetCollectionsHandler(w http.ResponseWriter, r *http.Request, ctx context.C
 
 	json, err := json.Marshal(collections)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(json)
 }
 
-func CreateCollectionHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) CreateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	name := r.FormValue("name")
-	description := r.FormValue("description")
-	var c db.Collection
+	var body CollectionRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
 
-	c.Name = name
-	c.Description = pgtype.Text{String: description, Valid: true}
-	collection, err := methods.CreateCollection(ctx, conn, c)
+	var c db.Collection
+	c.Name = body.Name
+	c.Description = pgtype.Text{String: body.Description, Valid: true}
+	collection, err := methods.CreateCollection(ctx, app.Store, c)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	json, err := json.Marshal(collection)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(json)
 }
 
-func GetCollectionByIDHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) GetCollectionByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	id := chi.URLParam(r, "id")
 	intId, err := strconv.Atoi(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid collection ID"))
 		return
 	}
 
-	collection, err := methods.GetCollection(ctx, conn, intId)
+	collection, err := methods.GetCollection(ctx, app.Store, intId)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	json, err := json.Marshal(collection)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(json)
 }
 
-func DeleteCollectionByIDHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) DeleteCollectionByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
 	id := chi.URLParam(r, "id")
 	idInt, _ := strconv.Atoi(id)
-	if err := methods.DeleteCollection(ctx, conn, idInt); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := methods.DeleteCollection(ctx, app.Store, idInt); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -109,15 +119,28 @@ func DeleteCollectionByIDHandler(w http.ResponseWriter, r *http.Request, ctx con
 	w.Write([]byte("Collection deleted"))
 }
 
-func UpdateCollectionByIDHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) UpdateCollectionByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	// id := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid collection ID"))
+		return
+	}
+
+	var body CollectionRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
 	var c db.Collection
-	// idInt,V _ := strconv.Atoi(id)
-	if err := methods.UpdateCollection(ctx, conn, c); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	c.ID = int32(id)
+	c.Name = body.Name
+	c.Description = pgtype.Text{String: body.Description, Valid: true}
+	if err := methods.UpdateCollection(ctx, app.Store, c); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -125,17 +148,17 @@ func UpdateCollectionByIDHandler(w http.ResponseWriter, r *http.Request, ctx con
 	w.Write([]byte("Collection updated"))
 }
 
-func AddProductToCollectionHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) AddProductToCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	collectionID := chi.URLParam(r, "collectionID")
+	collectionID := chi.URLParam(r, "id")
 	productID := chi.URLParam(r, "productID")
 	collectionIDInt, _ := strconv.Atoi(collectionID)
 	productIDInt, _ := strconv.Atoi(productID)
 
-	if err := methods.AddProductToCollection(ctx, conn, collectionIDInt, productIDInt); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := methods.AddProductToCollection(ctx, app.Store, collectionIDInt, productIDInt); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -143,17 +166,17 @@ func AddProductToCollectionHandler(w http.ResponseWriter, r *http.Request, ctx c
 	w.Write([]byte("Product added to collection"))
 }
 
-func RemoveProductFromCollectionHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) RemoveProductFromCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	collectionID := chi.URLParam(r, "collectionID")
+	collectionID := chi.URLParam(r, "id")
 	productID := chi.URLParam(r, "productID")
 	collectionIDInt, _ := strconv.Atoi(collectionID)
 	productIDInt, _ := strconv.Atoi(productID)
 
-	if err := methods.RemoveProductFromCollection(ctx, conn, collectionIDInt, productIDInt); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := methods.RemoveProductFromCollection(ctx, app.Store, collectionIDInt, productIDInt); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
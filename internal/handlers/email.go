@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+)
+
+func (app *App) ListEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			apperr.Write(w, r, apperr.Validation("Limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	emails, err := methods.GetEmails(ctx, app.Store, limit)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(emails)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+)
+
+// FulfillmentRequest creates or updates a fulfillment. Forms send items as
+// repeated itemID/quantity fields.
+type FulfillmentRequest struct {
+	Carrier        string                   `json:"carrier" validate:"max=100"`
+	TrackingNumber string                   `json:"trackingNumber" validate:"max=255"`
+	TrackingURL    string                   `json:"trackingURL" validate:"url"`
+	Status         string                   `json:"status" validate:"oneof=pending shipped delivered"`
+	Items          []FulfillmentItemRequest `json:"items"`
+}
+
+type FulfillmentItemRequest struct {
+	ItemID   int32 `json:"itemID" validate:"required"`
+	Quantity int32 `json:"quantity" validate:"min=1"`
+}
+
+type TrackOrderRequest struct {
+	OrderNumber int32  `json:"orderNumber" validate:"required"`
+	Email       string `json:"email" validate:"required,email"`
+}
+
+func (b FulfillmentRequest) input() methods.FulfillmentInput {
+	in := methods.FulfillmentInput{
+		Carrier:        b.Carrier,
+		TrackingNumber: b.TrackingNumber,
+		TrackingURL:    b.TrackingURL,
+		Status:         b.Status,
+	}
+	for _, item := range b.Items {
+		in.Items = append(in.Items, methods.FulfillmentItemRequest{OrderItemID: item.ItemID, Quantity: item.Quantity})
+	}
+	return in
+}
+
+// CreateFulfillmentHandler ships order items. Without items everything left
+// on the order ships.
+func (app *App) CreateFulfillmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	var body FulfillmentRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	actorID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	f, err := methods.CreateFulfillment(ctx, app.Store, int32(id), body.input(), methods.AdminActor(actorID))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(f)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(j)
+}
+
+func (app *App) UpdateFulfillmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	fulfillmentID, err := strconv.Atoi(chi.URLParam(r, "fulfillmentID"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("fulfillmentID", "Invalid fulfillment ID"))
+		return
+	}
+
+	actorID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	var body FulfillmentRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	f, err := methods.UpdateFulfillment(ctx, app.Store, int32(id), int32(fulfillmentID), body.input(), methods.AdminActor(actorID))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(f)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// TrackOrderHandler lets customers follow their order without logging in by
+// giving the order number and the email used at checkout
+func (app *App) TrackOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body TrackOrderRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	tracking, err := methods.TrackOrder(ctx, app.Store, body.OrderNumber, body.Email)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(tracking)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/health"
+)
+
+// readyTimeout bounds the database checks so a hung connection fails the
+// probe instead of stalling it
+const readyTimeout = 2 * time.Second
+
+// LivenessHandler answers as long as the process can serve requests
+func (app *App) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// ReadinessHandler reports whether the API should receive traffic, with the
+// result of each check. It answers 503 when any check fails. The probe is
+// public, so why a check failed, e.g. the columns a schema is missing, is
+// only logged.
+func (app *App) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	report := app.Health.Ready(ctx)
+	for name, result := range report.Checks {
+		if result != health.StatusOK {
+			app.Logger.WarnContext(ctx, "Readiness check failed", "check", name, "result", result)
+			report.Checks[name] = health.StatusUnavailable
+		}
+	}
+
+	j, err := json.Marshal(report)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if report.Status != health.StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.Write(j)
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/lockout"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/ratelimit"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+)
+
+// MFACodeRequest carries a code from the authenticator app, or a recovery
+// code where one is accepted
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// RecoveryCodesResponse lists new recovery codes. They aren't stored in the
+// clear so this is the only time they're shown.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+	LoggedIn      bool     `json:"loggedIn,omitempty"` // the admin cookie was set
+}
+
+// mfaSubject is who a two-factor request is for: the user behind a login's
+// challenge, or else the logged in admin
+func (app *App) mfaSubject(r *http.Request) (user db.User, challenge, setup bool, err error) {
+	ctx := r.Context()
+
+	id, setup, err := app.Auth.MFAChallenge(r)
+	if err == nil {
+		challenge = true
+	} else {
+		cookie, cerr := r.Cookie(app.Config.Auth.AdminCookieName)
+		if cerr != nil {
+			return db.User{}, false, false, auth.ErrPermissionDenied
+		}
+		if err := app.Auth.ValidateAdminJWT(ctx, cookie.Value); err != nil {
+			return db.User{}, false, false, err
+		}
+		if id, err = app.Auth.AdminID(r); err != nil {
+			return db.User{}, false, false, err
+		}
+	}
+
+	user, err = methods.GetUser(ctx, app.Store, id)
+	if err != nil || user.ID == 0 {
+		return db.User{}, false, false, auth.ErrPermissionDenied
+	}
+	return user, challenge, setup, nil
+}
+
+// StartMFASetupHandler creates a secret for the admin's authenticator app.
+// It's confirmed with EnableMFAHandler.
+func (app *App) StartMFASetupHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	user, _, _, err := app.mfaSubject(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	setup, err := methods.StartMFASetup(ctx, app.Store, user.ID, app.Config.Auth.MFAIssuer, user.Email)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(setup)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// EnableMFAHandler turns two-factor authentication on with a first code from
+// the app and returns the recovery codes. An admin who had to set it up to
+// log in is logged in as well.
+func (app *App) EnableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body MFACodeRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	user, challenge, _, err := app.mfaSubject(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	account := strings.ToLower(user.Email)
+
+	codes, err := methods.EnableMFA(ctx, app.Store, user.ID, body.Code)
+	if challenge && errors.Is(err, methods.ErrMFAInvalidCode) {
+		app.loginFailed(w, r, methods.SecurityEvent{Type: methods.SecurityMFAFailed, UserID: user.ID, Email: account, Detail: "wrong code during setup"}, err)
+		return
+	}
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Enable two-factor authentication error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityMFAEnabled, UserID: user.ID, Email: account})
+
+	res := RecoveryCodesResponse{RecoveryCodes: codes}
+	if challenge {
+		if err := app.completeLogin(w, r, user, "two-factor set up"); err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+		res.LoggedIn = true
+	}
+
+	j, err := json.Marshal(res)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// VerifyMFAHandler finishes a login with a code from the app or a recovery
+// code. Wrong codes count towards the login lockout.
+func (app *App) VerifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body MFACodeRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	id, setup, err := app.Auth.MFAChallenge(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if setup {
+		apperr.Write(w, r, methods.ErrMFANotSetUp)
+		return
+	}
+	user, err := methods.GetUser(ctx, app.Store, id)
+	if err != nil || user.ID == 0 {
+		apperr.Write(w, r, auth.ErrPermissionDenied)
+		return
+	}
+	account := strings.ToLower(user.Email)
+
+	// Codes are guessed like passwords, so they share the login lockout
+	wait, err := app.Lockout.Check(ctx, account, ratelimit.ClientIP(r))
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error checking login lockout", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		apperr.Write(w, r, lockout.ErrLocked)
+		return
+	}
+
+	usedRecoveryCode, err := methods.VerifyMFA(ctx, app.Store, user.ID, body.Code)
+	if errors.Is(err, methods.ErrMFAInvalidCode) {
+		app.loginFailed(w, r, methods.SecurityEvent{Type: methods.SecurityMFAFailed, UserID: user.ID, Email: account, Detail: "wrong code"}, err)
+		return
+	}
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Verify two-factor code error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	detail := "two-factor code"
+	if usedRecoveryCode {
+		detail = "recovery code"
+		app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityMFARecoveryCodeUsed, UserID: user.ID, Email: account})
+	}
+	if err := app.completeLogin(w, r, user, detail); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	writeLoginResponse(w, r, LoginResponse{LoggedIn: true})
+}
+
+// MFAStatusHandler says whether the logged in admin has two-factor
+// authentication on and how many recovery codes are left
+func (app *App) MFAStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	status, err := methods.GetMFAStatus(ctx, app.Store, id)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Get two-factor status error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(status)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// RegenerateRecoveryCodesHandler replaces the logged in admin's recovery
+// codes after checking a code from the app
+func (app *App) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body MFACodeRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	id, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	codes, err := methods.RegenerateRecoveryCodes(ctx, app.Store, id, body.Code)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Regenerate recovery codes error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityMFARecoveryCodesRegenerated, UserID: id})
+
+	j, err := json.Marshal(RecoveryCodesResponse{RecoveryCodes: codes})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// ResetMFAHandler turns two-factor authentication off for another admin who
+// lost their authenticator and recovery codes
+func (app *App) ResetMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Validation("Invalid user ID"))
+		return
+	}
+
+	adminID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if int32(id) == adminID {
+		// otherwise a stolen session could drop the second factor
+		apperr.Write(w, r, apperr.Conflict("Another admin has to reset your two-factor authentication"))
+		return
+	}
+
+	if err := methods.ResetMFA(ctx, app.Store, int32(id)); err != nil {
+		app.Logger.ErrorContext(ctx, "Reset two-factor authentication error", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+	app.securityEvent(r, methods.SecurityEvent{Type: methods.SecurityMFAReset, UserID: int32(id), Detail: fmt.Sprintf("by admin %d", adminID)})
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Two-factor authentication reset"))
+}
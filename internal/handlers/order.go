@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+)
+
+func (app *App) ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	orders, err := methods.GetOrders(ctx, app.Store)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(orders)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) GetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	order, err := methods.GetOrder(ctx, app.Store, int32(id))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(order)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Note   string `json:"note"`
+}
+
+// UpdateOrderStatusHandler moves an order through the state machine by hand,
+// e.g. to cancel it or mark a payment taken outside Stripe
+func (app *App) UpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	var body OrderStatusRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if !methods.ValidOrderStatus(body.Status) {
+		apperr.Write(w, r, apperr.Invalid("status", "Unknown order status"))
+		return
+	}
+
+	actorID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	order, err := methods.TransitionOrder(ctx, app.Store, int32(id), body.Status, methods.AdminActor(actorID), body.Note)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(order)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -1,16 +1,15 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
 )
 
 type NewProductHandler struct {
@@ -19,72 +18,106 @@ type NewProductHandler struct {
 	Sizes   []methods.Size `json:"sizes"`
 }
 
-func CreateProductHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+type CreateProductRequest struct {
+	ProductName        string  `json:"productName" validate:"required,max=255"`
+	ProductDescription string  `json:"productDescription"`
+	ProductPrice       float64 `json:"productPrice" validate:"required,min=0"`
+	PriceID            string  `json:"priceID" validate:"required,max=255"`
+	TaxCategory        string  `json:"taxCategory" validate:"max=50"`
+	WeightGrams        *int32  `json:"weightGrams" validate:"min=0"`
+}
+
+type UpdateProductRequest struct {
+	ProductName        string  `json:"productName" validate:"required,max=255"`
+	ProductDescription string  `json:"productDescription"`
+	ProductPrice       float64 `json:"productPrice" validate:"required,min=0"`
+	TaxCategory        string  `json:"taxCategory" validate:"max=50"`
+	WeightGrams        *int32  `json:"weightGrams" validate:"min=0"`
+}
+
+type StockRequest struct {
+	Stock *int32 `json:"stock" validate:"required,min=0"`
+}
+
+func (app *App) CreateProductHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
-	
+
+	var body CreateProductRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
 	var p methods.Product
-	name := r.PostFormValue("productName")
-	description := r.PostFormValue("productDescription")
-	price := r.PostFormValue("productPrice")
-	priceID := r.PostFormValue("priceID")
-	p.Name = name
-	p.Description = description
-	floatPrice, _ := strconv.ParseFloat(price, 64)
-	p.Price = floatPrice
-	p.PriceID = priceID
-
-	product, err := methods.CreateProduct(ctx, conn, p)
+	p.Name = body.ProductName
+	p.Description = body.ProductDescription
+	p.Price = body.ProductPrice
+	p.PriceID = body.PriceID
+
+	product, err := methods.CreateProduct(ctx, app.Store, p)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if err := methods.AddProductSizes(ctx, conn, product.ID, []methods.Size{
+	if body.TaxCategory != "" {
+		if err := methods.SetProductTaxCategory(ctx, app.Store, product.ID, body.TaxCategory); err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+		product.TaxCategory = body.TaxCategory
+	}
+
+	if body.WeightGrams != nil {
+		if err := methods.SetProductWeight(ctx, app.Store, product.ID, int(*body.WeightGrams)); err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+		product.WeightGrams = *body.WeightGrams
+	}
+
+	if err := methods.AddProductSizes(ctx, app.Store, product.ID, []methods.Size{
 		{Size: "S", Stock: 10},
 		{Size: "M", Stock: 20},
 		{Size: "L", Stock: 15},
 		{Size: "XL", Stock: 5},
-	}); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}, app.Config.StockLow); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
-	if err := methods.AddProductImages(ctx, conn, product.ID, []string{
+	if err := methods.AddProductImages(ctx, app.Store, product.ID, []string{
 		"https://example.com/image1.jpg",
 		"https://example.com/image2.jpg",
 	}); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	j, err := json.Marshal(product)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(j)
 }
 
-func ListProductsHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) ListProductsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
-	products, err := methods.GetProducts(ctx, conn)
+	products, err := methods.GetProducts(ctx, app.Store)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	// Marshal the products into JSON
 	j, err := json.Marshal(products)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -92,22 +125,21 @@ func ListProductsHandler(w http.ResponseWriter, r *http.Request, ctx context.Con
 	w.Write(j)
 }
 
-func GetProductHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	id := chi.URLParam(r, "id")
 	strId, _ := strconv.Atoi(id)
-	product, err := methods.GetProductByID(ctx, conn, int32(strId))
+	product, err := methods.GetProductByID(ctx, app.Store, int32(strId))
 
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	j, err := json.Marshal(product)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -115,14 +147,14 @@ func GetProductHandler(w http.ResponseWriter, r *http.Request, ctx context.Conte
 	w.Write(j)
 }
 
-func DeleteProductHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
 	id := chi.URLParam(r, "id")
 	idInt, _ := strconv.Atoi(id)
-	if err := methods.RemoveProduct(ctx, conn, idInt); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := methods.RemoveProduct(ctx, app.Store, idInt); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -130,27 +162,72 @@ func DeleteProductHandler(w http.ResponseWriter, r *http.Request, ctx context.Co
 	w.Write([]byte("Product deleted"))
 }
 
-func UpdateProductHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	id := chi.URLParam(r, "id")
-	name := r.PostFormValue("productName")
-	description := r.PostFormValue("productDescription")
-	price := r.PostFormValue("productPrice")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid product ID"))
+		return
+	}
+
+	var body UpdateProductRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
 
 	var p methods.Product
-	p.Name = name
-	p.Description = description
-	floatPrice, _ := strconv.ParseFloat(price, 64)
-	p.Price = floatPrice
-	p.ID, _ = strconv.Atoi(id)
+	p.ID = id
+	p.Name = body.ProductName
+	p.Description = body.ProductDescription
+	p.Price = body.ProductPrice
 
-	if err := methods.UpdateProduct(ctx, conn, p); err != nil {
-		log.Println(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := methods.UpdateProduct(ctx, app.Store, p); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
+	if body.TaxCategory != "" {
+		if err := methods.SetProductTaxCategory(ctx, app.Store, int32(p.ID), body.TaxCategory); err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+
+	if body.WeightGrams != nil {
+		if err := methods.SetProductWeight(ctx, app.Store, int32(p.ID), int(*body.WeightGrams)); err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Product updated"))
 }
+
+func (app *App) UpdateProductStockHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid product ID"))
+		return
+	}
+
+	var body StockRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if err := methods.SetProductStock(ctx, app.Store, int32(id), chi.URLParam(r, "size"), int(*body.Stock), app.Config.StockLow); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Stock updated"))
+}
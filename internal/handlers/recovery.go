@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/recovery"
+)
+
+// RestoreCartHandler handles the link in a recovery reminder. It sets the
+// cart cookie again and sends the customer on to the cart page.
+func (app *App) RestoreCartHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	cartID, err := recovery.VerifyToken(app.Config.Auth.JWTKey, r.URL.Query().Get("token"))
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error verifying recovery token", "err", err)
+		apperr.Write(w, r, apperr.Invalid("token", "This link is invalid or has expired"))
+		return
+	}
+
+	cart, err := methods.RestoreCart(ctx, app.Store, cartID)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error restoring cart", "err", err)
+		apperr.Write(w, r, apperr.NotFound("This cart is no longer available"))
+		return
+	}
+
+	if _, err := app.Auth.CreateJWT(w, r, cart); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if app.Config.CartRestoreURL != "" {
+		http.Redirect(w, r, app.Config.CartRestoreURL, http.StatusFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Cart restored: " + cart.ID.String()))
+}
+
+// CartRecoveryReportHandler reports reminders sent between from and to
+// (YYYY-MM-DD, defaulting to the last 30 days)
+func (app *App) CartRecoveryReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			apperr.Write(w, r, apperr.Validation("From must be a date like 2006-01-02"))
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			apperr.Write(w, r, apperr.Validation("To must be a date like 2006-01-02"))
+			return
+		}
+		// Include the whole of the last day
+		to = t.AddDate(0, 0, 1)
+	}
+
+	report, err := methods.GetRecoveryReport(ctx, app.Store, from, to)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(report)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+)
+
+// RefundRequest refunds an order. Leaving out the amount refunds everything
+// left on the order.
+type RefundRequest struct {
+	Amount   float64 `json:"amount" validate:"min=0"`
+	ReturnID int32   `json:"returnID" validate:"min=0"`
+	Reason   string  `json:"reason" validate:"required"`
+}
+
+func (app *App) CreateRefundHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	var body RefundRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	actorID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	refund, err := methods.RefundOrder(ctx, app.Store, app.Payments, methods.RefundInput{
+		OrderID:  int32(id),
+		ReturnID: body.ReturnID,
+		Amount:   body.Amount,
+		Reason:   body.Reason,
+		ActorID:  actorID,
+	})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(refund)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(j)
+}
+
+// RetryRefundHandler sends a refund the payment provider never confirmed
+// again, under the same idempotency key
+func (app *App) RetryRefundHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	refundID, err := strconv.Atoi(chi.URLParam(r, "refundID"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("refundID", "Invalid refund ID"))
+		return
+	}
+
+	actorID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	refund, err := methods.RetryRefund(ctx, app.Store, app.Payments, int32(id), int32(refundID), actorID)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(refund)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) ListRefundsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid order ID"))
+		return
+	}
+
+	refunds, err := methods.GetOrderRefunds(ctx, app.Store, int32(id))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(refunds)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
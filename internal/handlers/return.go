@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+)
+
+// ReturnRequest asks to return order items. Forms send items as repeated
+// itemID/quantity fields with optional size and itemReason fields in the
+// same order.
+type ReturnRequest struct {
+	OrderID int32               `json:"orderID" validate:"required"`
+	Email   string              `json:"email" validate:"required,email"`
+	Reason  string              `json:"reason" validate:"required"`
+	Items   []ReturnItemRequest `json:"items" validate:"required"`
+}
+
+type ReturnItemRequest struct {
+	ItemID     int32  `json:"itemID" validate:"required"`
+	Quantity   int32  `json:"quantity" validate:"min=1"`
+	Size       string `json:"size" validate:"max=50"`
+	ItemReason string `json:"itemReason"`
+}
+
+type ApproveReturnRequest struct {
+	Restock *bool  `json:"restock"`
+	Refund  *bool  `json:"refund"`
+	Note    string `json:"note"`
+}
+
+type NoteRequest struct {
+	Note string `json:"note"`
+}
+
+// CreateReturnHandler lets a customer request a return
+func (app *App) CreateReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body ReturnRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	items := make([]methods.ReturnItemRequest, len(body.Items))
+	for i, item := range body.Items {
+		items[i] = methods.ReturnItemRequest{
+			OrderItemID: item.ItemID,
+			Quantity:    item.Quantity,
+			SizeName:    item.Size,
+			Reason:      item.ItemReason,
+		}
+	}
+
+	ret, err := methods.RequestReturn(ctx, app.Store, body.OrderID, body.Email, body.Reason, items)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(ret)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(j)
+}
+
+func (app *App) ListReturnsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	returns, err := methods.GetReturns(ctx, app.Store)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(returns)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) GetReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid return ID"))
+		return
+	}
+
+	ret, err := methods.GetReturn(ctx, app.Store, int32(id))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(ret)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// ApproveReturnHandler restocks and refunds by default; send restock=false or
+// refund=false to skip either step
+func (app *App) ApproveReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid return ID"))
+		return
+	}
+
+	var body ApproveReturnRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	restock, refund := true, true
+	if body.Restock != nil {
+		restock = *body.Restock
+	}
+	if body.Refund != nil {
+		refund = *body.Refund
+	}
+
+	actorID, err := app.Auth.AdminID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	ret, err := methods.ApproveReturn(ctx, app.Store, app.Payments, int32(id), body.Note, restock, refund, actorID)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(ret)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) RejectReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid return ID"))
+		return
+	}
+
+	var body NoteRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	ret, err := methods.RejectReturn(ctx, app.Store, int32(id), body.Note)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(ret)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+)
+
+// RefreshCartSessionHandler swaps the cart's refresh cookie for a new
+// access token and refresh token
+func (app *App) RefreshCartSessionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+
+	if _, err := app.Auth.Refresh(w, r, auth.TypeCart); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Session refreshed"))
+}
+
+// RefreshAdminSessionHandler swaps the admin's refresh cookie for a new
+// access token and refresh token. A refresh token used twice ends the
+// session and is logged as a security event.
+func (app *App) RefreshAdminSessionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	session, err := app.Auth.Refresh(w, r, auth.TypeAdmin)
+	if errors.Is(err, auth.ErrRefreshReused) {
+		e := methods.SecurityEvent{Type: methods.SecurityRefreshTokenReused, UserID: session.UserID.Int32, Detail: "session " + session.ID.String() + " revoked"}
+		if user, err := methods.GetUser(ctx, app.Store, e.UserID); err == nil {
+			e.Email = strings.ToLower(user.Email)
+		}
+		app.securityEvent(r, e)
+	}
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Session refreshed"))
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+	"github.com/petermazzocco/go-ecommerce-api/internal/shipping"
+)
+
+// parseRegions reads a comma separated list of countries or country-state
+// pairs, e.g. "US,CA,AU-NSW"
+func parseRegions(s string) []shipping.Region {
+	var regions []shipping.Region
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		country, state, _ := strings.Cut(part, "-")
+		regions = append(regions, shipping.Region{Country: country, State: state})
+	}
+	return regions
+}
+
+type ShippingZoneRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+	// Regions are countries or country-state pairs; forms send them comma separated
+	Regions []string `json:"regions" validate:"required"`
+}
+
+// ShippingMethodRequest adds a method to a zone. Forms send tiers as
+// matching repeated tierMin and tierRate fields.
+type ShippingMethodRequest struct {
+	Name     string                `json:"name" validate:"required,max=255"`
+	Type     string                `json:"type" validate:"required,oneof=flat weight price"`
+	Rate     float64               `json:"rate" validate:"min=0"`
+	FreeOver float64               `json:"freeOver" validate:"min=0"`
+	MinDays  int                   `json:"minDays" validate:"min=0"`
+	MaxDays  int                   `json:"maxDays" validate:"min=0"`
+	Tiers    []ShippingTierRequest `json:"tiers"`
+}
+
+type ShippingTierRequest struct {
+	TierMin  float64 `json:"tierMin" validate:"min=0"`
+	TierRate float64 `json:"tierRate" validate:"min=0"`
+}
+
+type ShippingMethodUpdateRequest struct {
+	Active *bool `json:"active" validate:"required"`
+}
+
+func (app *App) ListShippingZonesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	zones, err := methods.GetShippingZones(ctx, app.Store)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(zones)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) CreateShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body ShippingZoneRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	zone, err := methods.CreateShippingZone(ctx, app.Store, body.Name, parseRegions(strings.Join(body.Regions, ",")))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(zone)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) DeleteShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid shipping zone ID"))
+		return
+	}
+
+	if err := methods.DeleteShippingZone(ctx, app.Store, id); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Shipping zone deleted"))
+}
+
+// CreateShippingMethodHandler adds a method to the zone in the URL
+func (app *App) CreateShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	zoneID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid shipping zone ID"))
+		return
+	}
+
+	var body ShippingMethodRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	tiers := make([]methods.ShippingTier, len(body.Tiers))
+	for i, tier := range body.Tiers {
+		tiers[i] = methods.ShippingTier{Min: tier.TierMin, Rate: tier.TierRate}
+	}
+
+	m, err := methods.CreateShippingMethod(ctx, app.Store, methods.ShippingMethod{
+		ZoneID:   zoneID,
+		Name:     body.Name,
+		Type:     body.Type,
+		Rate:     body.Rate,
+		FreeOver: body.FreeOver,
+		MinDays:  body.MinDays,
+		MaxDays:  body.MaxDays,
+		Tiers:    tiers,
+	})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(m)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) UpdateShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid shipping method ID"))
+		return
+	}
+
+	var body ShippingMethodUpdateRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if err := methods.SetShippingMethodActive(ctx, app.Store, id, *body.Active); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Shipping method updated"))
+}
+
+func (app *App) DeleteShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid shipping method ID"))
+		return
+	}
+
+	if err := methods.DeleteShippingMethod(ctx, app.Store, id); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Shipping method deleted"))
+}
+
+func (app *App) GetShippingRatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := app.Auth.CartID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	p, err := uuid.Parse(id)
+	if err != nil {
+		apperr.Write(w, r, auth.ErrPermissionDenied)
+		return
+	}
+
+	rates, err := methods.QuoteShipping(ctx, app.Store, p, addressFromRequest(r))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(rates)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -1,106 +1,191 @@
 package handlers
 
 import (
-	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/idempotency"
 	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
-	"github.com/stripe/stripe-go/v82"
-	"github.com/stripe/stripe-go/v82/customer"
-	"github.com/stripe/stripe-go/v82/checkout/session"
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tax"
 )
 
-func CreateCheckoutSession(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+// CheckoutRequest starts a checkout for the cart in the cookie. The address
+// is used to calculate tax and quote shipping.
+type CheckoutRequest struct {
+	Country          string `json:"country" validate:"len=2"`
+	State            string `json:"state" validate:"max=50"`
+	PostalCode       string `json:"postalCode" validate:"max=20"`
+	ShippingMethodID int32  `json:"shippingMethodID" validate:"min=0"`
+	SuccessURL       string `json:"successURL"`
+	CancelURL        string `json:"cancelURL"`
+	Email            string `json:"email" validate:"email,max=255"`
+	Name             string `json:"name" validate:"max=255"`
+}
+
+func (app *App) CreateCheckoutSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 
-	// Load Stripe key from environment variable
-	stripe.Key = os.Getenv("STRIPE_KEY")
+	var body CheckoutRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
 
 	// Get cart ID from cookie
-	id, err := GetCartIDFromCookie(r)
+	id, err := app.Auth.CartID(r)
 	if err != nil {
-		log.Println("Error getting cart ID from cookie:", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Error getting cart ID from cookie", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	// Parse the cart ID from the cookie
 	strID, err := uuid.Parse(id)
 	if err != nil {
-		log.Println("Error parsing cart ID:", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, r, auth.ErrPermissionDenied)
 		return
 	}
 
 	// Get cart items from the database
-	cart, err := methods.GetCart(ctx, conn, strID)
+	cart, err := methods.GetCart(ctx, app.Store, strID)
 	if err != nil {
-		log.Println("Error getting cart:", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Error getting cart", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	// Get items in the cart
-	items, err := methods.GetItems(ctx, conn, strID)
+	// Get items in the cart with tax calculated for the destination
+	summary, err := methods.GetCartSummary(ctx, app.Store, app.Config.Tax, strID, tax.Address{
+		Country:    body.Country,
+		State:      body.State,
+		PostalCode: body.PostalCode,
+	})
 	if err != nil {
-		log.Println("Error getting items:", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return	
+		app.Logger.ErrorContext(ctx, "Error getting cart summary", "err", err)
+		apperr.Write(w, r, err)
+		return
 	}
+	items := summary.Items
 
 	if len(items) == 0 {
-		log.Println("No items in cart")
-		http.Error(w, "No items in cart", http.StatusBadRequest)
+		app.Logger.WarnContext(ctx, "No items in cart", "cart_id", id)
+		apperr.Write(w, r, apperr.Validation("No items in cart"))
 		return
 	}
 
-	// Stripe line items
-	var lineItems []*stripe.CheckoutSessionLineItemParams
+	// Offer the chosen shipping method, or every method for the destination
+	var shippingRates []methods.ShippingRate
+	if body.ShippingMethodID != 0 {
+		summary, err = methods.ApplyShipping(ctx, app.Store, summary, int(body.ShippingMethodID))
+		if err != nil {
+			app.Logger.ErrorContext(ctx, "Error applying shipping", "err", err)
+			apperr.Write(w, r, err)
+			return
+		}
+		shippingRates = []methods.ShippingRate{*summary.Shipping}
+	} else {
+		shippingRates, err = methods.QuoteShipping(ctx, app.Store, strID, summary.Address)
+		if err != nil {
+			app.Logger.ErrorContext(ctx, "Error quoting shipping", "err", err)
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+
+	checkout := payments.CheckoutRequest{
+		CartID:       cart.ID.String(),
+		Currency:     methods.DefaultCurrency,
+		AutomaticTax: summary.TaxDeferred,
+	}
 	for _, item := range items {
-		lineItem := &stripe.CheckoutSessionLineItemParams{
-			Price:    stripe.String(string(item.PriceID)), 
-			Quantity: stripe.Int64(int64(item.Quantity)),
+		checkout.Items = append(checkout.Items, payments.CheckoutItem{
+			PriceID:  string(item.PriceID),
+			Quantity: int64(item.Quantity),
+		})
+	}
+
+	// Exclusive tax from the rule table is charged as its own line item
+	if !summary.TaxDeferred && !summary.TaxInclusive {
+		for _, line := range summary.TaxLines {
+			if line.Amount <= 0 {
+				continue
+			}
+			checkout.Charges = append(checkout.Charges, payments.CheckoutCharge{
+				Name:   fmt.Sprintf("%s (%s%%)", line.Name, strconv.FormatFloat(line.Rate, 'f', -1, 64)),
+				Amount: int64(math.Round(line.Amount * 100)),
+			})
+		}
+	}
+
+	for _, rate := range shippingRates {
+		checkout.ShippingOptions = append(checkout.ShippingOptions, payments.ShippingOption{
+			MethodID: rate.MethodID,
+			Name:     rate.Name,
+			Amount:   int64(math.Round(rate.Amount * 100)),
+			MinDays:  rate.MinDays,
+			MaxDays:  rate.MaxDays,
+		})
+	}
+
+	// Return URLs and shipping countries come from the store configuration
+	config := app.Config.Checkout
+	successURL, cancelURL, err := config.ReturnURLs(body.SuccessURL, body.CancelURL)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error validating return URLs", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	allowedCountries, err := config.Countries(ctx, app.Store)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error getting shipping countries", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	// The provider creates a customer for the email and name
+	email := body.Email
+	checkout.Email = email
+	checkout.Name = body.Name
+
+	// Keep the email so the cart can be recovered if checkout is abandoned
+	if email != "" {
+		if err := methods.SetCartEmail(ctx, app.Store, strID, email); err != nil {
+			app.Logger.ErrorContext(ctx, "Error saving cart email", "err", err)
 		}
-		lineItems = append(lineItems, lineItem)
-	}
-
-	// Create a stripe Customer if needed
-	email := r.PostFormValue("email")
-	name := r.PostFormValue("name")
-	customerParams := &stripe.CustomerParams{
-		Email: stripe.String(email),
-		Name:  stripe.String(name),
-		// Add more customer details as needed
-	}
-
-	customer, err := customer.New(customerParams)
-
-	// Stripe checkout session params
-	params := &stripe.CheckoutSessionParams{
-		SuccessURL: stripe.String("https://example.com/success"),
-		CancelURL:  stripe.String("https://example.com/cancel"),
-		LineItems:  lineItems,
-		Metadata: map[string]string{
-			"cartID": cart.ID.String(),
-			// Add more metadata as needed here
-		},
-		BillingAddressCollection: stripe.String(stripe.CheckoutSessionBillingAddressCollectionRequired),
-		ShippingAddressCollection: &stripe.CheckoutSessionShippingAddressCollectionParams{
-			AllowedCountries: stripe.StringSlice([]string{"US", "CA"}), // Example allowed AllowedCountries
-		},
-		Customer: stripe.String(customer.ID),
-		Mode: stripe.String(stripe.CheckoutSessionModePayment),
-	}
-
-	// Create the checkout session
-	result, err := session.New(params)
+	}
+
+	checkout.SuccessURL = successURL
+	checkout.CancelURL = cancelURL
+	checkout.AllowedCountries = allowedCountries
+
+	// Forward the client's key so the provider doesn't create duplicates either
+	if key := r.Header.Get(idempotency.Header); key != "" {
+		// Stripe keys are account wide, so scope them to the cart
+		checkout.IdempotencyKey = cart.ID.String() + ":" + key
+	}
+
+	result, err := app.Payments.CreateCheckout(ctx, checkout)
 	if err != nil {
-		log.Println("Error creating checkout session:", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Error creating checkout session", "err", err)
+		apperr.Write(w, r, apperr.Internal("Error creating checkout session"))
+		return
+	}
+
+	// Store the order with its tax lines so they survive the cart
+	if _, err := methods.CreateOrder(ctx, app.Store, strID, result.ID, email, summary); err != nil {
+		app.Logger.ErrorContext(ctx, "Error creating order", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -109,3 +194,61 @@ func CreateCheckoutSession(w http.ResponseWriter, r *http.Request, ctx context.C
 	// Redirect to the checkout session url
 	http.Redirect(w, r, result.URL, http.StatusSeeOther)
 }
+
+// CheckoutConfirmationHandler looks up the Stripe session a customer returned
+// from and the order created for it
+func (app *App) CheckoutConfirmationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		apperr.Write(w, r, apperr.Validation("A session_id is required"))
+		return
+	}
+
+	result, err := app.Payments.GetCheckout(ctx, sessionID)
+	if errors.Is(err, payments.ErrRejected) {
+		apperr.Write(w, r, apperr.NotFound("Checkout session not found"))
+		return
+	}
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error getting checkout session", "err", err)
+		apperr.Write(w, r, apperr.Internal("Error getting checkout session"))
+		return
+	}
+
+	// The customer is back from Stripe, so record the payment if it went through
+	if result.Paid {
+		if err := methods.MarkOrderPaid(ctx, app.Store, result.ID); err != nil {
+			app.Logger.ErrorContext(ctx, "Error marking order paid", "err", err)
+		}
+	}
+
+	order, err := methods.GetOrderByCheckoutSession(ctx, app.Store, result.ID)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error getting order", "err", err)
+		apperr.Write(w, r, apperr.NotFound("Order not found"))
+		return
+	}
+
+	confirmation := methods.CheckoutConfirmation{
+		SessionID:     result.ID,
+		Status:        result.Status,
+		PaymentStatus: result.PaymentStatus,
+		CustomerEmail: result.CustomerEmail,
+		AmountTotal:   float64(result.AmountTotal) / 100,
+		Currency:      result.Currency,
+		Order:         order,
+	}
+
+	j, err := json.Marshal(confirmation)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error marshalling confirmation", "err", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
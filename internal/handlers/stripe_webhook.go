@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/webhook"
+)
+
+// maxWebhookBody is the largest Stripe event accepted, well above what a
+// checkout session event carries
+const maxWebhookBody = 64 << 10
+
+// StripeWebhookHandler receives Stripe events signed with
+// STRIPE_WEBHOOK_SECRET and marks orders paid when their checkout session
+// completes, whether or not the customer comes back to the confirmation page.
+// Errors answer 500 so Stripe retries the event.
+func (app *App) StripeWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	if app.Config.StripeWebhookSecret == "" {
+		app.Logger.ErrorContext(ctx, "Stripe webhook received but STRIPE_WEBHOOK_SECRET is not set")
+		apperr.Write(w, r, apperr.Internal("Stripe webhooks are not configured"))
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		apperr.Write(w, r, apperr.Validation("Could not read request body"))
+		return
+	}
+
+	// The session fields read here are the same in every API version, so
+	// events from an endpoint pinned to another version are accepted
+	event, err := webhook.ConstructEventWithOptions(payload, r.Header.Get("Stripe-Signature"), app.Config.StripeWebhookSecret,
+		webhook.ConstructEventOptions{IgnoreAPIVersionMismatch: true})
+	if err != nil {
+		app.Logger.WarnContext(ctx, "Invalid Stripe webhook signature", "err", err)
+		apperr.Write(w, r, apperr.Validation("Invalid signature"))
+		return
+	}
+
+	switch event.Type {
+	case stripe.EventTypeCheckoutSessionCompleted, stripe.EventTypeCheckoutSessionAsyncPaymentSucceeded:
+		var cs stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &cs); err != nil {
+			app.Logger.ErrorContext(ctx, "Error decoding checkout session event", "event", event.ID, "err", err)
+			apperr.Write(w, r, apperr.Validation("Invalid checkout session"))
+			return
+		}
+		// Delayed payment methods complete unpaid and send
+		// async_payment_succeeded later
+		if cs.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+			break
+		}
+
+		err := methods.MarkOrderPaid(ctx, app.Store, cs.ID)
+		if errors.Is(err, methods.ErrOrderNotFound) {
+			// Not a checkout this API started
+			app.Logger.WarnContext(ctx, "No order for paid checkout session", "event", event.ID, "session", cs.ID)
+			break
+		}
+		if err != nil {
+			app.Logger.ErrorContext(ctx, "Error marking order paid", "event", event.ID, "session", cs.ID, "err", err)
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Event received"))
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tax"
+)
+
+// addressFromRequest reads a destination address from the query string or form
+func addressFromRequest(r *http.Request) tax.Address {
+	return tax.Address{
+		Country:    r.FormValue("country"),
+		State:      r.FormValue("state"),
+		PostalCode: r.FormValue("postalCode"),
+	}
+}
+
+func (app *App) GetCartSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := app.Auth.CartID(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	p, err := uuid.Parse(id)
+	if err != nil {
+		apperr.Write(w, r, auth.ErrPermissionDenied)
+		return
+	}
+
+	summary, err := methods.GetCartSummary(ctx, app.Store, app.Config.Tax, p, addressFromRequest(r))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if methodID := r.FormValue("shippingMethodID"); methodID != "" {
+		mID, err := strconv.Atoi(methodID)
+		if err != nil {
+			apperr.Write(w, r, apperr.Validation("Invalid shipping method ID"))
+			return
+		}
+		summary, err = methods.ApplyShipping(ctx, app.Store, summary, mID)
+		if err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+
+	j, err := json.Marshal(summary)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) ListTaxRatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	rates, err := methods.GetTaxRates(ctx, app.Store)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(rates)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+type TaxRateRequest struct {
+	Name         string   `json:"name" validate:"required,max=255"`
+	Country      string   `json:"country" validate:"required,len=2"`
+	State        string   `json:"state" validate:"max=50"`
+	PostalPrefix string   `json:"postalPrefix" validate:"max=20"`
+	TaxCategory  string   `json:"taxCategory" validate:"max=50"`
+	Rate         *float64 `json:"rate" validate:"required,min=0,max=100"`
+}
+
+func (app *App) CreateTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body TaxRateRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	t, err := methods.CreateTaxRate(ctx, app.Store, methods.TaxRate{
+		Name:         body.Name,
+		Country:      body.Country,
+		State:        body.State,
+		PostalPrefix: body.PostalPrefix,
+		TaxCategory:  body.TaxCategory,
+		Rate:         *body.Rate,
+	})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(t)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) DeleteTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid tax rate ID"))
+		return
+	}
+
+	if err := methods.DeleteTaxRate(ctx, app.Store, id); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Tax rate deleted"))
+}
@@ -1,28 +1,31 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
-	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
 )
 
-func CreateUserHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
-	
-	email := r.FormValue("email")
-	passwordHash := r.FormValue("password")
-	user, err := methods.CreateUser(ctx, conn, email, passwordHash)
+
+	var body UserRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	user, err := methods.CreateUser(ctx, app.Store, body.Email, body.Password)
 	if err != nil {
-		log.Println("CREATE USER ERROR: ", err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Create user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -30,53 +33,54 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request, ctx context.Conte
 	u.ID = user.ID
 	u.Email = user.Email
 	u.PasswordHash = user.PasswordHash
-	_, err = auth.CreateAdminJWT(w, r, u)
+	_, err = app.Auth.CreateAdminJWT(w, r, u)
 	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "An unknown error occurred", http.StatusInternalServerError)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	json, err := json.Marshal(user)
 	if err != nil {
-		log.Println("MARSHAL USER ERROR: ", err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Marshal user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(json)
 }
 
-func GetUserHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	id := chi.URLParam(r, "id")
 	strId, _ := strconv.Atoi(id)
 
-	user, err := methods.GetUser(ctx, conn, int32(strId))
+	user, err := methods.GetUser(ctx, app.Store, int32(strId))
 	if err != nil {
-		log.Println("GET USER ERROR: ", err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Get user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
 	json, err := json.Marshal(user)
 	if err != nil {
-		log.Println("MARSHAL USER ERROR: ", err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.Logger.ErrorContext(ctx, "Marshal user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(json)
 }
 
-func DeleteUserHandler(w http.ResponseWriter, r *http.Request, ctx context.Context, conn *pgx.Conn) {
+func (app *App) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "text/plain")
 	id := chi.URLParam(r, "id")
 	strId, _ := strconv.Atoi(id)
 
-	if err := methods.DeleteUser(ctx, conn, int32(strId)); err != nil {
-		log.Println("DELETE USER ERROR: ", err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := methods.DeleteUser(ctx, app.Store, int32(strId)); err != nil {
+		app.Logger.ErrorContext(ctx, "Delete user error", "err", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/request"
+)
+
+// WebhookRequest creates an endpoint. Forms send event types as repeated
+// eventType fields.
+type WebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"eventTypes" form:"eventType" validate:"required"`
+	Secret      string   `json:"secret" validate:"min=16,max=255"`
+}
+
+// WebhookUpdateRequest changes the fields that are sent
+type WebhookUpdateRequest struct {
+	URL         string   `json:"url" validate:"url"`
+	Description *string  `json:"description"`
+	EventTypes  []string `json:"eventTypes" form:"eventType"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+func (app *App) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	list, err := methods.GetWebhooks(ctx, app.Store)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(list)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// CreateWebhookHandler subscribes a URL to repeated eventType fields, or "*"
+// for every event. The signing secret is generated unless one is sent and is
+// only returned in this response.
+func (app *App) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var body WebhookRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	webhook, err := methods.CreateWebhook(ctx, app.Store, methods.WebhookInput{
+		URL:         body.URL,
+		Description: body.Description,
+		EventTypes:  body.EventTypes,
+		Secret:      body.Secret,
+	})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(webhook)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(j)
+}
+
+func (app *App) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid webhook ID"))
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			apperr.Write(w, r, apperr.Validation("Limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	detail, err := methods.GetWebhook(ctx, app.Store, int32(id), limit)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(detail)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// UpdateWebhookHandler changes only the fields that are sent. Sending
+// enabled=true turns a disabled endpoint back on.
+func (app *App) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid webhook ID"))
+		return
+	}
+
+	var body WebhookUpdateRequest
+	if err := request.Decode(r, &body); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	webhook, err := methods.UpdateWebhook(ctx, app.Store, int32(id), methods.WebhookUpdate{
+		URL:         body.URL,
+		Description: body.Description,
+		EventTypes:  body.EventTypes,
+		Enabled:     body.Enabled,
+	})
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(webhook)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "text/plain")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("id", "Invalid webhook ID"))
+		return
+	}
+
+	if err := methods.DeleteWebhook(ctx, app.Store, int32(id)); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Webhook deleted"))
+}
+
+func (app *App) GetWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("deliveryID", "Invalid delivery ID"))
+		return
+	}
+
+	detail, err := methods.GetWebhookDelivery(ctx, app.Store, int32(id))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(detail)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+func (app *App) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("deliveryID", "Invalid delivery ID"))
+		return
+	}
+
+	detail, err := methods.RedeliverWebhook(ctx, app.Store, int32(id))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	j, err := json.Marshal(detail)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(j)
+}
@@ -0,0 +1,145 @@
+// Package health decides whether the API is ready for traffic: the database
+// answers, it has every table and column in schema.sql, and the payment
+// provider is configured.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Report is the result of a readiness check, with "ok" or the problem for
+// each check
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type Checker struct {
+	Store    db.Store
+	Payments payments.Provider
+	Schema   string // the schema.sql this build expects
+
+	draining atomic.Bool
+	once     sync.Once
+	columns  map[string][]string
+}
+
+func NewChecker(store db.Store, provider payments.Provider, schema string) *Checker {
+	return &Checker{Store: store, Payments: provider, Schema: schema}
+}
+
+// Drain makes every later readiness check fail so load balancers stop
+// sending traffic before the server shuts down
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready runs every check
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: map[string]string{}}
+	record := func(name string, err error) {
+		if err != nil {
+			report.Status = StatusUnavailable
+			report.Checks[name] = err.Error()
+			return
+		}
+		report.Checks[name] = StatusOK
+	}
+
+	if c.draining.Load() {
+		record("server", errors.New("shutting down"))
+	}
+	record("database", c.ping(ctx))
+	record("schema", c.checkSchema(ctx))
+	record("payments", c.checkPayments())
+	return report
+}
+
+func (c *Checker) ping(ctx context.Context) error {
+	if c.Store == nil {
+		return errors.New("no database")
+	}
+	if p, ok := c.Store.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	_, err := c.Store.Exec(ctx, "SELECT 1")
+	return err
+}
+
+// checkSchema reports tables and columns from schema.sql the database is
+// missing, i.e. a schema change that hasn't been applied yet
+func (c *Checker) checkSchema(ctx context.Context) error {
+	if c.Store == nil {
+		return errors.New("no database")
+	}
+	c.once.Do(func() { c.columns = ParseSchema(c.Schema) })
+
+	rows, err := db.New(c.Store).ListSchemaColumns(ctx)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		have[row.TableName+"."+row.ColumnName] = true
+	}
+
+	var missing []string
+	for table, columns := range c.columns {
+		for _, column := range columns {
+			if !have[table+"."+column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (c *Checker) checkPayments() error {
+	if c.Payments == nil {
+		return errors.New("no payment provider")
+	}
+	if p, ok := c.Payments.(interface{ Ready() error }); ok {
+		return p.Ready()
+	}
+	return nil
+}
+
+var (
+	createTable = regexp.MustCompile(`(?is)CREATE TABLE\s+(?:IF NOT EXISTS\s+)?(\w+)\s*\((.*?)\n\);`)
+	constraint  = regexp.MustCompile(`(?i)^(PRIMARY|UNIQUE|FOREIGN|CONSTRAINT|CHECK|EXCLUDE)\b`)
+)
+
+// ParseSchema returns the columns of every CREATE TABLE in schema
+func ParseSchema(schema string) map[string][]string {
+	tables := map[string][]string{}
+	for _, m := range createTable.FindAllStringSubmatch(schema, -1) {
+		table := strings.ToLower(m[1])
+		for _, line := range strings.Split(m[2], "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "--") || constraint.MatchString(line) {
+				continue
+			}
+			tables[table] = append(tables[table], strings.ToLower(strings.Fields(line)[0]))
+		}
+	}
+	return tables
+}
@@ -0,0 +1,222 @@
+// Package idempotency lets clients safely retry mutating requests. The first
+// response for an Idempotency-Key header is stored per scope (cart or user)
+// and replayed for retries with the same body until the key expires.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+	DefaultTTL     = 24 * time.Hour
+	MaxKeyLength   = 200
+)
+
+// ScopeFunc returns what a key is scoped to, e.g. "cart:<id>"
+type ScopeFunc func(r *http.Request) (string, error)
+
+var (
+	ErrKeyReused = &apperr.Error{
+		Code:    apperr.CodeConflict,
+		Message: "Idempotency-Key was already used with a different request",
+		Status:  http.StatusUnprocessableEntity,
+	}
+	ErrInProgress = apperr.Conflict("A request with this Idempotency-Key is still in progress")
+)
+
+// Middleware honors the Idempotency-Key header on POST, PUT, PATCH and DELETE
+// requests. Requests without the header pass straight through.
+func Middleware(conn db.Store, ttl time.Duration, scope ScopeFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || !mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				apperr.Write(w, r, apperr.Invalid(Header, "Idempotency-Key is too long"))
+				return
+			}
+
+			s, err := scope(r)
+			if err != nil {
+				slog.Error("Idempotency scope error", "err", err)
+				apperr.Write(w, r, err)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				apperr.Write(w, r, apperr.Validation("Could not read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(r, body)
+
+			q := db.New(conn)
+			ctx := r.Context()
+
+			record, created, err := claim(r, q, key, s, hash, ttl)
+			if err != nil {
+				slog.ErrorContext(ctx, "Idempotency claim error", "err", err)
+				apperr.Write(w, r, err)
+				return
+			}
+
+			if !created {
+				switch {
+				case record.RequestHash != hash:
+					apperr.Write(w, r, ErrKeyReused)
+				case !record.StatusCode.Valid:
+					w.Header().Set("Retry-After", "1")
+					apperr.Write(w, r, ErrInProgress)
+				default:
+					replay(w, record)
+				}
+				return
+			}
+
+			// The record is settled even when the client hung up, otherwise
+			// every retry would get ErrInProgress until the key expires
+			settle := context.WithoutCancel(ctx)
+			saved := false
+			defer func() {
+				// server errors, panics and failed saves are not stored so
+				// the client can retry them
+				if saved {
+					return
+				}
+				if err := q.DeleteIdempotencyKey(settle, record.ID); err != nil {
+					slog.ErrorContext(ctx, "Idempotency delete error", "err", err)
+				}
+			}()
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+
+			headers, _ := json.Marshal(storedHeaders(w.Header()))
+			if err := q.SaveIdempotencyResponse(settle, db.SaveIdempotencyResponseParams{
+				ID:              record.ID,
+				StatusCode:      pgtype.Int4{Int32: int32(rec.status), Valid: true},
+				ResponseHeaders: headers,
+				ResponseBody:    rec.body.Bytes(),
+			}); err != nil {
+				slog.ErrorContext(ctx, "Idempotency save error", "err", err)
+				return
+			}
+			saved = true
+		})
+	}
+}
+
+// claim inserts a pending record for the key, or returns the existing one.
+// An expired record is replaced.
+func claim(r *http.Request, q *db.Queries, key, scope, hash string, ttl time.Duration) (db.IdempotencyKey, bool, error) {
+	ctx := r.Context()
+	for attempt := 0; attempt < 2; attempt++ {
+		record, err := q.CreateIdempotencyKey(ctx, db.CreateIdempotencyKeyParams{
+			Key:         key,
+			Scope:       scope,
+			RequestHash: hash,
+			ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+		})
+		if err == nil {
+			return record, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return db.IdempotencyKey{}, false, err
+		}
+
+		existing, err := q.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{Key: key, Scope: scope})
+		if err != nil {
+			return db.IdempotencyKey{}, false, err
+		}
+		if existing.ExpiresAt.Time.After(time.Now()) {
+			return existing, false, nil
+		}
+		if err := q.DeleteIdempotencyKey(ctx, existing.ID); err != nil {
+			return db.IdempotencyKey{}, false, err
+		}
+	}
+	return db.IdempotencyKey{}, false, errors.New("could not claim idempotency key")
+}
+
+func replay(w http.ResponseWriter, record db.IdempotencyKey) {
+	var headers http.Header
+	if err := json.Unmarshal(record.ResponseHeaders, &headers); err == nil {
+		for k, v := range storedHeaders(headers) {
+			w.Header()[k] = v
+		}
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(int(record.StatusCode.Int32))
+	w.Write(record.ResponseBody)
+}
+
+// storedHeaders leaves out cookies, which carry session tokens that must
+// never be kept in the database or handed to another request
+func storedHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	h.Del("Set-Cookie")
+	return h
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// recorder captures the status and body written by the handler
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
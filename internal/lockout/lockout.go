@@ -0,0 +1,153 @@
+// Package lockout slows down password guessing on the admin login. Failed
+// attempts are counted per account and per client IP in Postgres. After a
+// few free attempts each further failure on an account makes it wait twice
+// as long, and too many failures on an account or an IP lock it out for a
+// while.
+package lockout
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+const (
+	DefaultFreeAttempts  = 3
+	DefaultDelay         = time.Second
+	DefaultMaxAttempts   = 10
+	DefaultIPMaxAttempts = 50
+	DefaultLockout       = 15 * time.Minute
+	DefaultWindow        = time.Hour
+
+	// PruneInterval is how often forgotten failures are deleted
+	PruneInterval = time.Hour
+)
+
+var ErrLocked = apperr.RateLimited("Too many failed logins, try again later")
+
+type Config struct {
+	FreeAttempts  int           // failures on an account before it has to wait
+	Delay         time.Duration // first wait, doubled on each further failure
+	MaxAttempts   int           // failures on an account before it's locked
+	IPMaxAttempts int           // failures from a client IP before it's locked
+	Lockout       time.Duration // how long a lock lasts, and the longest wait
+	Window        time.Duration // failures this far apart start the count over
+}
+
+// accountWait is how long an account with n recent failures waits
+func (c Config) accountWait(n int) time.Duration {
+	if n >= c.MaxAttempts {
+		return c.Lockout
+	}
+	if n <= c.FreeAttempts {
+		return 0
+	}
+	d := c.Delay
+	for i := c.FreeAttempts + 1; i < n && d < c.Lockout; i++ {
+		d *= 2
+	}
+	return min(d, c.Lockout)
+}
+
+// Failure is what a failed attempt did to one key
+type Failure struct {
+	Failures int           // recent failures, this one included
+	Wait     time.Duration // before the next attempt is accepted
+	Locked   bool          // the key reached its limit and is locked out
+}
+
+// Guard counts failures in the login_failures table
+type Guard struct {
+	Config Config
+	DB     db.Store
+}
+
+func NewGuard(config Config, conn db.Store) *Guard {
+	return &Guard{Config: config, DB: conn}
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns how long the account or the client IP still has to wait.
+// Zero means the attempt may go ahead.
+func (g *Guard) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	seconds, err := db.New(g.DB).GetLoginLockout(ctx, []string{accountKey(email), ipKey(ip)})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Fail records a failed attempt against the account and the client IP and
+// starts their wait or lockout
+func (g *Guard) Fail(ctx context.Context, email, ip string) (account, addr Failure, err error) {
+	q := db.New(g.DB)
+	record := func(key string, wait func(n int) time.Duration, limit int) (Failure, error) {
+		n, err := q.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Key:           key,
+			WindowSeconds: g.Config.Window.Seconds(),
+		})
+		if err != nil {
+			return Failure{}, err
+		}
+		f := Failure{Failures: int(n), Wait: wait(int(n)), Locked: int(n) >= limit}
+		if f.Wait > 0 {
+			err = q.LockLogin(ctx, db.LockLoginParams{Seconds: f.Wait.Seconds(), Key: key})
+		}
+		return f, err
+	}
+
+	account, err = record(accountKey(email), g.Config.accountWait, g.Config.MaxAttempts)
+	if err != nil {
+		return account, addr, err
+	}
+	addr, err = record(ipKey(ip), func(n int) time.Duration {
+		if n >= g.Config.IPMaxAttempts {
+			return g.Config.Lockout
+		}
+		return 0
+	}, g.Config.IPMaxAttempts)
+	return account, addr, err
+}
+
+// Succeed forgets the account's failures. The client IP's stay counted so
+// one known password doesn't buy more guesses at other accounts.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return db.New(g.DB).ClearLoginFailures(ctx, accountKey(email))
+}
+
+// Run deletes failures older than the window every PruneInterval until ctx
+// is cancelled
+func (g *Guard) Run(ctx context.Context) {
+	ticker := time.NewTicker(PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		before := pgtype.Timestamptz{Time: time.Now().Add(-g.Config.Window), Valid: true}
+		n, err := db.New(g.DB).DeleteStaleLoginFailures(ctx, before)
+		if err != nil {
+			slog.ErrorContext(ctx, "Login failure prune error", "err", err)
+			continue
+		}
+		if n > 0 {
+			slog.DebugContext(ctx, "Pruned old login failures", "keys", n)
+		}
+	}
+}
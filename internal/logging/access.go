@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// AccessLog logs one line per request with its status, size and latency.
+// Server errors log at error level and client errors at warn. Only the path
+// is logged since query strings can carry tokens and emails.
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				switch {
+				case status >= 500:
+					level = slog.LevelError
+				case status >= 400:
+					level = slog.LevelWarn
+				}
+				logger.LogAttrs(r.Context(), level, "Request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+					slog.String("remote_ip", r.RemoteAddr),
+					slog.String("user_agent", r.UserAgent()),
+				)
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Package logging sets up the API's structured logger. Every record logged
+// with a request's context carries the chi request ID, and secrets and email
+// addresses are redacted before anything is written.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Attributes added from a record's context
+const (
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+type Config struct {
+	Level  slog.Level
+	Format string // FormatText or FormatJSON
+}
+
+// New builds a logger writing to w
+func New(w io.Writer, c Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: c.Level, ReplaceAttr: redact}
+	var h slog.Handler
+	if c.Format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+// contextHandler adds the request ID and the trace from the record's
+// context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	var attrs []slog.Attr
+	if id := middleware.GetReqID(ctx); id != "" {
+		attrs = append(attrs, slog.String(RequestIDKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String(TraceIDKey, sc.TraceID().String()), slog.String(SpanIDKey, sc.SpanID().String()))
+	}
+	if len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
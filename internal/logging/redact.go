@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute names whose values are never logged
+var secretKeys = []string{"password", "secret", "token", "authorization", "cookie", "jwt", "api_key", "signature"}
+
+var email = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// MaskEmails keeps the first letter and the domain of every email address in
+// s, e.g. j***@example.com
+func MaskEmails(s string) string {
+	return email.ReplaceAllString(s, "$1***@$2")
+}
+
+// redact is the slog ReplaceAttr hook. Secret attributes are dropped to a
+// placeholder and emails are masked wherever they appear, including the
+// message and error strings.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); strings.Contains(s, "@") {
+			return slog.String(a.Key, MaskEmails(s))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, MaskEmails(err.Error()))
+		}
+	}
+	return a
+}
@@ -2,56 +2,63 @@ package methods
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
 )
 
-func NewCart(ctx context.Context, conn *pgx.Conn) (db.Cart, error) {
+var ErrCartNotFound = apperr.NotFound("Cart not found")
+
+func NewCart(ctx context.Context, conn db.Store) (db.Cart, error) {
 	q := db.New(conn)
 
 	cart, err := q.CreateCart(ctx, pgtype.UUID{Bytes: uuid.New(), Valid: true})
 
 	if err != nil {
-		log.Println("NEW CART ERROR: ", err.Error())
+		slog.ErrorContext(ctx, "New cart error", "err", err)
 		return db.Cart{}, err
 	}
+	metrics.CartsCreated.Inc()
 
 	return cart, nil
 }
 
-func GetCart(ctx context.Context, conn *pgx.Conn, id uuid.UUID) (db.Cart, error) {
+func GetCart(ctx context.Context, conn db.Store, id uuid.UUID) (db.Cart, error) {
 	q := db.New(conn)
 
 	parsedID := pgtype.UUID{Bytes: id, Valid: true}
 
 	cart, err := q.GetCart(ctx, parsedID)
-	if err != nil || cart.ID != parsedID {
-		log.Println("GET CART ERROR: ", err.Error())
-		return db.Cart{}, fmt.Errorf("Error getting cart")
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && cart.ID != parsedID) {
+		return db.Cart{}, ErrCartNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get cart error", "err", err)
+		return db.Cart{}, apperr.Internal("Error getting cart")
 	}
 	return cart, nil
 }
 
-func GetItems(ctx context.Context, conn *pgx.Conn, id uuid.UUID) ([]db.GetCartItemsRow, error) {
+func GetItems(ctx context.Context, conn db.Store, id uuid.UUID) ([]db.GetCartItemsRow, error) {
 	q := db.New(conn)
 
 	cart, err := GetCart(ctx, conn, id)
 	if err != nil {
-		log.Println("GET CART ERROR: ", err.Error())
-		return nil, fmt.Errorf("Error getting cart")
+		return nil, err
 	}
-	fmt.Println("CART ID: ", cart.ID)
 	items, err := q.GetCartItems(ctx, pgtype.UUID{Bytes: id, Valid: true})
-	fmt.Println("ITEMS: ", items)
 	if err != nil {
-		log.Println("GET CART ITEMS ERROR: ", err.Error())
-		return []db.GetCartItemsRow{}, fmt.Errorf("Error fetching items")
+		slog.ErrorContext(ctx, "Get cart items error", "err", err)
+		return []db.GetCartItemsRow{}, apperr.Internal("Error fetching items")
 	}
+	slog.DebugContext(ctx, "Fetched cart items", "cart_id", cart.ID, "items", len(items))
 
 	if len(items) == 0 {
 		// return an empty array instead of null for better front end handling
@@ -60,71 +67,74 @@ func GetItems(ctx context.Context, conn *pgx.Conn, id uuid.UUID) ([]db.GetCartIt
 	return items, nil
 }
 
-func ClearAll(ctx context.Context, conn *pgx.Conn, id uuid.UUID) error {
+func ClearAll(ctx context.Context, conn db.Store, id uuid.UUID) error {
 	q := db.New(conn)
 
 	_, err := GetCart(ctx, conn, id)
 	if err != nil {
-		log.Println("GET CART ERROR: ", err.Error())
-		return fmt.Errorf("Error getting cart")
+		return err
 	}
 
 	if err := q.ClearCart(ctx, pgtype.UUID{Bytes: id, Valid: true}); err != nil {
-		log.Println("CLEAR CART ERROR: ", err.Error())
-		return fmt.Errorf("Error clearing items in cart ")
+		slog.ErrorContext(ctx, "Clear cart error", "err", err)
+		return apperr.Internal("Error clearing items in cart ")
 	}
 
+	touchCart(ctx, q, id)
+
 	return nil
 }
 
-func RemoveItem(ctx context.Context, conn *pgx.Conn, id uuid.UUID, prodID int) error {
+func RemoveItem(ctx context.Context, conn db.Store, id uuid.UUID, prodID int) error {
 	q := db.New(conn)
 
 	_, err := GetCart(ctx, conn, id)
 	if err != nil {
-		log.Println("GET CART ERROR: ", err.Error())
-		return fmt.Errorf("Error getting cart")
+		return err
 	}
 
 	if err := q.RemoveCartItem(ctx, db.RemoveCartItemParams{
 		CartID:    pgtype.UUID{Bytes: id, Valid: true},
 		ProductID: int32(prodID),
 	}); err != nil {
-		log.Println("REMOVE CART ITEM ERROR: ", err.Error())
-		return fmt.Errorf("Error removing the item in cart")
+		slog.ErrorContext(ctx, "Remove cart item error", "err", err)
+		return apperr.Internal("Error removing the item in cart")
 	}
 
+	touchCart(ctx, q, id)
+
 	return nil
 }
 
-func AddItem(ctx context.Context, conn *pgx.Conn, id uuid.UUID, prodID int, quan int) error {
+func AddItem(ctx context.Context, conn db.Store, id uuid.UUID, prodID int, quan int) error {
 	q := db.New(conn)
 
 	_, err := GetCart(ctx, conn, id)
 	if err != nil {
-		log.Println("GET CART ERROR: ", err.Error())
-		return fmt.Errorf("Error getting cart")
+		return err
 	}
-	
+
 	if err := q.AddCartItem(ctx, db.AddCartItemParams{
 		CartID:    pgtype.UUID{Bytes: id, Valid: true},
 		ProductID: int32(prodID),
 		Quantity:  int32(quan),
 	}); err != nil {
-		log.Println("ADD CART ITEM ERROR: ", err.Error())
-		return fmt.Errorf("Error adding the item in cart")
+		slog.ErrorContext(ctx, "Add cart item error", "err", err)
+		return apperr.Internal("Error adding the item in cart")
 	}
+	metrics.CartItemsAdded.Add(float64(quan))
+
+	touchCart(ctx, q, id)
 
 	return nil
 }
 
-func UpdateItemQuantity(ctx context.Context, conn *pgx.Conn, id uuid.UUID, prodID int, quan int) error {
+func UpdateItemQuantity(ctx context.Context, conn db.Store, id uuid.UUID, prodID int, quan int) error {
 	q := db.New(conn)
 
 	_, err := GetCart(ctx, conn, id)
 	if err != nil {
-		log.Println("GET CART ERROR: ", err.Error())
-		return fmt.Errorf("Error getting cart")
+		return err
 	}
 
 	if err := q.UpdateCartItemQuantity(ctx, db.UpdateCartItemQuantityParams{
@@ -132,8 +142,92 @@ func UpdateItemQuantity(ctx context.Context, conn *pgx.Conn, id uuid.UUID, prodI
 		ProductID: int32(prodID),
 		Quantity:  int32(quan),
 	}); err != nil {
-		log.Println("UPDATE ITEM CART ERROR :", err.Error())
-		return fmt.Errorf("Error changing the item quantity")
+		slog.ErrorContext(ctx, "Update item cart error", "err", err)
+		return apperr.Internal("Error changing the item quantity")
 	}
+
+	touchCart(ctx, q, id)
 	return nil
 }
+
+// SetCartEmail stores the email given at checkout for cart recovery
+func SetCartEmail(ctx context.Context, conn db.Store, id uuid.UUID, email string) error {
+	q := db.New(conn)
+
+	if err := q.SetCartEmail(ctx, db.SetCartEmailParams{
+		ID:    pgtype.UUID{Bytes: id, Valid: true},
+		Email: pgtype.Text{String: email, Valid: email != ""},
+	}); err != nil {
+		slog.ErrorContext(ctx, "Set cart email error", "err", err)
+		return apperr.Internal("Error updating cart")
+	}
+	return nil
+}
+
+// touchCart records cart activity, which keeps the cart out of abandoned
+// cart recovery
+func touchCart(ctx context.Context, q *db.Queries, id uuid.UUID) {
+	if err := q.UpdateCartTimestamp(ctx, pgtype.UUID{Bytes: id, Valid: true}); err != nil {
+		slog.ErrorContext(ctx, "Update cart timestamp error", "err", err)
+	}
+}
+
+// PurgeExpiredCarts deletes up to limit carts untouched since before, along
+// with their items, and returns how many rows of each were removed. Carts
+// with a recovery link sent after linksAfter are kept so the link still
+// works. Orders keep their snapshot of the cart and only lose the reference
+// to it.
+func PurgeExpiredCarts(ctx context.Context, conn db.Store, before, linksAfter time.Time, limit int) (int64, int64, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin purge carts error", "err", err)
+		return 0, 0, apperr.Internal("Error purging carts")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	ids, err := q.ListExpiredCarts(ctx, db.ListExpiredCartsParams{
+		UpdatedAt: pgtype.Timestamptz{Time: before, Valid: true},
+		Limit:     int32(limit),
+		SentAt:    pgtype.Timestamptz{Time: linksAfter, Valid: true},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "List expired carts error", "err", err)
+		return 0, 0, apperr.Internal("Error purging carts")
+	}
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+
+	items, err := q.DeleteCartItemsByCarts(ctx, ids)
+	if err != nil {
+		slog.ErrorContext(ctx, "Delete cart items error", "err", err)
+		return 0, 0, apperr.Internal("Error purging carts")
+	}
+
+	carts, err := q.DeleteCarts(ctx, ids)
+	if err != nil {
+		slog.ErrorContext(ctx, "Delete carts error", "err", err)
+		return 0, 0, apperr.Internal("Error purging carts")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit purge carts error", "err", err)
+		return 0, 0, apperr.Internal("Error purging carts")
+	}
+
+	return carts, items, nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes stored responses past their TTL
+func PurgeExpiredIdempotencyKeys(ctx context.Context, conn db.Store) (int64, error) {
+	q := db.New(conn)
+
+	n, err := q.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Delete expired idempotency keys error", "err", err)
+		return 0, apperr.Internal("Error purging idempotency keys")
+	}
+	return n, nil
+}
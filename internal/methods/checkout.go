@@ -0,0 +1,145 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Stripe replaces this placeholder in the success URL with the session ID
+const CheckoutSessionPlaceholder = "{CHECKOUT_SESSION_ID}"
+
+type CheckoutConfig struct {
+	SuccessURL       string
+	CancelURL        string
+	AllowedCountries []string // upper case, empty to take them from the shipping zones
+	// ReturnURLAllowlist holds the origins a request may send customers back to
+	ReturnURLAllowlist []string
+}
+
+type CheckoutConfirmation struct {
+	SessionID     string      `json:"sessionId"`
+	Status        string      `json:"status"`
+	PaymentStatus string      `json:"paymentStatus"`
+	CustomerEmail string      `json:"customerEmail"`
+	AmountTotal   float64     `json:"amountTotal"`
+	Currency      string      `json:"currency"`
+	Order         OrderDetail `json:"order"`
+}
+
+// Validate checks the default success and cancel URLs every checkout needs
+func (c CheckoutConfig) Validate() error {
+	if c.SuccessURL == "" || c.CancelURL == "" {
+		return apperr.Internal("CHECKOUT_SUCCESS_URL and CHECKOUT_CANCEL_URL must be set")
+	}
+	for _, u := range []string{c.SuccessURL, c.CancelURL} {
+		if _, err := urlOrigin(u); err != nil {
+			return apperr.Internal(fmt.Sprintf("Invalid checkout URL %q", u))
+		}
+	}
+	return nil
+}
+
+// ReturnURLs picks the success and cancel URLs for a checkout session. URLs
+// sent with the request override the configured ones when their origin is on
+// the allowlist. The success URL always carries the session ID placeholder so
+// the confirmation page can look the order up.
+func (c CheckoutConfig) ReturnURLs(success, cancel string) (string, string, error) {
+	if err := c.Validate(); err != nil {
+		return "", "", err
+	}
+	if success == "" {
+		success = c.SuccessURL
+	}
+	if cancel == "" {
+		cancel = c.CancelURL
+	}
+
+	for _, u := range []string{success, cancel} {
+		if !c.allowed(u) {
+			return "", "", apperr.Validation("Return URL is not allowed")
+		}
+	}
+
+	if !strings.Contains(success, CheckoutSessionPlaceholder) {
+		sep := "?"
+		if strings.Contains(success, "?") {
+			sep = "&"
+		}
+		success += sep + "session_id=" + CheckoutSessionPlaceholder
+	}
+
+	return success, cancel, nil
+}
+
+// Countries returns the shipping countries checkout accepts, falling back to
+// the countries covered by shipping zones
+func (c CheckoutConfig) Countries(ctx context.Context, conn db.Store) ([]string, error) {
+	if len(c.AllowedCountries) > 0 {
+		return c.AllowedCountries, nil
+	}
+
+	countries, err := ShippingCountries(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(countries) == 0 {
+		return nil, apperr.Internal("No shipping countries are configured")
+	}
+	return countries, nil
+}
+
+func (c CheckoutConfig) allowed(u string) bool {
+	// the placeholder's braces aren't valid in a URL
+	origin, err := urlOrigin(strings.ReplaceAll(u, CheckoutSessionPlaceholder, "id"))
+	if err != nil {
+		return false
+	}
+	// the default success and cancel URLs are always allowed
+	for _, d := range []string{c.SuccessURL, c.CancelURL} {
+		if o, err := urlOrigin(d); err == nil && o == origin {
+			return true
+		}
+	}
+	for _, a := range c.ReturnURLAllowlist {
+		if strings.EqualFold(strings.TrimRight(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOrderByCheckoutSession finds the order created for a Stripe session
+func GetOrderByCheckoutSession(ctx context.Context, conn db.Store, sessionID string) (OrderDetail, error) {
+	q := db.New(conn)
+
+	order, err := q.GetOrderByCheckoutSession(ctx, pgtype.Text{String: sessionID, Valid: true})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return OrderDetail{}, ErrOrderNotFound
+		}
+		slog.ErrorContext(ctx, "Get order by session error", "err", err)
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	return GetOrder(ctx, conn, order.ID)
+}
+
+func urlOrigin(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("URL must be absolute")
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
@@ -2,14 +2,17 @@ package methods
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
 )
 
+var ErrCollectionNotFound = apperr.NotFound("Collection not found")
 
-func CreateCollection(ctx context.Context, conn *pgx.Conn, c db.Collection) (db.Collection, error) {
+func CreateCollection(ctx context.Context, conn db.Store, c db.Collection) (db.Collection, error) {
 	q := db.New(conn)
 
 	collection, err := q.CreateCollection(ctx, db.CreateCollectionParams{
@@ -17,85 +20,88 @@ func CreateCollection(ctx context.Context, conn *pgx.Conn, c db.Collection) (db.
 		Description: c.Description,
 	})
 	if err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "Query failed", "query", "CreateCollection", "err", err)
 		return db.Collection{}, err
 	}
 
 	return collection, nil
 }
 
-func AddProductToCollection(ctx context.Context, conn *pgx.Conn, collectionID int, productID int) error {
+func AddProductToCollection(ctx context.Context, conn db.Store, collectionID int, productID int) error {
 	q := db.New(conn)
 
 	if err := q.AddProductToCollection(ctx, db.AddProductToCollectionParams{
 		CollectionID: int32(collectionID),
-		ProductID:   int32(productID),
+		ProductID:    int32(productID),
 	}); err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "Query failed", "query", "AddProductToCollection", "err", err)
 		return err
 	}
 
 	return nil
 }
 
-func RemoveProductFromCollection(ctx context.Context, conn *pgx.Conn, collectionID int, productID int) error {
+func RemoveProductFromCollection(ctx context.Context, conn db.Store, collectionID int, productID int) error {
 	q := db.New(conn)
 
 	if err := q.RemoveProductFromCollection(ctx, db.RemoveProductFromCollectionParams{
 		CollectionID: int32(collectionID),
-		ProductID:   int32(productID),
+		ProductID:    int32(productID),
 	}); err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "Query failed", "query", "RemoveProductFromCollection", "err", err)
 		return err
 	}
 
 	return nil
 }
 
-func GetCollections(ctx context.Context, conn *pgx.Conn) ([]db.Collection, error) {
+func GetCollections(ctx context.Context, conn db.Store) ([]db.Collection, error) {
 	q := db.New(conn)
 
 	collections, err := q.ListCollections(ctx)
 	if err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "Query failed", "query", "ListCollections", "err", err)
 		return []db.Collection{}, err
 	}
 
 	return collections, nil
 }
 
-func GetCollection(ctx context.Context, conn *pgx.Conn, id int) (db.Collection, error) {
+func GetCollection(ctx context.Context, conn db.Store, id int) (db.Collection, error) {
 	q := db.New(conn)
 
 	collection, err := q.GetCollection(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Collection{}, ErrCollectionNotFound
+	}
 	if err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "Query failed", "query", "GetCollection", "err", err)
 		return db.Collection{}, err
 	}
 
 	return collection, nil
 }
 
-func UpdateCollection(ctx context.Context, conn *pgx.Conn, c db.Collection) error {
+func UpdateCollection(ctx context.Context, conn db.Store, c db.Collection) error {
 	q := db.New(conn)
 
 	if err := q.UpdateCollection(ctx, db.UpdateCollectionParams{
 		ID:          int32(c.ID),
 		Name:        c.Name,
 		Description: c.Description,
-	});err != nil {
-		log.Println(err.Error())
+	}); err != nil {
+		slog.ErrorContext(ctx, "Query failed", "query", "UpdateCollection", "err", err)
 		return err
 	}
 
 	return nil
 }
 
-func DeleteCollection(ctx context.Context, conn *pgx.Conn, id int) error {
+func DeleteCollection(ctx context.Context, conn db.Store, id int) error {
 	q := db.New(conn)
 
 	if err := q.DeleteCollection(ctx, int32(id)); err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "Query failed", "query", "DeleteCollection", "err", err)
 		return err
 	}
 
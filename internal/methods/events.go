@@ -0,0 +1,48 @@
+package methods
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+)
+
+const DefaultStockLowThreshold = 5
+
+// publish writes a domain event to the outbox. q should be bound to the
+// transaction making the change so the event is only kept if it commits.
+func publish(ctx context.Context, q *db.Queries, p events.Payload) error {
+	if _, err := events.Publish(ctx, q, p); err != nil {
+		slog.ErrorContext(ctx, "Error publishing event", "type", p.EventType(), "err", err)
+		return err
+	}
+	return nil
+}
+
+// publishStockLow raises StockLow when stock is at or below threshold
+func publishStockLow(ctx context.Context, q *db.Queries, threshold, productID int32, size string, stock int32) error {
+	if stock > threshold {
+		return nil
+	}
+	return publish(ctx, q, events.StockLowData{
+		ProductID: productID,
+		Size:      size,
+		Stock:     stock,
+		Threshold: threshold,
+	})
+}
+
+// GetDomainEvents returns the most recent events in the outbox
+func GetDomainEvents(ctx context.Context, conn db.Store, limit int) ([]db.DomainEvent, error) {
+	q := db.New(conn)
+
+	list, err := q.ListDomainEvents(ctx, int32(limit))
+	if err != nil {
+		slog.ErrorContext(ctx, "List domain events error", "err", err)
+		return []db.DomainEvent{}, apperr.Internal("Error fetching events")
+	}
+
+	return append(make([]db.DomainEvent, 0), list...), nil
+}
@@ -0,0 +1,428 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/fulfillment"
+)
+
+var (
+	ErrInvalidFulfillment  = apperr.Validation("Invalid fulfillment")
+	ErrFulfillmentNotFound = apperr.NotFound("Fulfillment not found")
+)
+
+type Fulfillment struct {
+	db.Fulfillment
+	Items []db.FulfillmentItem `json:"items"`
+}
+
+type FulfillmentItemRequest struct {
+	OrderItemID int32 `json:"orderItemId"`
+	Quantity    int32 `json:"quantity"`
+}
+
+// FulfillmentInput creates or updates a fulfillment. Empty fields are left
+// unchanged on update, and an empty Items list fulfills everything left on
+// the order.
+type FulfillmentInput struct {
+	Carrier        string
+	TrackingNumber string
+	TrackingURL    string
+	Status         string
+	Items          []FulfillmentItemRequest
+}
+
+type TrackingItem struct {
+	Name      string `json:"name"`
+	Quantity  int32  `json:"quantity"`
+	Fulfilled int32  `json:"fulfilled"`
+}
+
+type TrackingShipment struct {
+	Carrier        string             `json:"carrier"`
+	TrackingNumber string             `json:"trackingNumber"`
+	TrackingURL    string             `json:"trackingUrl"`
+	Status         string             `json:"status"`
+	ShippedAt      pgtype.Timestamptz `json:"shippedAt"`
+	DeliveredAt    pgtype.Timestamptz `json:"deliveredAt"`
+	Items          []TrackingItem     `json:"items"`
+}
+
+// OrderTracking is the customer facing view of an order, without prices or
+// internal IDs
+type OrderTracking struct {
+	OrderNumber       int32              `json:"orderNumber"`
+	Status            string             `json:"status"`
+	FulfillmentStatus string             `json:"fulfillmentStatus"`
+	CreatedAt         pgtype.Timestamptz `json:"createdAt"`
+	Items             []TrackingItem     `json:"items"`
+	Shipments         []TrackingShipment `json:"shipments"`
+}
+
+func CreateFulfillment(ctx context.Context, conn db.Store, orderID int32, in FulfillmentInput, actor Actor) (Fulfillment, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin fulfillment error", "err", err)
+		return Fulfillment{}, apperr.Internal("Error occurred creating fulfillment")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	order, err := q.GetOrderForUpdate(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Fulfillment{}, ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order error", "err", err)
+		return Fulfillment{}, apperr.Internal("Error fetching order")
+	}
+	if order.Status != OrderPaid && order.Status != OrderProcessing {
+		return Fulfillment{}, ErrInvalidFulfillment.Withf("order is %s", order.Status)
+	}
+
+	ordered, shipments, err := loadShipments(ctx, q, orderID)
+	if err != nil {
+		return Fulfillment{}, apperr.Internal("Error occurred creating fulfillment")
+	}
+	remaining := fulfillment.Remaining(ordered, shipments)
+
+	items := in.Items
+	if len(items) == 0 {
+		for id, qty := range remaining {
+			if qty > 0 {
+				items = append(items, FulfillmentItemRequest{OrderItemID: id, Quantity: qty})
+			}
+		}
+		if len(items) == 0 {
+			return Fulfillment{}, ErrInvalidFulfillment.Withf("every item has already been fulfilled")
+		}
+	}
+	for _, item := range items {
+		left, ok := remaining[item.OrderItemID]
+		if !ok {
+			return Fulfillment{}, ErrInvalidFulfillment.Withf("item %d is not on this order", item.OrderItemID)
+		}
+		if item.Quantity <= 0 || item.Quantity > left {
+			return Fulfillment{}, ErrInvalidFulfillment.Withf("item %d has %d left to fulfill", item.OrderItemID, left)
+		}
+		remaining[item.OrderItemID] -= item.Quantity
+	}
+
+	// A fulfillment with a tracking number has been handed to the carrier
+	status := in.Status
+	if status == "" {
+		status = fulfillment.StatusPending
+		if in.TrackingNumber != "" {
+			status = fulfillment.StatusShipped
+		}
+	}
+	if !fulfillment.ValidStatus(status) {
+		return Fulfillment{}, ErrInvalidFulfillment.Withf("unknown status %q", status)
+	}
+
+	trackingURL := in.TrackingURL
+	if trackingURL == "" {
+		trackingURL = fulfillment.TrackingURL(in.Carrier, in.TrackingNumber)
+	}
+
+	var shippedAt pgtype.Timestamptz
+	if status != fulfillment.StatusPending {
+		shippedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	f, err := q.CreateFulfillment(ctx, db.CreateFulfillmentParams{
+		OrderID:        orderID,
+		Carrier:        pgtype.Text{String: in.Carrier, Valid: in.Carrier != ""},
+		TrackingNumber: pgtype.Text{String: in.TrackingNumber, Valid: in.TrackingNumber != ""},
+		TrackingUrl:    pgtype.Text{String: trackingURL, Valid: trackingURL != ""},
+		Status:         status,
+		ShippedAt:      shippedAt,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create fulfillment error", "err", err)
+		return Fulfillment{}, apperr.Internal("Error occurred creating fulfillment")
+	}
+
+	result := Fulfillment{Fulfillment: f, Items: make([]db.FulfillmentItem, 0, len(items))}
+	for _, item := range items {
+		if err := q.AddFulfillmentItem(ctx, db.AddFulfillmentItemParams{
+			FulfillmentID: f.ID,
+			OrderItemID:   item.OrderItemID,
+			Quantity:      item.Quantity,
+		}); err != nil {
+			slog.ErrorContext(ctx, "Add fulfillment item error", "err", err)
+			return Fulfillment{}, apperr.Internal("Error occurred creating fulfillment")
+		}
+		result.Items = append(result.Items, db.FulfillmentItem{FulfillmentID: f.ID, OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+
+	if err := refreshFulfillmentStatus(ctx, q, &order, actor); err != nil {
+		return Fulfillment{}, apperr.Internal("Error occurred creating fulfillment")
+	}
+	notifyShipment(ctx, q, order, f, result.Items)
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit fulfillment error", "err", err)
+		return Fulfillment{}, apperr.Internal("Error occurred creating fulfillment")
+	}
+
+	return result, nil
+}
+
+// UpdateFulfillment changes tracking details and moves the fulfillment forward
+func UpdateFulfillment(ctx context.Context, conn db.Store, orderID, id int32, in FulfillmentInput, actor Actor) (db.Fulfillment, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin fulfillment error", "err", err)
+		return db.Fulfillment{}, apperr.Internal("Error occurred updating fulfillment")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	order, err := q.GetOrderForUpdate(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Fulfillment{}, ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order error", "err", err)
+		return db.Fulfillment{}, apperr.Internal("Error fetching order")
+	}
+
+	f, err := q.GetFulfillment(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Fulfillment{}, ErrFulfillmentNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get fulfillment error", "err", err)
+		return db.Fulfillment{}, apperr.Internal("Error fetching fulfillment")
+	}
+	if f.OrderID != orderID {
+		return db.Fulfillment{}, ErrFulfillmentNotFound
+	}
+
+	if in.Carrier != "" {
+		f.Carrier = pgtype.Text{String: in.Carrier, Valid: true}
+	}
+	if in.TrackingNumber != "" {
+		f.TrackingNumber = pgtype.Text{String: in.TrackingNumber, Valid: true}
+	}
+	if in.TrackingURL != "" {
+		f.TrackingUrl = pgtype.Text{String: in.TrackingURL, Valid: true}
+	} else if in.Carrier != "" || in.TrackingNumber != "" {
+		if u := fulfillment.TrackingURL(f.Carrier.String, f.TrackingNumber.String); u != "" {
+			f.TrackingUrl = pgtype.Text{String: u, Valid: true}
+		}
+	}
+
+	previous := f.Status
+	if in.Status != "" {
+		if !fulfillment.CanTransition(f.Status, in.Status) {
+			return db.Fulfillment{}, ErrInvalidFulfillment.Withf("cannot move from %s to %s", f.Status, in.Status)
+		}
+		f.Status = in.Status
+	}
+
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	if f.Status != fulfillment.StatusPending && !f.ShippedAt.Valid {
+		f.ShippedAt = now
+	}
+	if f.Status == fulfillment.StatusDelivered && !f.DeliveredAt.Valid {
+		f.DeliveredAt = now
+	}
+
+	if err := q.UpdateFulfillment(ctx, db.UpdateFulfillmentParams{
+		ID:             f.ID,
+		Carrier:        f.Carrier,
+		TrackingNumber: f.TrackingNumber,
+		TrackingUrl:    f.TrackingUrl,
+		Status:         f.Status,
+		ShippedAt:      f.ShippedAt,
+		DeliveredAt:    f.DeliveredAt,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update fulfillment error", "err", err)
+		return db.Fulfillment{}, apperr.Internal("Error occurred updating fulfillment")
+	}
+
+	if err := refreshFulfillmentStatus(ctx, q, &order, actor); err != nil {
+		return db.Fulfillment{}, apperr.Internal("Error occurred updating fulfillment")
+	}
+
+	if f.Status != previous {
+		items, err := q.ListOrderFulfillmentItems(ctx, orderID)
+		if err != nil {
+			slog.ErrorContext(ctx, "List fulfillment items error", "err", err)
+			return db.Fulfillment{}, apperr.Internal("Error occurred updating fulfillment")
+		}
+		shipped := make([]db.FulfillmentItem, 0, len(items))
+		for _, item := range items {
+			if item.FulfillmentID == f.ID {
+				shipped = append(shipped, item)
+			}
+		}
+		notifyShipment(ctx, q, order, f, shipped)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit fulfillment error", "err", err)
+		return db.Fulfillment{}, apperr.Internal("Error occurred updating fulfillment")
+	}
+
+	return f, nil
+}
+
+// TrackOrder returns the shipment progress of an order for a customer. The
+// email must match the order, otherwise the order is reported as not found.
+func TrackOrder(ctx context.Context, conn db.Store, orderID int32, email string) (OrderTracking, error) {
+	q := db.New(conn)
+
+	order, err := q.GetOrder(ctx, orderID)
+	if err != nil || !order.Email.Valid || !strings.EqualFold(order.Email.String, strings.TrimSpace(email)) {
+		return OrderTracking{}, ErrOrderNotFound
+	}
+
+	items, err := q.GetOrderItems(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return OrderTracking{}, apperr.Internal("Error fetching order")
+	}
+
+	fulfillments, err := loadFulfillments(ctx, q, orderID)
+	if err != nil {
+		return OrderTracking{}, apperr.Internal("Error fetching order")
+	}
+
+	names := make(map[int32]string, len(items))
+	fulfilled := make(map[int32]int32, len(items))
+	for _, item := range items {
+		names[item.ID] = item.Name
+	}
+
+	shipments := make([]TrackingShipment, 0, len(fulfillments))
+	for _, f := range fulfillments {
+		s := TrackingShipment{
+			Carrier:        f.Carrier.String,
+			TrackingNumber: f.TrackingNumber.String,
+			TrackingURL:    f.TrackingUrl.String,
+			Status:         f.Status,
+			ShippedAt:      f.ShippedAt,
+			DeliveredAt:    f.DeliveredAt,
+			Items:          make([]TrackingItem, 0, len(f.Items)),
+		}
+		for _, item := range f.Items {
+			fulfilled[item.OrderItemID] += item.Quantity
+			s.Items = append(s.Items, TrackingItem{Name: names[item.OrderItemID], Quantity: item.Quantity, Fulfilled: item.Quantity})
+		}
+		shipments = append(shipments, s)
+	}
+
+	tracking := OrderTracking{
+		OrderNumber:       order.ID,
+		Status:            order.Status,
+		FulfillmentStatus: order.FulfillmentStatus,
+		CreatedAt:         order.CreatedAt,
+		Items:             make([]TrackingItem, 0, len(items)),
+		Shipments:         shipments,
+	}
+	for _, item := range items {
+		tracking.Items = append(tracking.Items, TrackingItem{Name: item.Name, Quantity: item.Quantity, Fulfilled: fulfilled[item.ID]})
+	}
+
+	return tracking, nil
+}
+
+// loadFulfillments returns the fulfillments of an order with their items
+func loadFulfillments(ctx context.Context, q *db.Queries, orderID int32) ([]Fulfillment, error) {
+	rows, err := q.ListOrderFulfillments(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "List fulfillments error", "err", err)
+		return nil, err
+	}
+
+	items, err := q.ListOrderFulfillmentItems(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "List fulfillment items error", "err", err)
+		return nil, err
+	}
+
+	byID := make(map[int32][]db.FulfillmentItem, len(rows))
+	for _, item := range items {
+		byID[item.FulfillmentID] = append(byID[item.FulfillmentID], item)
+	}
+
+	fulfillments := make([]Fulfillment, 0, len(rows))
+	for _, f := range rows {
+		fulfillments = append(fulfillments, Fulfillment{
+			Fulfillment: f,
+			Items:       append(make([]db.FulfillmentItem, 0), byID[f.ID]...),
+		})
+	}
+	return fulfillments, nil
+}
+
+// loadShipments returns the ordered quantity of each order item and the
+// quantities already covered by fulfillments
+func loadShipments(ctx context.Context, q *db.Queries, orderID int32) (map[int32]int32, []fulfillment.Shipment, error) {
+	items, err := q.GetOrderItems(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return nil, nil, err
+	}
+
+	ordered := make(map[int32]int32, len(items))
+	for _, item := range items {
+		ordered[item.ID] = item.Quantity
+	}
+
+	fulfillments, err := loadFulfillments(ctx, q, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shipments := make([]fulfillment.Shipment, 0, len(fulfillments))
+	for _, f := range fulfillments {
+		s := fulfillment.Shipment{Status: f.Status, Items: make(map[int32]int32, len(f.Items))}
+		for _, item := range f.Items {
+			s.Items[item.OrderItemID] += item.Quantity
+		}
+		shipments = append(shipments, s)
+	}
+	return ordered, shipments, nil
+}
+
+// refreshFulfillmentStatus recomputes the order's fulfillment status and
+// moves the order itself forward to processing, shipped or delivered
+func refreshFulfillmentStatus(ctx context.Context, q *db.Queries, order *db.Order, actor Actor) error {
+	ordered, shipments, err := loadShipments(ctx, q, order.ID)
+	if err != nil {
+		return err
+	}
+
+	status := fulfillment.OrderStatus(ordered, shipments)
+	if err := q.UpdateOrderFulfillmentStatus(ctx, db.UpdateOrderFulfillmentStatusParams{
+		ID:                order.ID,
+		FulfillmentStatus: status,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update order fulfillment status error", "err", err)
+		return err
+	}
+	order.FulfillmentStatus = status
+
+	target := OrderProcessing
+	switch status {
+	case fulfillment.OrderShipped:
+		target = OrderShipped
+	case fulfillment.OrderDelivered:
+		target = OrderDelivered
+	}
+	return advanceOrder(ctx, q, order, target, actor, "Fulfillment "+status)
+}
@@ -0,0 +1,254 @@
+package methods
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/totp"
+)
+
+// RecoveryCodeCount is how many recovery codes an admin gets at a time
+const RecoveryCodeCount = 10
+
+var (
+	ErrMFAInvalidCode    = apperr.Unauthorized("Invalid two-factor code")
+	ErrMFANotSetUp       = apperr.Conflict("Two-factor authentication isn't set up")
+	ErrMFAAlreadyEnabled = apperr.Conflict("Two-factor authentication is already enabled")
+)
+
+// MFASetup is what an authenticator app needs to start producing codes
+type MFASetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// URI to show as a QR code
+}
+
+// MFAStatus says whether an admin has two-factor authentication on
+type MFAStatus struct {
+	Enabled           bool  `json:"enabled"`
+	Pending           bool  `json:"pending"` // set up but not confirmed with a code yet
+	RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+}
+
+// MFAEnabled reports whether the user has to give a two-factor code to log in
+func MFAEnabled(ctx context.Context, conn db.Store, userID int32) (bool, error) {
+	mfa, err := db.New(conn).GetUserMFA(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return mfa.Enabled, nil
+}
+
+func GetMFAStatus(ctx context.Context, conn db.Store, userID int32) (MFAStatus, error) {
+	q := db.New(conn)
+
+	mfa, err := q.GetUserMFA(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return MFAStatus{}, nil
+	}
+	if err != nil {
+		return MFAStatus{}, err
+	}
+	left, err := q.CountMFARecoveryCodes(ctx, userID)
+	if err != nil {
+		return MFAStatus{}, err
+	}
+	return MFAStatus{Enabled: mfa.Enabled, Pending: !mfa.Enabled, RecoveryCodesLeft: left}, nil
+}
+
+// StartMFASetup stores a new pending secret for the user. Starting again
+// before confirming replaces it; once enabled it has to be reset first.
+func StartMFASetup(ctx context.Context, conn db.Store, userID int32, issuer, account string) (MFASetup, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return MFASetup{}, err
+	}
+
+	_, err = db.New(conn).StartUserMFA(ctx, db.StartUserMFAParams{UserID: userID, Secret: secret})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return MFASetup{}, ErrMFAAlreadyEnabled
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Start MFA setup error", "err", err)
+		return MFASetup{}, err
+	}
+
+	return MFASetup{Secret: secret, URI: totp.URI(issuer, account, secret)}, nil
+}
+
+// EnableMFA confirms the pending secret with a code from the app and
+// returns the recovery codes, which are only ever shown this once
+func EnableMFA(ctx context.Context, conn db.Store, userID int32, code string) ([]string, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(tx)
+
+	mfa, err := q.GetUserMFA(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMFANotSetUp
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrMFAInvalidCode
+	}
+	n, err := q.EnableUserMFA(ctx, db.EnableUserMFAParams{UserID: userID, LastStep: step})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyMFA checks a code from the app, or a recovery code, for a user with
+// two-factor authentication enabled. Each code works once.
+func VerifyMFA(ctx context.Context, conn db.Store, userID int32, code string) (usedRecoveryCode bool, err error) {
+	q := db.New(conn)
+
+	mfa, err := q.GetUserMFA(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !mfa.Enabled) {
+		return false, ErrMFANotSetUp
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := totp.Validate(mfa.Secret, code, time.Now()); ok {
+		n, err := q.UseUserMFAStep(ctx, db.UseUserMFAStepParams{UserID: userID, LastStep: step})
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			// the code, or a later one, was already used
+			return false, ErrMFAInvalidCode
+		}
+		return false, nil
+	}
+
+	n, err := q.UseMFARecoveryCode(ctx, db.UseMFARecoveryCodeParams{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, ErrMFAInvalidCode
+	}
+	return true, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a
+// code from the app
+func RegenerateRecoveryCodes(ctx context.Context, conn db.Store, userID int32, code string) ([]string, error) {
+	if used, err := VerifyMFA(ctx, conn, userID, code); err != nil {
+		return nil, err
+	} else if used {
+		// a recovery code can't vouch for new ones
+		return nil, ErrMFAInvalidCode
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	codes, err := replaceRecoveryCodes(ctx, db.New(tx), userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetMFA turns two-factor authentication off for a user who lost their
+// authenticator and recovery codes. They set it up again on their next
+// login.
+func ResetMFA(ctx context.Context, conn db.Store, userID int32) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(tx)
+
+	n, err := q.DeleteUserMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrMFANotSetUp
+	}
+	if err := q.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *db.Queries, userID int32) ([]string, error) {
+	if err := q.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if err := q.AddMFARecoveryCode(ctx, db.AddMFARecoveryCodeParams{UserID: userID, CodeHash: hashRecoveryCode(code)}); err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns 80 random bits as xxxx-xxxx-xxxx-xxxx
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+	return s[:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:], nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed
+// back however they were written down
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package methods
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// numericToCents converts a DECIMAL(10, 2) price into cents
+func numericToCents(n pgtype.Numeric) int64 {
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return 0
+	}
+	return int64(math.Round(f.Float64 * 100))
+}
+
+func centsToFloat(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+func floatToNumeric(f float64) pgtype.Numeric {
+	var n pgtype.Numeric
+	n.Scan(strconv.FormatFloat(f, 'f', -1, 64))
+	return n
+}
+
+func floatToCents(f float64) int64 {
+	return int64(math.Round(f * 100))
+}
+
+// optionalNumeric stores zero as NULL
+func optionalNumeric(f float64) pgtype.Numeric {
+	if f == 0 {
+		return pgtype.Numeric{}
+	}
+	return floatToNumeric(f)
+}
@@ -0,0 +1,93 @@
+package methods
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/fulfillment"
+	"github.com/petermazzocco/go-ecommerce-api/internal/notifications"
+)
+
+// notifyOrderPaid queues the order confirmation email. Template errors are
+// logged rather than returned so they never block a payment.
+func notifyOrderPaid(ctx context.Context, q *db.Queries, order db.Order) {
+	if !order.Email.Valid {
+		return
+	}
+
+	items, err := q.GetOrderItems(ctx, order.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return
+	}
+
+	data := notifications.OrderConfirmationData{
+		OrderNumber: order.ID,
+		Items:       make([]notifications.LineItem, 0, len(items)),
+		Subtotal:    centsToFloat(numericToCents(order.Subtotal)),
+		Shipping:    centsToFloat(numericToCents(order.ShippingTotal)),
+		Tax:         centsToFloat(numericToCents(order.TaxTotal)),
+		Total:       centsToFloat(numericToCents(order.Total)),
+		Currency:    order.Currency,
+	}
+	for _, item := range items {
+		data.Items = append(data.Items, notifications.LineItem{
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    centsToFloat(numericToCents(item.UnitPrice) * int64(item.Quantity)),
+		})
+	}
+
+	if _, err := notifications.Enqueue(ctx, q, notifications.OrderConfirmation, order.Email.String, data); err != nil {
+		slog.ErrorContext(ctx, "Enqueue order confirmation error", "err", err)
+	}
+}
+
+// notifyShipment queues a shipping update once a fulfillment ships or is
+// delivered
+func notifyShipment(ctx context.Context, q *db.Queries, order db.Order, f db.Fulfillment, items []db.FulfillmentItem) {
+	if !order.Email.Valid || f.Status == fulfillment.StatusPending {
+		return
+	}
+
+	orderItems, err := q.GetOrderItems(ctx, order.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return
+	}
+	names := make(map[int32]string, len(orderItems))
+	for _, item := range orderItems {
+		names[item.ID] = item.Name
+	}
+
+	data := notifications.ShippingUpdateData{
+		OrderNumber:    order.ID,
+		Status:         f.Status,
+		Carrier:        f.Carrier.String,
+		TrackingNumber: f.TrackingNumber.String,
+		TrackingURL:    f.TrackingUrl.String,
+		Items:          make([]notifications.LineItem, 0, len(items)),
+	}
+	for _, item := range items {
+		data.Items = append(data.Items, notifications.LineItem{Name: names[item.OrderItemID], Quantity: item.Quantity})
+	}
+
+	if _, err := notifications.Enqueue(ctx, q, notifications.ShippingUpdate, order.Email.String, data); err != nil {
+		slog.ErrorContext(ctx, "Enqueue shipping update error", "err", err)
+	}
+}
+
+// GetEmails returns the most recent emails in the outbox
+func GetEmails(ctx context.Context, conn db.Store, limit int) ([]db.EmailOutbox, error) {
+	q := db.New(conn)
+
+	emails, err := q.ListEmails(ctx, int32(limit))
+	if err != nil {
+		slog.ErrorContext(ctx, "List emails error", "err", err)
+		return []db.EmailOutbox{}, apperr.Internal("Error fetching emails")
+	}
+
+	return append(make([]db.EmailOutbox, 0), emails...), nil
+}
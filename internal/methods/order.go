@@ -0,0 +1,194 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tax"
+)
+
+const DefaultCurrency = "usd"
+
+// Order statuses, see order_state.go for the allowed transitions
+const (
+	OrderPendingPayment = "pending_payment"
+	OrderPaid           = "paid"
+	OrderProcessing     = "processing"
+	OrderShipped        = "shipped"
+	OrderDelivered      = "delivered"
+	OrderCancelled      = "cancelled"
+	OrderRefunded       = "refunded"
+)
+
+type OrderDetail struct {
+	Order        db.Order          `json:"order"`
+	Items        []db.OrderItem    `json:"items"`
+	TaxLines     []db.OrderTaxLine `json:"taxLines"`
+	Refunds      []db.Refund       `json:"refunds"`
+	Fulfillments []Fulfillment     `json:"fulfillments"`
+	Events       []db.OrderEvent   `json:"events"`
+}
+
+// CreateOrder snapshots the cart summary, including its tax lines, into a new order
+func CreateOrder(ctx context.Context, conn db.Store, cartID uuid.UUID, sessionID, email string, s CartSummary) (db.Order, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin order error", "err", err)
+		return db.Order{}, apperr.Internal("Error occurred creating order")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	var shippingMethod pgtype.Text
+	if s.Shipping != nil {
+		shippingMethod = pgtype.Text{String: s.Shipping.Name, Valid: true}
+	}
+
+	order, err := q.CreateOrder(ctx, db.CreateOrderParams{
+		CartID:            pgtype.UUID{Bytes: cartID, Valid: true},
+		CheckoutSessionID: pgtype.Text{String: sessionID, Valid: sessionID != ""},
+		Email:             pgtype.Text{String: email, Valid: email != ""},
+		Currency:          DefaultCurrency,
+		Subtotal:          floatToNumeric(s.Subtotal),
+		TaxTotal:          floatToNumeric(s.TaxTotal),
+		Total:             floatToNumeric(s.Total),
+		TaxInclusive:      s.TaxInclusive,
+		ShippingMethod:    shippingMethod,
+		ShippingTotal:     floatToNumeric(s.ShippingTotal),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create order error", "err", err)
+		return db.Order{}, apperr.Internal("Error occurred creating order")
+	}
+
+	if err := recordOrderEvent(ctx, q, order.ID, "", order.Status, ActorCustomer, "Checkout started"); err != nil {
+		return db.Order{}, apperr.Internal("Error occurred creating order")
+	}
+
+	for _, item := range s.Items {
+		category := item.TaxCategory
+		if category == "" {
+			category = tax.DefaultCategory
+		}
+		if err := q.AddOrderItem(ctx, db.AddOrderItemParams{
+			OrderID:     order.ID,
+			ProductID:   pgtype.Int4{Int32: item.ProductID, Valid: true},
+			Name:        item.Name,
+			PriceID:     item.PriceID,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.Price,
+			TaxCategory: category,
+		}); err != nil {
+			slog.ErrorContext(ctx, "Add order item error", "err", err)
+			return db.Order{}, apperr.Internal("Error occurred creating order")
+		}
+	}
+
+	for _, line := range s.TaxLines {
+		if err := q.AddOrderTaxLine(ctx, db.AddOrderTaxLineParams{
+			OrderID:       order.ID,
+			Name:          line.Name,
+			Jurisdiction:  line.Jurisdiction,
+			Rate:          floatToNumeric(line.Rate),
+			TaxableAmount: floatToNumeric(line.TaxableAmount),
+			Amount:        floatToNumeric(line.Amount),
+		}); err != nil {
+			slog.ErrorContext(ctx, "Add order tax line error", "err", err)
+			return db.Order{}, apperr.Internal("Error occurred creating order")
+		}
+	}
+
+	if err := publish(ctx, q, events.CartCheckedOutData{
+		CartID:            cartID.String(),
+		OrderID:           order.ID,
+		CheckoutSessionID: sessionID,
+		Email:             email,
+		Total:             s.Total,
+		Currency:          order.Currency,
+	}); err != nil {
+		return db.Order{}, apperr.Internal("Error occurred creating order")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit order error", "err", err)
+		return db.Order{}, apperr.Internal("Error occurred creating order")
+	}
+	metrics.CheckoutsStarted.Inc()
+
+	return order, nil
+}
+
+func GetOrders(ctx context.Context, conn db.Store) ([]db.Order, error) {
+	q := db.New(conn)
+
+	orders, err := q.ListOrders(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List orders error", "err", err)
+		return []db.Order{}, apperr.Internal("Error fetching orders")
+	}
+
+	if len(orders) == 0 {
+		return make([]db.Order, 0), nil
+	}
+	return orders, nil
+}
+
+func GetOrder(ctx context.Context, conn db.Store, id int32) (OrderDetail, error) {
+	q := db.New(conn)
+
+	order, err := q.GetOrder(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return OrderDetail{}, ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order error", "err", err)
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	items, err := q.GetOrderItems(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	lines, err := q.GetOrderTaxLines(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order tax lines error", "err", err)
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	refunds, err := q.ListOrderRefunds(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "List refunds error", "err", err)
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	fulfillments, err := loadFulfillments(ctx, q, id)
+	if err != nil {
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	events, err := q.ListOrderEvents(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "List order events error", "err", err)
+		return OrderDetail{}, apperr.Internal("Error fetching order")
+	}
+
+	return OrderDetail{
+		Order:        order,
+		Items:        append(make([]db.OrderItem, 0), items...),
+		TaxLines:     append(make([]db.OrderTaxLine, 0), lines...),
+		Refunds:      append(make([]db.Refund, 0), refunds...),
+		Fulfillments: fulfillments,
+		Events:       append(make([]db.OrderEvent, 0), events...),
+	}, nil
+}
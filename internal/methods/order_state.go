@@ -0,0 +1,213 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+)
+
+var ErrInvalidTransition = apperr.Conflict("Invalid order status transition")
+
+// orderTransitions lists the statuses each order status may move to.
+// Cancelled and refunded orders are final.
+var orderTransitions = map[string][]string{
+	OrderPendingPayment: {OrderPaid, OrderCancelled},
+	OrderPaid:           {OrderProcessing, OrderShipped, OrderCancelled, OrderRefunded},
+	OrderProcessing:     {OrderShipped, OrderCancelled, OrderRefunded},
+	OrderShipped:        {OrderDelivered, OrderRefunded},
+	OrderDelivered:      {OrderRefunded},
+	OrderCancelled:      {},
+	OrderRefunded:       {},
+}
+
+// Actor records who moved an order to a new status
+type Actor struct {
+	Name   string
+	UserID int32
+}
+
+var (
+	ActorSystem   = Actor{Name: "system"}
+	ActorStripe   = Actor{Name: "stripe"}
+	ActorCustomer = Actor{Name: "customer"}
+)
+
+func AdminActor(userID int32) Actor {
+	return Actor{Name: "admin", UserID: userID}
+}
+
+func ValidOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+func CanTransitionOrder(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionOrder moves an order to a new status, rejecting transitions the
+// state machine does not allow, and records the change in order_events
+func TransitionOrder(ctx context.Context, conn db.Store, id int32, to string, actor Actor, note string) (db.Order, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin order transition error", "err", err)
+		return db.Order{}, apperr.Internal("Error occurred updating order")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	order, err := q.GetOrderForUpdate(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Order{}, ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order error", "err", err)
+		return db.Order{}, apperr.Internal("Error fetching order")
+	}
+
+	if err := transitionOrder(ctx, q, &order, to, actor, note); err != nil {
+		return db.Order{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit order transition error", "err", err)
+		return db.Order{}, apperr.Internal("Error occurred updating order")
+	}
+
+	return order, nil
+}
+
+// transitionOrder is TransitionOrder for callers that already hold a
+// transaction and the order row
+func transitionOrder(ctx context.Context, q *db.Queries, order *db.Order, to string, actor Actor, note string) error {
+	if !CanTransitionOrder(order.Status, to) {
+		return ErrInvalidTransition.Withf("%s to %s", order.Status, to)
+	}
+
+	if err := q.UpdateOrderStatus(ctx, db.UpdateOrderStatusParams{ID: order.ID, Status: to}); err != nil {
+		slog.ErrorContext(ctx, "Update order status error", "err", err)
+		return apperr.Internal("Error occurred updating order")
+	}
+
+	if err := recordOrderEvent(ctx, q, order.ID, order.Status, to, actor, note); err != nil {
+		return err
+	}
+
+	if err := publish(ctx, q, events.OrderStatusChangedData{
+		OrderID: order.ID,
+		From:    order.Status,
+		To:      to,
+		Actor:   actor.Name,
+		Note:    note,
+	}); err != nil {
+		return apperr.Internal("Error occurred updating order")
+	}
+
+	order.Status = to
+	if to == OrderPaid {
+		if err := publish(ctx, q, events.OrderPaidData{
+			OrderID:  order.ID,
+			Email:    order.Email.String,
+			Total:    centsToFloat(numericToCents(order.Total)),
+			Currency: order.Currency,
+		}); err != nil {
+			return apperr.Internal("Error occurred updating order")
+		}
+		notifyOrderPaid(ctx, q, *order)
+	}
+	return nil
+}
+
+// advanceOrder walks an order forward along paid, processing, shipped and
+// delivered until it reaches target, recording every step. Orders already at
+// or past target, or off that path, are left alone.
+func advanceOrder(ctx context.Context, q *db.Queries, order *db.Order, target string, actor Actor, note string) error {
+	path := []string{OrderPaid, OrderProcessing, OrderShipped, OrderDelivered}
+
+	current, goal := -1, -1
+	for i, s := range path {
+		if s == order.Status {
+			current = i
+		}
+		if s == target {
+			goal = i
+		}
+	}
+	if current < 0 || goal <= current {
+		return nil
+	}
+
+	for _, s := range path[current+1 : goal+1] {
+		if err := transitionOrder(ctx, q, order, s, actor, note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordOrderEvent(ctx context.Context, q *db.Queries, orderID int32, from, to string, actor Actor, note string) error {
+	if err := q.AddOrderEvent(ctx, db.AddOrderEventParams{
+		OrderID:    orderID,
+		FromStatus: pgtype.Text{String: from, Valid: from != ""},
+		ToStatus:   to,
+		Actor:      actor.Name,
+		UserID:     pgtype.Int4{Int32: actor.UserID, Valid: actor.UserID != 0},
+		Note:       pgtype.Text{String: note, Valid: note != ""},
+	}); err != nil {
+		slog.ErrorContext(ctx, "Add order event error", "err", err)
+		return apperr.Internal("Error occurred updating order")
+	}
+	return nil
+}
+
+// MarkOrderPaid moves the order created for a checkout session to paid once
+// Stripe reports the payment, from its webhook or the customer's return to
+// the confirmation page. It is safe to call more than once.
+func MarkOrderPaid(ctx context.Context, conn db.Store, sessionID string) error {
+	order, err := db.New(conn).GetOrderByCheckoutSession(ctx, pgtype.Text{String: sessionID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order by session error", "err", err)
+		return apperr.Internal("Error occurred updating order")
+	}
+	if order.Status != OrderPendingPayment {
+		return nil
+	}
+
+	_, err = TransitionOrder(ctx, conn, order.ID, OrderPaid, ActorStripe, "Checkout session "+sessionID+" paid")
+	if errors.Is(err, ErrInvalidTransition) {
+		// Another request marked it paid first
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	total, _ := order.Total.Float64Value()
+	metrics.RecordSale(order.Currency, total.Float64)
+
+	// Credit the recovery reminder, if any, with the sale
+	if order.CartID.Valid {
+		if err := db.New(conn).MarkCartRecoveryConverted(ctx, db.MarkCartRecoveryConvertedParams{
+			CartID:  order.CartID,
+			OrderID: pgtype.Int4{Int32: order.ID, Valid: true},
+		}); err != nil {
+			slog.ErrorContext(ctx, "Mark cart recovery converted error", "err", err)
+		}
+	}
+	return nil
+}
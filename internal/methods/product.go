@@ -2,21 +2,31 @@ package methods
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
 	"strconv"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+)
+
+var (
+	ErrProductNotFound = apperr.NotFound("Product not found")
+	ErrSizeNotFound    = apperr.NotFound("Product size not found")
+	ErrInvalidStock    = apperr.Validation("Invalid stock")
 )
 
 type Product struct {
 	ID          int                     `json:"id"`
 	Price       float64                 `json:"price"`
 	Name        string                  `json:"name"`
-	PriceID   string                  `json:"productID"`
+	PriceID     string                  `json:"productID"`
 	Description string                  `json:"description"`
+	TaxCategory string                  `json:"taxCategory"`
+	WeightGrams int                     `json:"weightGrams"`
 	Images      []string                `json:"images"`
 	Sizes       []db.GetProductSizesRow `json:"sizes"`
 }
@@ -41,7 +51,7 @@ type FitGuide struct {
 	Knee          float64 `json:"knee"`
 }
 
-func GetProducts(ctx context.Context, conn *pgx.Conn) ([]Product, error) {
+func GetProducts(ctx context.Context, conn db.Store) ([]Product, error) {
 	var p []Product
 	q := db.New(conn)
 
@@ -49,14 +59,14 @@ func GetProducts(ctx context.Context, conn *pgx.Conn) ([]Product, error) {
 	for i := range products {
 		images, err := q.GetProductImages(ctx, products[i].ID)
 		if err != nil {
-			log.Println(err.Error())
-			return []Product{}, fmt.Errorf("Error occurred fetching product")
+			slog.ErrorContext(ctx, "Query failed", "query", "GetProductImages", "err", err)
+			return []Product{}, apperr.Internal("Error occurred fetching product")
 		}
 
 		sizes, err := q.GetProductSizes(ctx, products[i].ID)
 		if err != nil {
-			log.Println(err.Error())
-			return []Product{}, fmt.Errorf("Error occurred fetching product")
+			slog.ErrorContext(ctx, "Query failed", "query", "GetProductSizes", "err", err)
+			return []Product{}, apperr.Internal("Error occurred fetching product")
 		}
 		floatP, _ := products[i].Price.Float64Value()
 		p = append(p, Product{
@@ -64,103 +74,140 @@ func GetProducts(ctx context.Context, conn *pgx.Conn) ([]Product, error) {
 			Name:        products[i].Name,
 			Description: products[i].Description.String,
 			Price:       floatP.Float64,
+			TaxCategory: products[i].TaxCategory,
+			WeightGrams: int(products[i].WeightGrams),
 			Images:      images,
 			Sizes:       sizes,
 		})
 	}
 	if err != nil {
-		log.Println(err.Error())
-		return []Product{}, fmt.Errorf("Error occurred fetching product")
+		slog.ErrorContext(ctx, "Error listing products", "err", err)
+		return []Product{}, apperr.Internal("Error occurred fetching product")
 	}
 
 	return p, nil
 }
 
-func GetProductByID(ctx context.Context, conn *pgx.Conn, id int32) (Product, error) {
+func GetProductByID(ctx context.Context, conn db.Store, id int32) (Product, error) {
 	var p Product
 	q := db.New(conn)
 
 	product, err := q.GetProduct(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Product{}, ErrProductNotFound
+	}
 	if err != nil {
-		log.Println(err.Error())
-		return Product{}, fmt.Errorf("Error occurred fetching product")
+		slog.ErrorContext(ctx, "Query failed", "query", "GetProduct", "err", err)
+		return Product{}, apperr.Internal("Error occurred fetching product")
 	}
 
 	images, err := q.GetProductImages(ctx, id)
 	if err != nil {
-		log.Println(err.Error())
-		return Product{}, fmt.Errorf("Error occurred fetching product")
+		slog.ErrorContext(ctx, "Query failed", "query", "GetProductImages", "err", err)
+		return Product{}, apperr.Internal("Error occurred fetching product")
 	}
 
 	sizes, err := q.GetProductSizes(ctx, id)
 	if err != nil {
-		log.Println(err.Error())
-		return Product{}, fmt.Errorf("Error occurred fetching product")
+		slog.ErrorContext(ctx, "Query failed", "query", "GetProductSizes", "err", err)
+		return Product{}, apperr.Internal("Error occurred fetching product")
 	}
 	floatP, _ := product.Price.Float64Value()
 	p.ID = int(product.ID)
 	p.Name = product.Name
 	p.Price = floatP.Float64
 	p.Description = product.Description.String
+	p.TaxCategory = product.TaxCategory
+	p.WeightGrams = int(product.WeightGrams)
 	p.Images = images
 	p.Sizes = sizes
 
 	return p, nil
 }
 
-func GetProductSizesByID(ctx context.Context, conn *pgx.Conn, id int32) ([]db.GetProductSizesRow, error) {
+func GetProductSizesByID(ctx context.Context, conn db.Store, id int32) ([]db.GetProductSizesRow, error) {
 	q := db.New(conn)
 
 	sizes, err := q.GetProductSizes(ctx, id)
 
 	if err != nil {
-		log.Println(err.Error())
-		return []db.GetProductSizesRow{}, fmt.Errorf("Error occurred fetching product")
+		slog.ErrorContext(ctx, "Query failed", "query", "GetProductSizes", "err", err)
+		return []db.GetProductSizesRow{}, apperr.Internal("Error occurred fetching product")
 
 	}
 
 	return sizes, nil
 }
 
-func GetProductImagesByID(ctx context.Context, conn *pgx.Conn, id int32) ([]string, error) {
+func GetProductImagesByID(ctx context.Context, conn db.Store, id int32) ([]string, error) {
 	q := db.New(conn)
 
 	images, err := q.GetProductImages(ctx, id)
 
 	if err != nil {
-		log.Println(err.Error())
-		return []string{}, fmt.Errorf("Error occurred fetching product")
+		slog.ErrorContext(ctx, "Query failed", "query", "GetProductImages", "err", err)
+		return []string{}, apperr.Internal("Error occurred fetching product")
 	}
 
 	return images, nil
 }
 
-func CreateProduct(ctx context.Context, conn *pgx.Conn, p Product) (db.Product, error) {
-	q := db.New(conn)
-
+func CreateProduct(ctx context.Context, conn db.Store, p Product) (db.Product, error) {
 	var price pgtype.Numeric
 	strPrice := strconv.FormatFloat(p.Price, 'f', -1, 64)
 	err := price.Scan(strPrice)
 	if err != nil {
-		log.Println(err.Error())
-		return db.Product{}, fmt.Errorf("Error occurred creating product")
+		slog.ErrorContext(ctx, "Error parsing price", "price", p.Price, "err", err)
+		return db.Product{}, apperr.Internal("Error occurred creating product")
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin product error", "err", err)
+		return db.Product{}, apperr.Internal("Error occurred creating product")
 	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
 	product, err := q.CreateProduct(ctx, db.CreateProductParams{
 		Name:        p.Name,
 		Description: pgtype.Text{String: p.Description},
 		Price:       price,
-		PriceID:   p.PriceID,
+		PriceID:     p.PriceID,
 	})
 
 	if err != nil {
-		log.Println(err.Error())
-		return db.Product{}, fmt.Errorf("Error occurred creating product")
+		slog.ErrorContext(ctx, "Query failed", "query", "CreateProduct", "err", err)
+		return db.Product{}, apperr.Internal("Error occurred creating product")
+	}
+
+	if err := publish(ctx, q, events.ProductCreatedData{
+		ProductID: product.ID,
+		Name:      product.Name,
+		Price:     p.Price,
+		PriceID:   product.PriceID,
+	}); err != nil {
+		return db.Product{}, apperr.Internal("Error occurred creating product")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit product error", "err", err)
+		return db.Product{}, apperr.Internal("Error occurred creating product")
 	}
 	return product, nil
 }
 
-func AddProductSizes(ctx context.Context, conn *pgx.Conn, pID int32, sizes []Size) error {
-	q := db.New(conn)
+// AddProductSizes stores a product's sizes, raising StockLow for those at or
+// below stockLow
+func AddProductSizes(ctx context.Context, conn db.Store, pID int32, sizes []Size, stockLow int32) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin product sizes error", "err", err)
+		return apperr.Internal("Error occurred creating product")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
 
 	for _, size := range sizes {
 		if err := q.AddProductSize(ctx, db.AddProductSizeParams{
@@ -168,15 +215,61 @@ func AddProductSizes(ctx context.Context, conn *pgx.Conn, pID int32, sizes []Siz
 			SizeName:  size.Size,
 			Stock:     int32(size.Stock),
 		}); err != nil {
-			log.Println(err.Error())
-			return fmt.Errorf("Error occurred creating product")
+			slog.ErrorContext(ctx, "Query failed", "query", "AddProductSize", "err", err)
+			return apperr.Internal("Error occurred creating product")
 		}
+		if err := publishStockLow(ctx, q, stockLow, pID, size.Size, int32(size.Stock)); err != nil {
+			return apperr.Internal("Error occurred creating product")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit product sizes error", "err", err)
+		return apperr.Internal("Error occurred creating product")
+	}
+	return nil
+}
+
+// SetProductStock replaces the stock of one of a product's sizes, raising
+// StockLow when it's at or below stockLow
+func SetProductStock(ctx context.Context, conn db.Store, pID int32, size string, stock int, stockLow int32) error {
+	if stock < 0 {
+		return ErrInvalidStock.Withf("stock can not be negative")
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin product stock error", "err", err)
+		return apperr.Internal("Error occurred updating stock")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+	rows, err := q.UpdateProductStock(ctx, db.UpdateProductStockParams{
+		ProductID: pID,
+		SizeName:  size,
+		Stock:     int32(stock),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Update product stock error", "err", err)
+		return apperr.Internal("Error occurred updating stock")
+	}
+	if rows == 0 {
+		return ErrSizeNotFound
+	}
+
+	if err := publishStockLow(ctx, q, stockLow, pID, size, int32(stock)); err != nil {
+		return apperr.Internal("Error occurred updating stock")
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit product stock error", "err", err)
+		return apperr.Internal("Error occurred updating stock")
+	}
 	return nil
 }
 
-func AddProductImages(ctx context.Context, conn *pgx.Conn, pID int32, images []string) error {
+func AddProductImages(ctx context.Context, conn db.Store, pID int32, images []string) error {
 	q := db.New(conn)
 
 	for _, image := range images {
@@ -184,56 +277,103 @@ func AddProductImages(ctx context.Context, conn *pgx.Conn, pID int32, images []s
 			ProductID: pID,
 			ImageUrl:  image,
 		}); err != nil {
-			log.Println(err.Error())
-			return fmt.Errorf("Error occurred creating product")
+			slog.ErrorContext(ctx, "Query failed", "query", "AddProductImage", "err", err)
+			return apperr.Internal("Error occurred creating product")
 		}
 	}
 
 	return nil
 }
 
-func RemoveProduct(ctx context.Context, conn *pgx.Conn, id int) error {
-	q := db.New(conn)
-
+func RemoveProduct(ctx context.Context, conn db.Store, id int) error {
 	_, err := GetProductByID(ctx, conn, int32(id))
 	if err != nil {
-		log.Println(err.Error())
-		return fmt.Errorf("Product ID is not valid")
+		return err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin product error", "err", err)
+		return apperr.Internal("Error occurred deleting product")
 	}
+	defer tx.Rollback(ctx)
 
+	q := db.New(conn).WithTx(tx)
 	if err := q.DeleteProduct(ctx, int32(id)); err != nil {
-		return fmt.Errorf("Error occurred creating product")
+		return apperr.Internal("Error occurred deleting product")
+	}
+
+	if err := publish(ctx, q, events.ProductDeletedData{ProductID: int32(id)}); err != nil {
+		return apperr.Internal("Error occurred deleting product")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit product error", "err", err)
+		return apperr.Internal("Error occurred deleting product")
 	}
 	return nil
 }
 
-func UpdateProduct(ctx context.Context, conn *pgx.Conn, p Product) error {
-	q := db.New(conn)
-
+func UpdateProduct(ctx context.Context, conn db.Store, p Product) error {
 	_, err := GetProductByID(ctx, conn, int32(p.ID))
 	if err != nil {
-		log.Println(err.Error())
-		return fmt.Errorf("Product ID is not valid")
+		return err
 	}
 
 	var price pgtype.Numeric
 
 	strPrice := strconv.FormatFloat(p.Price, 'f', -1, 64)
 	if err := price.Scan(strPrice); err != nil {
-		log.Println(err.Error())
-		return fmt.Errorf("Error occurred updating product")
+		slog.ErrorContext(ctx, "Error parsing price", "price", p.Price, "err", err)
+		return apperr.Internal("Error occurred updating product")
 	}
 
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin product error", "err", err)
+		return apperr.Internal("Error occurred updating product")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
 	if err := q.UpdateProduct(ctx, db.UpdateProductParams{
 		ID:          int32(p.ID),
 		Name:        p.Name,
 		Price:       price,
 		Description: pgtype.Text{String: p.Description},
 	}); err != nil {
-		return fmt.Errorf("Error occurred updating product")
+		return apperr.Internal("Error occurred updating product")
+	}
+
+	if err := publish(ctx, q, events.ProductUpdatedData{
+		ProductID:   int32(p.ID),
+		Name:        p.Name,
+		Price:       p.Price,
+		Description: p.Description,
+	}); err != nil {
+		return apperr.Internal("Error occurred updating product")
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit product error", "err", err)
+		return apperr.Internal("Error occurred updating product")
+	}
 	return nil
 }
 
+func SetProductWeight(ctx context.Context, conn db.Store, id int32, grams int) error {
+	q := db.New(conn)
+
+	if grams < 0 {
+		return apperr.Invalid("weightGrams", "Weight can not be negative")
+	}
 
+	if err := q.UpdateProductWeight(ctx, db.UpdateProductWeightParams{
+		ID:          id,
+		WeightGrams: int32(grams),
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update product weight error", "err", err)
+		return apperr.Internal("Error occurred updating product")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+type AbandonedCart struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type RecoveryReport struct {
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	Sent             int32     `json:"sent"`
+	Restored         int32     `json:"restored"`
+	Converted        int32     `json:"converted"`
+	RestoreRate      float64   `json:"restoreRate"`
+	ConversionRate   float64   `json:"conversionRate"`
+	RecoveredRevenue float64   `json:"recoveredRevenue"`
+}
+
+// FindAbandonedCarts returns carts with an email and items that have been idle
+// for longer than idle but less than maxAge, have not been reminded yet and
+// have not been paid for
+func FindAbandonedCarts(ctx context.Context, conn db.Store, idle, maxAge time.Duration, limit int) ([]AbandonedCart, error) {
+	q := db.New(conn)
+
+	now := time.Now()
+	rows, err := q.ListAbandonedCarts(ctx, db.ListAbandonedCartsParams{
+		IdleBefore: pgtype.Timestamptz{Time: now.Add(-idle), Valid: true},
+		IdleAfter:  pgtype.Timestamptz{Time: now.Add(-maxAge), Valid: true},
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "List abandoned carts error", "err", err)
+		return nil, apperr.Internal("Error fetching abandoned carts")
+	}
+
+	carts := make([]AbandonedCart, 0, len(rows))
+	for _, row := range rows {
+		carts = append(carts, AbandonedCart{
+			ID:        row.ID.Bytes,
+			Email:     row.Email.String,
+			UpdatedAt: row.UpdatedAt.Time,
+		})
+	}
+	return carts, nil
+}
+
+// ClaimCartRecovery records that a reminder is being sent for a cart. It
+// returns false when another run already claimed the cart.
+func ClaimCartRecovery(ctx context.Context, conn db.Store, cartID uuid.UUID, email string) (db.CartRecovery, bool, error) {
+	q := db.New(conn)
+
+	recovery, err := q.CreateCartRecovery(ctx, db.CreateCartRecoveryParams{
+		CartID: pgtype.UUID{Bytes: cartID, Valid: true},
+		Email:  email,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.CartRecovery{}, false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Create cart recovery error", "err", err)
+		return db.CartRecovery{}, false, apperr.Internal("Error recording cart recovery")
+	}
+	return recovery, true, nil
+}
+
+// ReleaseCartRecovery forgets a claim whose reminder could not be sent, so the
+// next run tries again
+func ReleaseCartRecovery(ctx context.Context, conn db.Store, id int32) error {
+	q := db.New(conn)
+
+	if err := q.DeleteCartRecovery(ctx, id); err != nil {
+		slog.ErrorContext(ctx, "Delete cart recovery error", "err", err)
+		return apperr.Internal("Error releasing cart recovery")
+	}
+	return nil
+}
+
+// RestoreCart marks the reminder for a cart as used and returns the cart so
+// its cookie can be set again
+func RestoreCart(ctx context.Context, conn db.Store, cartID uuid.UUID) (db.Cart, error) {
+	cart, err := GetCart(ctx, conn, cartID)
+	if err != nil {
+		return db.Cart{}, err
+	}
+
+	q := db.New(conn)
+	if err := q.MarkCartRecoveryRestored(ctx, cart.ID); err != nil {
+		slog.ErrorContext(ctx, "Mark cart restored error", "err", err)
+		return db.Cart{}, apperr.Internal("Error restoring cart")
+	}
+	touchCart(ctx, q, cartID)
+
+	return cart, nil
+}
+
+// GetRecoveryReport counts reminders sent between from and to, and how many
+// of them brought the customer back and ended in a paid order
+func GetRecoveryReport(ctx context.Context, conn db.Store, from, to time.Time) (RecoveryReport, error) {
+	q := db.New(conn)
+
+	stats, err := q.GetCartRecoveryStats(ctx, db.GetCartRecoveryStatsParams{
+		SentFrom: pgtype.Timestamptz{Time: from, Valid: true},
+		SentTo:   pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Get cart recovery stats error", "err", err)
+		return RecoveryReport{}, apperr.Internal("Error fetching recovery report")
+	}
+
+	report := RecoveryReport{
+		From:             from,
+		To:               to,
+		Sent:             stats.Sent,
+		Restored:         stats.Restored,
+		Converted:        stats.Converted,
+		RecoveredRevenue: centsToFloat(numericToCents(stats.RecoveredRevenue)),
+	}
+	if stats.Sent > 0 {
+		report.RestoreRate = float64(stats.Restored) / float64(stats.Sent)
+		report.ConversionRate = float64(stats.Converted) / float64(stats.Sent)
+	}
+	return report, nil
+}
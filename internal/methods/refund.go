@@ -0,0 +1,284 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+)
+
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+var (
+	ErrOrderNotRefundable = apperr.Conflict("Order has no payment to refund")
+	ErrRefundExceedsTotal = apperr.Validation("Refund exceeds the amount left on the order")
+	ErrRefundNotFound     = apperr.NotFound("Refund not found")
+	ErrRefundSettled      = apperr.Conflict("Refund is no longer pending")
+	// ErrRefundUnconfirmed means the provider never answered. The refund
+	// stays pending and is sent again with RetryRefund.
+	ErrRefundUnconfirmed = &apperr.Error{Code: apperr.CodeInternal, Message: "Payment provider did not confirm the refund, retry it", Status: http.StatusBadGateway}
+)
+
+type RefundInput struct {
+	OrderID  int32
+	ReturnID int32   // optional, links the refund to an approved return
+	Amount   float64 // zero refunds everything left on the order
+	Reason   string
+	ActorID  int32 // admin issuing the refund
+}
+
+type OrderRefunds struct {
+	Refunds       []db.Refund      `json:"refunds"`
+	Events        []db.RefundEvent `json:"events"`
+	RefundedTotal float64          `json:"refundedTotal"`
+}
+
+// RefundOrder records a pending refund, sends it to the payment provider and
+// stores the outcome. Every state change is written to refund_events.
+func RefundOrder(ctx context.Context, conn db.Store, provider payments.Provider, in RefundInput) (db.Refund, error) {
+	q := db.New(conn)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin refund error", "err", err)
+		return db.Refund{}, apperr.Internal("Error occurred creating refund")
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	order, remaining, err := lockRefundableOrder(ctx, qtx, in.OrderID)
+	if err != nil {
+		return db.Refund{}, err
+	}
+	amount := floatToCents(in.Amount)
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return db.Refund{}, ErrRefundExceedsTotal
+	}
+
+	refund, err := createRefund(ctx, qtx, order.ID, amount, in)
+	if err != nil {
+		return db.Refund{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit refund error", "err", err)
+		return db.Refund{}, apperr.Internal("Error occurred creating refund")
+	}
+
+	return sendRefund(ctx, conn, provider, order, refund, in.ActorID)
+}
+
+// RetryRefund sends a refund left pending by an unanswered provider call
+// again. It reuses the refund's ID, so the provider's idempotency key stops
+// it from being paid out twice.
+func RetryRefund(ctx context.Context, conn db.Store, provider payments.Provider, orderID, refundID, actorID int32) (db.Refund, error) {
+	q := db.New(conn)
+
+	refund, err := q.GetOrderRefund(ctx, db.GetOrderRefundParams{ID: refundID, OrderID: orderID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Refund{}, ErrRefundNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get refund error", "err", err)
+		return db.Refund{}, apperr.Internal("Error fetching refund")
+	}
+	if refund.Status != RefundPending {
+		return db.Refund{}, ErrRefundSettled
+	}
+
+	order, err := q.GetOrder(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order error", "err", err)
+		return db.Refund{}, apperr.Internal("Error fetching order")
+	}
+
+	return sendRefund(ctx, conn, provider, order, refund, actorID)
+}
+
+// lockRefundableOrder locks the order until the transaction ends, so
+// concurrent refunds see each other's pending rows, and returns it with the
+// cents left to refund
+func lockRefundableOrder(ctx context.Context, q *db.Queries, orderID int32) (db.Order, int64, error) {
+	order, err := q.GetOrderForUpdate(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Order{}, 0, ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order error", "err", err)
+		return db.Order{}, 0, apperr.Internal("Error fetching order")
+	}
+	// Only paid orders that have not been cancelled or fully refunded
+	if !order.CheckoutSessionID.Valid || !CanTransitionOrder(order.Status, OrderRefunded) {
+		return db.Order{}, 0, ErrOrderNotRefundable
+	}
+
+	refunded, err := q.GetRefundedTotal(ctx, order.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get refunded total error", "err", err)
+		return db.Order{}, 0, apperr.Internal("Error fetching refunds")
+	}
+	return order, numericToCents(order.Total) - numericToCents(refunded), nil
+}
+
+// createRefund records a pending refund of amount cents. Call it in the
+// transaction that locked the order.
+func createRefund(ctx context.Context, q *db.Queries, orderID int32, amount int64, in RefundInput) (db.Refund, error) {
+	refund, err := q.CreateRefund(ctx, db.CreateRefundParams{
+		OrderID:   orderID,
+		ReturnID:  pgtype.Int4{Int32: in.ReturnID, Valid: in.ReturnID != 0},
+		Amount:    floatToNumeric(centsToFloat(amount)),
+		Reason:    in.Reason,
+		CreatedBy: pgtype.Int4{Int32: in.ActorID, Valid: in.ActorID != 0},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create refund error", "err", err)
+		return db.Refund{}, apperr.Internal("Error occurred creating refund")
+	}
+
+	if err := q.AddRefundEvent(ctx, db.AddRefundEventParams{
+		RefundID: refund.ID,
+		Status:   RefundPending,
+		Message:  fmt.Sprintf("Refund of %.2f requested by user %d: %s", centsToFloat(amount), in.ActorID, in.Reason),
+	}); err != nil {
+		slog.ErrorContext(ctx, "Add refund event error", "err", err)
+		return db.Refund{}, apperr.Internal("Error occurred creating refund")
+	}
+	return refund, nil
+}
+
+// sendRefund asks the provider to pay out a pending refund and stores the
+// answer. Without an answer the refund stays pending for RetryRefund.
+func sendRefund(ctx context.Context, conn db.Store, provider payments.Provider, order db.Order, refund db.Refund, actorID int32) (db.Refund, error) {
+	q := db.New(conn)
+
+	result, err := provider.Refund(ctx, payments.RefundRequest{
+		SessionID: order.CheckoutSessionID.String,
+		Amount:    numericToCents(refund.Amount),
+		Reason:    refund.Reason,
+		OrderID:   order.ID,
+		RefundID:  refund.ID,
+	})
+	if err != nil && !errors.Is(err, payments.ErrRejected) {
+		// The refund may have gone through, so it is neither failed nor
+		// released for a new refund
+		slog.ErrorContext(ctx, "Provider refund outcome unknown", "refund_id", refund.ID, "err", err)
+		return db.Refund{}, ErrRefundUnconfirmed
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Provider refund error", "err", err)
+		if err := setRefundStatus(ctx, q, refund.ID, RefundFailed, "", err.Error()); err != nil {
+			slog.ErrorContext(ctx, "Update refund error", "err", err)
+		}
+		failReturnRefund(ctx, q, refund)
+		return db.Refund{}, apperr.Internal("Payment provider rejected the refund")
+	}
+
+	status := RefundPending
+	switch result.Status {
+	case "succeeded":
+		status = RefundSucceeded
+	case "failed", "canceled":
+		status = RefundFailed
+	}
+
+	if err := setRefundStatus(ctx, q, refund.ID, status, result.ProviderRefundID, ""); err != nil {
+		slog.ErrorContext(ctx, "Update refund error", "err", err)
+		return db.Refund{}, apperr.Internal("Error occurred updating refund")
+	}
+
+	if status == RefundFailed {
+		failReturnRefund(ctx, q, refund)
+	} else {
+		refunded, err := q.GetRefundedTotal(ctx, order.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Get refunded total error", "err", err)
+		} else if numericToCents(refunded) >= numericToCents(order.Total) {
+			if _, err := TransitionOrder(ctx, conn, order.ID, OrderRefunded, AdminActor(actorID), refund.Reason); err != nil {
+				slog.ErrorContext(ctx, "Refund order transition error", "err", err)
+			}
+		}
+		if refund.ReturnID.Valid {
+			if err := q.UpdateReturnStatus(ctx, db.UpdateReturnStatusParams{ID: refund.ReturnID.Int32, Status: ReturnRefunded}); err != nil {
+				slog.ErrorContext(ctx, "Update return status error", "err", err)
+			}
+		}
+	}
+
+	refund.Status = status
+	refund.ProviderRefundID = pgtype.Text{String: result.ProviderRefundID, Valid: result.ProviderRefundID != ""}
+	return refund, nil
+}
+
+// failReturnRefund marks the return a failed refund belongs to, so it can be
+// approved again
+func failReturnRefund(ctx context.Context, q *db.Queries, refund db.Refund) {
+	if !refund.ReturnID.Valid {
+		return
+	}
+	if err := q.UpdateReturnStatus(ctx, db.UpdateReturnStatusParams{ID: refund.ReturnID.Int32, Status: ReturnRefundFailed}); err != nil {
+		slog.ErrorContext(ctx, "Update return status error", "err", err)
+	}
+}
+
+// setRefundStatus updates a refund and records the change in its audit trail
+func setRefundStatus(ctx context.Context, q *db.Queries, id int32, status, providerID, failure string) error {
+	if err := q.UpdateRefundStatus(ctx, db.UpdateRefundStatusParams{
+		ID:               id,
+		Status:           status,
+		ProviderRefundID: pgtype.Text{String: providerID, Valid: providerID != ""},
+		FailureReason:    pgtype.Text{String: failure, Valid: failure != ""},
+	}); err != nil {
+		return err
+	}
+
+	message := "Payment provider returned " + status
+	if providerID != "" {
+		message += " for " + providerID
+	}
+	if failure != "" {
+		message += ": " + failure
+	}
+	return q.AddRefundEvent(ctx, db.AddRefundEventParams{RefundID: id, Status: status, Message: message})
+}
+
+func GetOrderRefunds(ctx context.Context, conn db.Store, orderID int32) (OrderRefunds, error) {
+	q := db.New(conn)
+
+	refunds, err := q.ListOrderRefunds(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "List refunds error", "err", err)
+		return OrderRefunds{}, apperr.Internal("Error fetching refunds")
+	}
+
+	events, err := q.ListOrderRefundEvents(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "List refund events error", "err", err)
+		return OrderRefunds{}, apperr.Internal("Error fetching refunds")
+	}
+
+	refunded, err := q.GetRefundedTotal(ctx, orderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get refunded total error", "err", err)
+		return OrderRefunds{}, apperr.Internal("Error fetching refunds")
+	}
+
+	return OrderRefunds{
+		Refunds:       append(make([]db.Refund, 0), refunds...),
+		Events:        append(make([]db.RefundEvent, 0), events...),
+		RefundedTotal: centsToFloat(numericToCents(refunded)),
+	}, nil
+}
@@ -0,0 +1,354 @@
+package methods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+)
+
+// Return statuses
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnRefunded  = "refunded"
+	// ReturnRefundFailed is an approved return whose refund the provider
+	// rejected. Approving it again retries the refund.
+	ReturnRefundFailed = "refund_failed"
+)
+
+var (
+	ErrOrderNotFound      = apperr.NotFound("Order not found")
+	ErrReturnNotFound     = apperr.NotFound("Return not found")
+	ErrInvalidReturn      = apperr.Validation("Invalid return")
+	ErrReturnResolved     = apperr.Conflict("Return has already been resolved")
+	ErrOrderNotReturnable = apperr.Conflict("Only paid orders can be returned")
+)
+
+// returnableStatuses are the order statuses whose goods were paid for and may
+// have reached the customer
+var returnableStatuses = map[string]bool{
+	OrderPaid:       true,
+	OrderProcessing: true,
+	OrderShipped:    true,
+	OrderDelivered:  true,
+}
+
+type ReturnItemRequest struct {
+	OrderItemID int32  `json:"orderItemId"`
+	Quantity    int32  `json:"quantity"`
+	SizeName    string `json:"sizeName"`
+	Reason      string `json:"reason"`
+}
+
+type ReturnDetail struct {
+	Return db.Return       `json:"return"`
+	Items  []db.ReturnItem `json:"items"`
+}
+
+// RequestReturn opens a return for items on an order. The email must match the
+// order so customers can only open returns for their own orders.
+func RequestReturn(ctx context.Context, conn db.Store, orderID int32, email, reason string, items []ReturnItemRequest) (ReturnDetail, error) {
+	q := db.New(conn)
+
+	order, err := q.GetOrder(ctx, orderID)
+	if err != nil || !order.Email.Valid || !strings.EqualFold(order.Email.String, strings.TrimSpace(email)) {
+		return ReturnDetail{}, ErrOrderNotFound
+	}
+	// Unpaid, cancelled and refunded orders have nothing to send back
+	if !returnableStatuses[order.Status] {
+		return ReturnDetail{}, ErrOrderNotReturnable
+	}
+
+	if len(items) == 0 {
+		return ReturnDetail{}, ErrInvalidReturn.Withf("select at least one item")
+	}
+
+	orderItems, err := q.GetOrderItems(ctx, order.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching order")
+	}
+	ordered := make(map[int32]int32, len(orderItems))
+	for _, item := range orderItems {
+		ordered[item.ID] = item.Quantity
+	}
+
+	requested := make(map[int32]int32, len(items))
+	for _, item := range items {
+		quantity, ok := ordered[item.OrderItemID]
+		if !ok {
+			return ReturnDetail{}, ErrInvalidReturn.Withf("item %d is not on this order", item.OrderItemID)
+		}
+		if item.Quantity <= 0 {
+			return ReturnDetail{}, ErrInvalidReturn.Withf("quantity must be positive")
+		}
+
+		returned, err := q.GetReturnedQuantity(ctx, item.OrderItemID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Get returned quantity error", "err", err)
+			return ReturnDetail{}, apperr.Internal("Error occurred creating return")
+		}
+		requested[item.OrderItemID] += item.Quantity
+		if returned+requested[item.OrderItemID] > quantity {
+			return ReturnDetail{}, ErrInvalidReturn.Withf("item %d only has %d left to return", item.OrderItemID, quantity-returned)
+		}
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred creating return")
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := q.WithTx(tx)
+	ret, err := qtx.CreateReturn(ctx, db.CreateReturnParams{
+		OrderID: order.ID,
+		Email:   order.Email.String,
+		Reason:  reason,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred creating return")
+	}
+
+	for _, item := range items {
+		if err := qtx.AddReturnItem(ctx, db.AddReturnItemParams{
+			ReturnID:    ret.ID,
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			SizeName:    item.SizeName,
+			Reason:      pgtype.Text{String: item.Reason, Valid: item.Reason != ""},
+		}); err != nil {
+			slog.ErrorContext(ctx, "Add return item error", "err", err)
+			return ReturnDetail{}, apperr.Internal("Error occurred creating return")
+		}
+	}
+
+	if err := publish(ctx, qtx, events.ReturnRequestedData{
+		ReturnID: ret.ID,
+		OrderID:  order.ID,
+		Email:    ret.Email,
+		Reason:   reason,
+	}); err != nil {
+		return ReturnDetail{}, apperr.Internal("Error occurred creating return")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred creating return")
+	}
+
+	return GetReturn(ctx, conn, ret.ID)
+}
+
+func GetReturns(ctx context.Context, conn db.Store) ([]db.Return, error) {
+	q := db.New(conn)
+
+	returns, err := q.ListReturns(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List returns error", "err", err)
+		return []db.Return{}, apperr.Internal("Error fetching returns")
+	}
+
+	return append(make([]db.Return, 0), returns...), nil
+}
+
+func GetReturn(ctx context.Context, conn db.Store, id int32) (ReturnDetail, error) {
+	q := db.New(conn)
+
+	ret, err := q.GetReturn(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ReturnDetail{}, ErrReturnNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching return")
+	}
+
+	items, err := q.GetReturnItems(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get return items error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching return")
+	}
+
+	return ReturnDetail{
+		Return: ret,
+		Items:  append(make([]db.ReturnItem, 0), items...),
+	}, nil
+}
+
+// ApproveReturn puts the returned sizes back into stock and, when refund is
+// set, refunds the returned items plus their share of exclusive tax. The
+// return stays locked while it is approved and its refund recorded, so it is
+// only approved once. A refund the provider rejects leaves the return
+// refund_failed, and approving it again retries the refund without
+// restocking twice.
+func ApproveReturn(ctx context.Context, conn db.Store, provider payments.Provider, id int32, note string, restock, refund bool, actorID int32) (ReturnDetail, error) {
+	q := db.New(conn)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin approve return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred approving return")
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	ret, err := qtx.GetReturnForUpdate(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ReturnDetail{}, ErrReturnNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching return")
+	}
+	if ret.Status != ReturnRequested && ret.Status != ReturnRefundFailed {
+		return ReturnDetail{}, ErrReturnResolved
+	}
+
+	items, err := qtx.GetReturnItems(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get return items error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching return")
+	}
+	orderItems, err := qtx.GetOrderItems(ctx, ret.OrderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Get order items error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching order")
+	}
+	byID := make(map[int32]db.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		byID[item.ID] = item
+	}
+
+	var amount int64
+	for _, item := range items {
+		orderItem := byID[item.OrderItemID]
+		amount += numericToCents(orderItem.UnitPrice) * int64(item.Quantity)
+
+		if !restock || item.Restocked || !orderItem.ProductID.Valid || item.SizeName == "" {
+			continue
+		}
+		rows, err := qtx.IncrementProductStock(ctx, db.IncrementProductStockParams{
+			ProductID: orderItem.ProductID.Int32,
+			SizeName:  item.SizeName,
+			Quantity:  item.Quantity,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Restock error", "err", err)
+			return ReturnDetail{}, apperr.Internal("Error occurred approving return")
+		}
+		if rows == 0 {
+			// The size was removed from the product since the order was placed
+			continue
+		}
+		if err := qtx.MarkReturnItemRestocked(ctx, item.ID); err != nil {
+			slog.ErrorContext(ctx, "Mark restocked error", "err", err)
+			return ReturnDetail{}, apperr.Internal("Error occurred approving return")
+		}
+	}
+
+	// The refund is checked and recorded before anything is committed, so a
+	// refund that can't be made leaves the return as it was
+	var order db.Order
+	var pending db.Refund
+	if refund && amount > 0 {
+		var remaining int64
+		order, remaining, err = lockRefundableOrder(ctx, qtx, ret.OrderID)
+		if err != nil {
+			return ReturnDetail{}, err
+		}
+		if subtotal := numericToCents(order.Subtotal); !order.TaxInclusive && subtotal > 0 {
+			amount += numericToCents(order.TaxTotal) * amount / subtotal
+		}
+		// Rounding the tax share must not push the last return past the order total
+		if amount > remaining {
+			amount = remaining
+		}
+		if amount <= 0 {
+			return ReturnDetail{}, ErrRefundExceedsTotal
+		}
+
+		pending, err = createRefund(ctx, qtx, order.ID, amount, RefundInput{
+			ReturnID: id,
+			Reason:   "Return #" + fmt.Sprint(id) + ": " + ret.Reason,
+			ActorID:  actorID,
+		})
+		if err != nil {
+			return ReturnDetail{}, err
+		}
+	}
+
+	if err := qtx.UpdateReturnStatus(ctx, db.UpdateReturnStatusParams{
+		ID:     id,
+		Status: ReturnApproved,
+		Note:   pgtype.Text{String: note, Valid: note != ""},
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update return status error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred approving return")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit approve return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred approving return")
+	}
+
+	if pending.ID != 0 {
+		if _, err := sendRefund(ctx, conn, provider, order, pending, actorID); err != nil {
+			return ReturnDetail{}, err
+		}
+	}
+
+	return GetReturn(ctx, conn, id)
+}
+
+func RejectReturn(ctx context.Context, conn db.Store, id int32, note string) (ReturnDetail, error) {
+	q := db.New(conn)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin reject return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred rejecting return")
+	}
+	defer tx.Rollback(ctx)
+	qtx := q.WithTx(tx)
+
+	ret, err := qtx.GetReturnForUpdate(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ReturnDetail{}, ErrReturnNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Get return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error fetching return")
+	}
+	if ret.Status != ReturnRequested {
+		return ReturnDetail{}, ErrReturnResolved
+	}
+
+	if err := qtx.UpdateReturnStatus(ctx, db.UpdateReturnStatusParams{
+		ID:     id,
+		Status: ReturnRejected,
+		Note:   pgtype.Text{String: note, Valid: note != ""},
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update return status error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred rejecting return")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit reject return error", "err", err)
+		return ReturnDetail{}, apperr.Internal("Error occurred rejecting return")
+	}
+
+	return GetReturn(ctx, conn, id)
+}
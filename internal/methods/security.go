@@ -0,0 +1,71 @@
+package methods
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Security event types
+const (
+	SecurityLoginSucceeded  = "login_succeeded"
+	SecurityLoginFailed     = "login_failed"
+	SecurityAccountLocked   = "account_locked"
+	SecurityIPLocked        = "ip_locked"
+	SecurityPasswordChanged = "password_changed"
+
+	SecurityMFAEnabled                  = "mfa_enabled"
+	SecurityMFAFailed                   = "mfa_failed"
+	SecurityMFAReset                    = "mfa_reset"
+	SecurityMFARecoveryCodeUsed         = "mfa_recovery_code_used"
+	SecurityMFARecoveryCodesRegenerated = "mfa_recovery_codes_regenerated"
+
+	SecurityAdminDeactivated   = "admin_deactivated"
+	SecurityRefreshTokenReused = "refresh_token_reused"
+)
+
+// SecurityEvent is one entry for the admin security log
+type SecurityEvent struct {
+	Type      string
+	UserID    int32 // zero when the email matched no user
+	Email     string
+	IP        string
+	UserAgent string
+	Detail    string
+}
+
+// RecordSecurityEvent saves e to the security log and writes it to the
+// application log as well
+func RecordSecurityEvent(ctx context.Context, conn db.Store, e SecurityEvent) error {
+	slog.InfoContext(ctx, "Security event", "type", e.Type, "user_id", e.UserID, "email", e.Email, "ip", e.IP, "detail", e.Detail)
+
+	err := db.New(conn).AddSecurityEvent(ctx, db.AddSecurityEventParams{
+		EventType: e.Type,
+		UserID:    pgtype.Int4{Int32: e.UserID, Valid: e.UserID != 0},
+		Email:     e.Email,
+		Ip:        e.IP,
+		UserAgent: e.UserAgent,
+		Detail:    e.Detail,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error recording security event", "type", e.Type, "err", err)
+	}
+	return err
+}
+
+// GetSecurityEvents returns the most recent security events, only the
+// email's when it isn't empty
+func GetSecurityEvents(ctx context.Context, conn db.Store, email string, limit int) ([]db.SecurityEvent, error) {
+	q := db.New(conn)
+
+	list, err := q.ListSecurityEvents(ctx, db.ListSecurityEventsParams{Email: email, MaxRows: int32(limit)})
+	if err != nil {
+		slog.ErrorContext(ctx, "List security events error", "err", err)
+		return []db.SecurityEvent{}, apperr.Internal("Error fetching security events")
+	}
+
+	return append(make([]db.SecurityEvent, 0), list...), nil
+}
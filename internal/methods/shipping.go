@@ -0,0 +1,397 @@
+package methods
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/shipping"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tax"
+)
+
+type ShippingZone struct {
+	ID      int               `json:"id"`
+	Name    string            `json:"name"`
+	Regions []shipping.Region `json:"regions"`
+	Methods []ShippingMethod  `json:"methods"`
+}
+
+type ShippingTier struct {
+	// Min is grams for weight methods and the cart subtotal for price methods
+	Min  float64 `json:"min"`
+	Rate float64 `json:"rate"`
+}
+
+type ShippingMethod struct {
+	ID       int            `json:"id"`
+	ZoneID   int            `json:"zoneId"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Rate     float64        `json:"rate"`
+	FreeOver float64        `json:"freeOver"`
+	MinDays  int            `json:"minDays"`
+	MaxDays  int            `json:"maxDays"`
+	Active   bool           `json:"active"`
+	Tiers    []ShippingTier `json:"tiers"`
+}
+
+type ShippingRate struct {
+	MethodID int     `json:"methodId"`
+	Zone     string  `json:"zone"`
+	Name     string  `json:"name"`
+	Amount   float64 `json:"amount"`
+	MinDays  int     `json:"minDays"`
+	MaxDays  int     `json:"maxDays"`
+}
+
+func GetShippingZones(ctx context.Context, conn db.Store) ([]ShippingZone, error) {
+	zones, methods, err := loadShipping(ctx, conn)
+	if err != nil {
+		return []ShippingZone{}, err
+	}
+
+	z := make([]ShippingZone, len(zones))
+	for i, zone := range zones {
+		z[i] = ShippingZone{
+			ID:      int(zone.ID),
+			Name:    zone.Name,
+			Regions: append(make([]shipping.Region, 0), zone.Regions...),
+			Methods: make([]ShippingMethod, 0),
+		}
+		for _, m := range methods {
+			if m.ZoneID == zone.ID {
+				z[i].Methods = append(z[i].Methods, toShippingMethod(m))
+			}
+		}
+	}
+	return z, nil
+}
+
+func CreateShippingZone(ctx context.Context, conn db.Store, name string, regions []shipping.Region) (ShippingZone, error) {
+	if name == "" || len(regions) == 0 {
+		return ShippingZone{}, apperr.Validation("A name and at least one region are required")
+	}
+	for _, r := range regions {
+		if len(r.Country) != 2 {
+			return ShippingZone{}, apperr.Invalid("regions", "Regions must use two letter country codes")
+		}
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin shipping zone error", "err", err)
+		return ShippingZone{}, apperr.Internal("Error occurred creating shipping zone")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	zone, err := q.CreateShippingZone(ctx, name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Create shipping zone error", "err", err)
+		return ShippingZone{}, apperr.Internal("Error occurred creating shipping zone")
+	}
+
+	for i := range regions {
+		regions[i].Country = strings.ToUpper(regions[i].Country)
+		regions[i].State = strings.ToUpper(regions[i].State)
+		if err := q.AddShippingZoneRegion(ctx, db.AddShippingZoneRegionParams{
+			ZoneID:  zone.ID,
+			Country: regions[i].Country,
+			State:   regions[i].State,
+		}); err != nil {
+			slog.ErrorContext(ctx, "Add shipping zone region error", "err", err)
+			return ShippingZone{}, apperr.Internal("Error occurred creating shipping zone")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit shipping zone error", "err", err)
+		return ShippingZone{}, apperr.Internal("Error occurred creating shipping zone")
+	}
+
+	return ShippingZone{
+		ID:      int(zone.ID),
+		Name:    zone.Name,
+		Regions: regions,
+		Methods: make([]ShippingMethod, 0),
+	}, nil
+}
+
+func DeleteShippingZone(ctx context.Context, conn db.Store, id int) error {
+	q := db.New(conn)
+
+	if err := q.DeleteShippingZone(ctx, int32(id)); err != nil {
+		slog.ErrorContext(ctx, "Delete shipping zone error", "err", err)
+		return apperr.Internal("Error occurred deleting shipping zone")
+	}
+	return nil
+}
+
+func CreateShippingMethod(ctx context.Context, conn db.Store, m ShippingMethod) (ShippingMethod, error) {
+	if m.Name == "" {
+		return ShippingMethod{}, apperr.Invalid("name", "A name is required")
+	}
+	if !shipping.ValidType(m.Type) {
+		return ShippingMethod{}, apperr.Invalid("type", "Type must be flat, weight or price")
+	}
+	if m.Type != shipping.TypeFlat && len(m.Tiers) == 0 {
+		return ShippingMethod{}, apperr.Invalid("tiers", "Weight and price methods need at least one tier")
+	}
+	if m.Rate < 0 || m.FreeOver < 0 {
+		return ShippingMethod{}, apperr.Validation("Rates can not be negative")
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Begin shipping method error", "err", err)
+		return ShippingMethod{}, apperr.Internal("Error occurred creating shipping method")
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(conn).WithTx(tx)
+
+	if _, err := q.GetShippingZone(ctx, int32(m.ZoneID)); err != nil {
+		slog.ErrorContext(ctx, "Get shipping zone error", "err", err)
+		return ShippingMethod{}, apperr.NotFound("Shipping zone not found")
+	}
+
+	created, err := q.CreateShippingMethod(ctx, db.CreateShippingMethodParams{
+		ZoneID:   int32(m.ZoneID),
+		Name:     m.Name,
+		Type:     m.Type,
+		Rate:     floatToNumeric(m.Rate),
+		FreeOver: optionalNumeric(m.FreeOver),
+		MinDays:  pgtype.Int4{Int32: int32(m.MinDays), Valid: m.MinDays > 0},
+		MaxDays:  pgtype.Int4{Int32: int32(m.MaxDays), Valid: m.MaxDays > 0},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create shipping method error", "err", err)
+		return ShippingMethod{}, apperr.Internal("Error occurred creating shipping method")
+	}
+
+	for _, t := range m.Tiers {
+		if err := q.AddShippingMethodTier(ctx, db.AddShippingMethodTierParams{
+			MethodID: created.ID,
+			MinValue: floatToNumeric(t.Min),
+			Rate:     floatToNumeric(t.Rate),
+		}); err != nil {
+			slog.ErrorContext(ctx, "Add shipping tier error", "err", err)
+			return ShippingMethod{}, apperr.Internal("Error occurred creating shipping method")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "Commit shipping method error", "err", err)
+		return ShippingMethod{}, apperr.Internal("Error occurred creating shipping method")
+	}
+
+	m.ID = int(created.ID)
+	m.Active = created.Active
+	if m.Tiers == nil {
+		m.Tiers = make([]ShippingTier, 0)
+	}
+	return m, nil
+}
+
+func SetShippingMethodActive(ctx context.Context, conn db.Store, id int, active bool) error {
+	q := db.New(conn)
+
+	if err := q.SetShippingMethodActive(ctx, db.SetShippingMethodActiveParams{
+		ID:     int32(id),
+		Active: active,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update shipping method error", "err", err)
+		return apperr.Internal("Error occurred updating shipping method")
+	}
+	return nil
+}
+
+func DeleteShippingMethod(ctx context.Context, conn db.Store, id int) error {
+	q := db.New(conn)
+
+	if err := q.DeleteShippingMethod(ctx, int32(id)); err != nil {
+		slog.ErrorContext(ctx, "Delete shipping method error", "err", err)
+		return apperr.Internal("Error occurred deleting shipping method")
+	}
+	return nil
+}
+
+// QuoteShipping returns every shipping option for the cart and destination
+func QuoteShipping(ctx context.Context, conn db.Store, id uuid.UUID, addr tax.Address) ([]ShippingRate, error) {
+	items, err := GetItems(ctx, conn, id)
+	if err != nil {
+		return []ShippingRate{}, err
+	}
+	return quoteItems(ctx, conn, items, addr)
+}
+
+// ApplyShipping adds the chosen shipping method to a cart summary
+func ApplyShipping(ctx context.Context, conn db.Store, s CartSummary, methodID int) (CartSummary, error) {
+	rates, err := quoteItems(ctx, conn, s.Items, s.Address)
+	if err != nil {
+		return s, err
+	}
+
+	for _, rate := range rates {
+		if rate.MethodID == methodID {
+			s.Shipping = &rate
+			s.ShippingTotal = rate.Amount
+			s.Total = centsToFloat(floatToCents(s.Total) + floatToCents(rate.Amount))
+			return s, nil
+		}
+	}
+	return s, apperr.Invalid("shippingMethodID", "Shipping method is not available for this address")
+}
+
+// ShippingCountries lists every country covered by a shipping zone
+func ShippingCountries(ctx context.Context, conn db.Store) ([]string, error) {
+	q := db.New(conn)
+
+	regions, err := q.ListShippingZoneRegions(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List shipping regions error", "err", err)
+		return []string{}, apperr.Internal("Error fetching shipping zones")
+	}
+
+	seen := map[string]bool{}
+	countries := []string{}
+	for _, r := range regions {
+		if !seen[r.Country] {
+			seen[r.Country] = true
+			countries = append(countries, r.Country)
+		}
+	}
+	sort.Strings(countries)
+	return countries, nil
+}
+
+func quoteItems(ctx context.Context, conn db.Store, items []db.GetCartItemsRow, addr tax.Address) ([]ShippingRate, error) {
+	zones, methods, err := loadShipping(ctx, conn)
+	if err != nil {
+		return []ShippingRate{}, err
+	}
+
+	zone, ok := shipping.MatchZone(zones, shipping.Destination(addr))
+	if !ok {
+		return []ShippingRate{}, nil
+	}
+
+	var cart shipping.Cart
+	for _, item := range items {
+		cart.Subtotal += numericToCents(item.Price) * int64(item.Quantity)
+		cart.WeightGrams += int64(item.WeightGrams) * int64(item.Quantity)
+	}
+
+	quoted := shipping.Quote(zone, methods, cart)
+	rates := make([]ShippingRate, len(quoted))
+	for i, r := range quoted {
+		rates[i] = ShippingRate{
+			MethodID: int(r.MethodID),
+			Zone:     r.Zone,
+			Name:     r.Name,
+			Amount:   centsToFloat(r.Amount),
+			MinDays:  int(r.MinDays),
+			MaxDays:  int(r.MaxDays),
+		}
+	}
+	return rates, nil
+}
+
+// loadShipping reads every zone and method with their regions and tiers
+func loadShipping(ctx context.Context, conn db.Store) ([]shipping.Zone, []shipping.Method, error) {
+	q := db.New(conn)
+
+	zones, err := q.ListShippingZones(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List shipping zones error", "err", err)
+		return nil, nil, apperr.Internal("Error fetching shipping zones")
+	}
+
+	regions, err := q.ListShippingZoneRegions(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List shipping regions error", "err", err)
+		return nil, nil, apperr.Internal("Error fetching shipping zones")
+	}
+
+	methods, err := q.ListShippingMethods(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List shipping methods error", "err", err)
+		return nil, nil, apperr.Internal("Error fetching shipping methods")
+	}
+
+	tiers, err := q.ListShippingMethodTiers(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List shipping tiers error", "err", err)
+		return nil, nil, apperr.Internal("Error fetching shipping methods")
+	}
+
+	z := make([]shipping.Zone, len(zones))
+	for i, zone := range zones {
+		z[i] = shipping.Zone{ID: zone.ID, Name: zone.Name}
+		for _, r := range regions {
+			if r.ZoneID == zone.ID {
+				z[i].Regions = append(z[i].Regions, shipping.Region{Country: r.Country, State: r.State})
+			}
+		}
+	}
+
+	m := make([]shipping.Method, len(methods))
+	for i, method := range methods {
+		m[i] = shipping.Method{
+			ID:       method.ID,
+			ZoneID:   method.ZoneID,
+			Name:     method.Name,
+			Type:     method.Type,
+			Rate:     numericToCents(method.Rate),
+			FreeOver: numericToCents(method.FreeOver),
+			MinDays:  method.MinDays.Int32,
+			MaxDays:  method.MaxDays.Int32,
+			Active:   method.Active,
+		}
+		for _, t := range tiers {
+			if t.MethodID != method.ID {
+				continue
+			}
+			// weight tiers are stored in grams, price tiers in currency units
+			min := numericToCents(t.MinValue)
+			if method.Type == shipping.TypeWeight {
+				f, _ := t.MinValue.Float64Value()
+				min = int64(math.Round(f.Float64))
+			}
+			m[i].Tiers = append(m[i].Tiers, shipping.Tier{Min: min, Rate: numericToCents(t.Rate)})
+		}
+	}
+
+	return z, m, nil
+}
+
+func toShippingMethod(m shipping.Method) ShippingMethod {
+	tiers := make([]ShippingTier, len(m.Tiers))
+	for i, t := range m.Tiers {
+		min := centsToFloat(t.Min)
+		if m.Type == shipping.TypeWeight {
+			min = float64(t.Min)
+		}
+		tiers[i] = ShippingTier{Min: min, Rate: centsToFloat(t.Rate)}
+	}
+
+	return ShippingMethod{
+		ID:       int(m.ID),
+		ZoneID:   int(m.ZoneID),
+		Name:     m.Name,
+		Type:     m.Type,
+		Rate:     centsToFloat(m.Rate),
+		FreeOver: centsToFloat(m.FreeOver),
+		MinDays:  int(m.MinDays),
+		MaxDays:  int(m.MaxDays),
+		Active:   m.Active,
+		Tiers:    tiers,
+	}
+}
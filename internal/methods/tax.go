@@ -0,0 +1,233 @@
+package methods
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tax"
+)
+
+type TaxRate struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Country      string  `json:"country"`
+	State        string  `json:"state"`
+	PostalPrefix string  `json:"postalPrefix"`
+	TaxCategory  string  `json:"taxCategory"`
+	Rate         float64 `json:"rate"`
+}
+
+type TaxLine struct {
+	Name          string  `json:"name"`
+	Jurisdiction  string  `json:"jurisdiction"`
+	Rate          float64 `json:"rate"`
+	TaxableAmount float64 `json:"taxableAmount"`
+	Amount        float64 `json:"amount"`
+}
+
+type CartSummary struct {
+	Items         []db.GetCartItemsRow `json:"items"`
+	Address       tax.Address          `json:"address"`
+	Subtotal      float64              `json:"subtotal"`
+	TaxLines      []TaxLine            `json:"taxLines"`
+	TaxTotal      float64              `json:"taxTotal"`
+	Shipping      *ShippingRate        `json:"shipping,omitempty"`
+	ShippingTotal float64              `json:"shippingTotal"`
+	Total         float64              `json:"total"`
+	TaxInclusive  bool                 `json:"taxInclusive"`
+	TaxProvider   string               `json:"taxProvider"`
+	// TaxDeferred is true when tax is calculated by Stripe during checkout
+	TaxDeferred bool `json:"taxDeferred"`
+}
+
+// TaxConfig picks how tax is calculated
+type TaxConfig struct {
+	Provider  string // tax.ProviderRules or tax.ProviderStripe
+	Inclusive bool   // product prices already include tax
+}
+
+// NewTaxCalculator builds the configured calculator for a destination
+func NewTaxCalculator(ctx context.Context, conn db.Store, c TaxConfig, addr tax.Address) (tax.Calculator, error) {
+	if c.Provider == tax.ProviderStripe {
+		return &tax.StripeCalculator{Inclusive: c.Inclusive}, nil
+	}
+
+	q := db.New(conn)
+
+	rates, err := q.ListTaxRatesByCountry(ctx, strings.ToUpper(addr.Country))
+	if err != nil {
+		slog.ErrorContext(ctx, "List tax rates error", "err", err)
+		return nil, apperr.Internal("Error loading tax rates")
+	}
+
+	rules := make([]tax.Rule, len(rates))
+	for i, rate := range rates {
+		rules[i] = taxRule(rate)
+	}
+
+	return tax.NewRuleCalculator(rules, c.Inclusive), nil
+}
+
+// GetCartSummary totals the cart and calculates tax for the destination address
+func GetCartSummary(ctx context.Context, conn db.Store, c TaxConfig, id uuid.UUID, addr tax.Address) (CartSummary, error) {
+	items, err := GetItems(ctx, conn, id)
+	if err != nil {
+		return CartSummary{}, err
+	}
+
+	calc, err := NewTaxCalculator(ctx, conn, c, addr)
+	if err != nil {
+		return CartSummary{}, err
+	}
+
+	req := tax.Request{Address: addr}
+	var subtotal int64
+	for _, item := range items {
+		unit := numericToCents(item.Price)
+		subtotal += unit * int64(item.Quantity)
+		req.Items = append(req.Items, tax.Item{
+			ProductID:  item.ProductID,
+			Name:       item.Name,
+			Category:   item.TaxCategory,
+			UnitAmount: unit,
+			Quantity:   int64(item.Quantity),
+		})
+	}
+
+	res, err := calc.Calculate(ctx, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Tax calculation error", "err", err)
+		return CartSummary{}, apperr.Internal("Error calculating tax")
+	}
+
+	total := subtotal
+	if !res.Inclusive {
+		total += res.Total
+	}
+
+	lines := make([]TaxLine, len(res.Lines))
+	for i, l := range res.Lines {
+		lines[i] = TaxLine{
+			Name:          l.Name,
+			Jurisdiction:  l.Jurisdiction,
+			Rate:          l.Rate,
+			TaxableAmount: centsToFloat(l.TaxableAmount),
+			Amount:        centsToFloat(l.Amount),
+		}
+	}
+
+	return CartSummary{
+		Items:        items,
+		Address:      addr,
+		Subtotal:     centsToFloat(subtotal),
+		TaxLines:     lines,
+		TaxTotal:     centsToFloat(res.Total),
+		Total:        centsToFloat(total),
+		TaxInclusive: res.Inclusive,
+		TaxProvider:  res.Provider,
+		TaxDeferred:  res.Deferred,
+	}, nil
+}
+
+func GetTaxRates(ctx context.Context, conn db.Store) ([]TaxRate, error) {
+	q := db.New(conn)
+
+	rates, err := q.ListTaxRates(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List tax rates error", "err", err)
+		return []TaxRate{}, apperr.Internal("Error fetching tax rates")
+	}
+
+	t := make([]TaxRate, len(rates))
+	for i, rate := range rates {
+		t[i] = toTaxRate(rate)
+	}
+	return t, nil
+}
+
+func CreateTaxRate(ctx context.Context, conn db.Store, t TaxRate) (TaxRate, error) {
+	q := db.New(conn)
+
+	if t.Name == "" || len(t.Country) != 2 {
+		return TaxRate{}, apperr.Validation("A name and two letter country code are required")
+	}
+	if t.Rate < 0 || t.Rate > 100 {
+		return TaxRate{}, apperr.Invalid("rate", "Rate must be a percentage between 0 and 100")
+	}
+
+	var rate pgtype.Numeric
+	if err := rate.Scan(strconv.FormatFloat(t.Rate, 'f', 4, 64)); err != nil {
+		slog.ErrorContext(ctx, "Error parsing tax rate", "rate", t.Rate, "err", err)
+		return TaxRate{}, apperr.Internal("Error occurred creating tax rate")
+	}
+
+	created, err := q.CreateTaxRate(ctx, db.CreateTaxRateParams{
+		Name:         t.Name,
+		Country:      strings.ToUpper(t.Country),
+		State:        strings.ToUpper(t.State),
+		PostalPrefix: strings.ToUpper(t.PostalPrefix),
+		TaxCategory:  t.TaxCategory,
+		Rate:         rate,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create tax rate error", "err", err)
+		return TaxRate{}, apperr.Internal("Error occurred creating tax rate")
+	}
+
+	return toTaxRate(created), nil
+}
+
+func DeleteTaxRate(ctx context.Context, conn db.Store, id int) error {
+	q := db.New(conn)
+
+	if err := q.DeleteTaxRate(ctx, int32(id)); err != nil {
+		slog.ErrorContext(ctx, "Delete tax rate error", "err", err)
+		return apperr.Internal("Error occurred deleting tax rate")
+	}
+	return nil
+}
+
+func SetProductTaxCategory(ctx context.Context, conn db.Store, id int32, category string) error {
+	q := db.New(conn)
+
+	if err := q.UpdateProductTaxCategory(ctx, db.UpdateProductTaxCategoryParams{
+		ID:          id,
+		TaxCategory: category,
+	}); err != nil {
+		slog.ErrorContext(ctx, "Update tax category error", "err", err)
+		return apperr.Internal("Error occurred updating product")
+	}
+	return nil
+}
+
+func taxRule(rate db.TaxRate) tax.Rule {
+	r, _ := rate.Rate.Float64Value()
+	return tax.Rule{
+		ID:           rate.ID,
+		Name:         rate.Name,
+		Country:      rate.Country,
+		State:        rate.State,
+		PostalPrefix: rate.PostalPrefix,
+		Category:     rate.TaxCategory,
+		Rate:         r.Float64,
+	}
+}
+
+func toTaxRate(rate db.TaxRate) TaxRate {
+	r, _ := rate.Rate.Float64Value()
+	return TaxRate{
+		ID:           int(rate.ID),
+		Name:         rate.Name,
+		Country:      rate.Country,
+		State:        rate.State,
+		PostalPrefix: rate.PostalPrefix,
+		TaxCategory:  rate.TaxCategory,
+		Rate:         r.Float64,
+	}
+}
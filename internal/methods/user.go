@@ -2,36 +2,109 @@ package methods
 
 import (
 	"context"
-	"log"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
 	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func Login(ctx context.Context, conn *pgx.Conn, email, password string) (db.User, error) {
+var (
+	ErrInvalidLogin  = apperr.Unauthorized("Invalid email or password")
+	ErrWrongPassword = apperr.Invalid("currentPassword", "Current password is incorrect")
+)
+
+// dummyHash is checked when the email is unknown so a missing account takes
+// as long to turn down as a wrong password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash stored in users.password_hash
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", apperr.Invalid("password", "password must be at most 72 bytes")
+	}
+	return string(hash), err
+}
+
+// checkPassword compares password with a stored hash. Users created before
+// passwords were hashed have the password itself stored; those match in
+// constant time and report legacy so the caller can hash it.
+func checkPassword(stored, password string) (ok, legacy bool) {
+	if !strings.HasPrefix(stored, "$2") {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+}
+
+// Login returns the user with the email and password, or ErrInvalidLogin
+func Login(ctx context.Context, conn db.Store, email, password string) (db.User, error) {
 	q := db.New(conn)
 
-	user, err := q.GetUserByEmailAndPassword(ctx, db.GetUserByEmailAndPasswordParams{
-		Email: email,
-		PasswordHash: 	password,
-	})
+	user, err := q.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return db.User{}, ErrInvalidLogin
+	}
 	if err != nil {
-		log.Println("LOGIN ERROR: ", err.Error())		
+		slog.ErrorContext(ctx, "Login error", "err", err)
 		return db.User{}, err
 	}
 
+	ok, legacy := checkPassword(user.PasswordHash, password)
+	if !ok {
+		return db.User{}, ErrInvalidLogin
+	}
+	if legacy {
+		if err := setPassword(ctx, q, user.ID, password); err != nil {
+			slog.WarnContext(ctx, "Error hashing legacy password", "user_id", user.ID, "err", err)
+		}
+	}
+
 	return user, nil
 }
 
+// ChangePassword replaces the user's password after checking the current one
+func ChangePassword(ctx context.Context, conn db.Store, id int32, current, password string) error {
+	q := db.New(conn)
+
+	user, err := q.GetUser(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperr.NotFound("User not found")
+	}
+	if err != nil {
+		return err
+	}
+	if ok, _ := checkPassword(user.PasswordHash, current); !ok {
+		return ErrWrongPassword
+	}
+	return setPassword(ctx, q, id, password)
+}
 
+func setPassword(ctx context.Context, q *db.Queries, id int32, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return q.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{ID: id, PasswordHash: hash})
+}
 
-func CreateUser(ctx context.Context, conn *pgx.Conn, email, password string) (db.User, error) {
+func CreateUser(ctx context.Context, conn db.Store, email, password string) (db.User, error) {
 	q := db.New(conn)
 
+	hash, err := HashPassword(password)
+	if err != nil {
+		return db.User{}, err
+	}
+
 	user, err := q.CreateUser(ctx, db.CreateUserParams{
 		Email:        email,
-		PasswordHash: password,
+		PasswordHash: hash,
 		IsAdmin:      pgtype.Bool{Bool: true, Valid: true},
 	})
 
@@ -42,7 +115,7 @@ func CreateUser(ctx context.Context, conn *pgx.Conn, email, password string) (db
 	return user, nil
 }
 
-func CheckUserAdmin(ctx context.Context, conn *pgx.Conn, id int32) (bool, error) {
+func CheckUserAdmin(ctx context.Context, conn db.Store, id int32) (bool, error) {
 
 	q := db.New(conn)
 
@@ -57,7 +130,7 @@ func CheckUserAdmin(ctx context.Context, conn *pgx.Conn, id int32) (bool, error)
 	return isAdmin, nil
 }
 
-func GetUser(ctx context.Context, conn *pgx.Conn, id int32) (db.User, error) {
+func GetUser(ctx context.Context, conn db.Store, id int32) (db.User, error) {
 	q := db.New(conn)
 	user, err := q.GetUser(ctx, id)
 	if err != nil {
@@ -70,6 +143,31 @@ func GetUser(ctx context.Context, conn *pgx.Conn, id int32) (db.User, error) {
 	return user, nil
 }
 
-func DeleteUser(ctx context.Context, conn *pgx.Conn, id int32) error {
+func DeleteUser(ctx context.Context, conn db.Store, id int32) error {
 	return nil
 }
+
+// DeactivateUser takes away the user's admin role and revokes their
+// sessions, so their tokens stop working on the next request
+func DeactivateUser(ctx context.Context, conn db.Store, id int32) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := db.New(tx)
+
+	n, err := q.DeactivateUser(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Deactivate user error", "err", err)
+		return err
+	}
+	if n == 0 {
+		return apperr.NotFound("Admin user not found")
+	}
+	if _, err := q.RevokeUserSessions(ctx, pgtype.Int4{Int32: id, Valid: true}); err != nil {
+		slog.ErrorContext(ctx, "Revoke user sessions error", "err", err)
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,232 @@
+package methods
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/webhooks"
+)
+
+var (
+	ErrWebhookNotFound         = apperr.NotFound("Webhook not found")
+	ErrWebhookDeliveryNotFound = apperr.NotFound("Webhook delivery not found")
+	ErrInvalidWebhook          = apperr.Validation("Invalid webhook")
+)
+
+// Webhook is an endpoint as shown to admins. The secret is only returned
+// when the endpoint is created.
+type Webhook struct {
+	db.WebhookEndpoint
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookInput struct {
+	URL         string
+	Description string
+	EventTypes  []string
+	Secret      string // optional, generated when empty
+}
+
+// WebhookUpdate changes the fields that are set
+type WebhookUpdate struct {
+	URL         string
+	Description *string
+	EventTypes  []string
+	Enabled     *bool
+}
+
+type WebhookDetail struct {
+	Webhook    Webhook              `json:"webhook"`
+	Deliveries []db.WebhookDelivery `json:"deliveries"`
+}
+
+type WebhookDeliveryDetail struct {
+	Delivery db.WebhookDelivery          `json:"delivery"`
+	Attempts []db.WebhookDeliveryAttempt `json:"attempts"`
+}
+
+func CreateWebhook(ctx context.Context, conn db.Store, in WebhookInput) (Webhook, error) {
+	if err := validateWebhook(in.URL, in.EventTypes); err != nil {
+		return Webhook{}, err
+	}
+
+	secret := in.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhooks.NewSecret(); err != nil {
+			slog.ErrorContext(ctx, "Webhook secret error", "err", err)
+			return Webhook{}, apperr.Internal("Error occurred creating webhook")
+		}
+	}
+
+	endpoint, err := db.New(conn).CreateWebhookEndpoint(ctx, db.CreateWebhookEndpointParams{
+		Url:         in.URL,
+		Description: pgtype.Text{String: in.Description, Valid: in.Description != ""},
+		EventTypes:  uniqueStrings(in.EventTypes),
+		Secret:      secret,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Create webhook error", "err", err)
+		return Webhook{}, apperr.Internal("Error occurred creating webhook")
+	}
+
+	return Webhook{WebhookEndpoint: endpoint, Secret: secret}, nil
+}
+
+func GetWebhooks(ctx context.Context, conn db.Store) ([]Webhook, error) {
+	endpoints, err := db.New(conn).ListWebhookEndpoints(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "List webhooks error", "err", err)
+		return []Webhook{}, apperr.Internal("Error fetching webhooks")
+	}
+
+	list := make([]Webhook, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		list = append(list, Webhook{WebhookEndpoint: endpoint})
+	}
+	return list, nil
+}
+
+// GetWebhook returns an endpoint with its most recent deliveries
+func GetWebhook(ctx context.Context, conn db.Store, id int32, limit int) (WebhookDetail, error) {
+	q := db.New(conn)
+
+	endpoint, err := q.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		return WebhookDetail{}, ErrWebhookNotFound
+	}
+
+	deliveries, err := q.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		EndpointID: id,
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "List webhook deliveries error", "err", err)
+		return WebhookDetail{}, apperr.Internal("Error fetching webhook")
+	}
+
+	return WebhookDetail{
+		Webhook:    Webhook{WebhookEndpoint: endpoint},
+		Deliveries: append(make([]db.WebhookDelivery, 0), deliveries...),
+	}, nil
+}
+
+// UpdateWebhook edits an endpoint. Enabling a disabled endpoint clears its
+// failure count, and its pending deliveries are sent again.
+func UpdateWebhook(ctx context.Context, conn db.Store, id int32, in WebhookUpdate) (Webhook, error) {
+	q := db.New(conn)
+
+	endpoint, err := q.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		return Webhook{}, ErrWebhookNotFound
+	}
+
+	params := db.UpdateWebhookEndpointParams{
+		ID:          id,
+		Url:         endpoint.Url,
+		Description: endpoint.Description,
+		EventTypes:  endpoint.EventTypes,
+		Enabled:     endpoint.Enabled,
+	}
+	if in.URL != "" {
+		params.Url = in.URL
+	}
+	if in.Description != nil {
+		params.Description = pgtype.Text{String: *in.Description, Valid: *in.Description != ""}
+	}
+	if len(in.EventTypes) > 0 {
+		params.EventTypes = uniqueStrings(in.EventTypes)
+	}
+	if in.Enabled != nil {
+		params.Enabled = *in.Enabled
+	}
+	if err := validateWebhook(params.Url, params.EventTypes); err != nil {
+		return Webhook{}, err
+	}
+
+	updated, err := q.UpdateWebhookEndpoint(ctx, params)
+	if err != nil {
+		slog.ErrorContext(ctx, "Update webhook error", "err", err)
+		return Webhook{}, apperr.Internal("Error occurred updating webhook")
+	}
+	return Webhook{WebhookEndpoint: updated}, nil
+}
+
+func DeleteWebhook(ctx context.Context, conn db.Store, id int32) error {
+	rows, err := db.New(conn).DeleteWebhookEndpoint(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Delete webhook error", "err", err)
+		return apperr.Internal("Error occurred deleting webhook")
+	}
+	if rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// GetWebhookDelivery returns a delivery with every attempt made to send it
+func GetWebhookDelivery(ctx context.Context, conn db.Store, id int32) (WebhookDeliveryDetail, error) {
+	q := db.New(conn)
+
+	delivery, err := q.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return WebhookDeliveryDetail{}, ErrWebhookDeliveryNotFound
+	}
+
+	attempts, err := q.ListWebhookDeliveryAttempts(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "List webhook attempts error", "err", err)
+		return WebhookDeliveryDetail{}, apperr.Internal("Error fetching webhook delivery")
+	}
+
+	return WebhookDeliveryDetail{
+		Delivery: delivery,
+		Attempts: append(make([]db.WebhookDeliveryAttempt, 0), attempts...),
+	}, nil
+}
+
+// RedeliverWebhook queues a delivery to be sent again right away with a fresh
+// set of attempts, whatever its current status
+func RedeliverWebhook(ctx context.Context, conn db.Store, id int32) (WebhookDeliveryDetail, error) {
+	rows, err := db.New(conn).RedeliverWebhookDelivery(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Redeliver webhook error", "err", err)
+		return WebhookDeliveryDetail{}, apperr.Internal("Error occurred redelivering webhook")
+	}
+	if rows == 0 {
+		return WebhookDeliveryDetail{}, ErrWebhookDeliveryNotFound
+	}
+	return GetWebhookDelivery(ctx, conn, id)
+}
+
+func validateWebhook(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhook.Withf("url must be an absolute http or https URL")
+	}
+	if len(eventTypes) == 0 {
+		return ErrInvalidWebhook.Withf("subscribe to at least one event type")
+	}
+	for _, t := range eventTypes {
+		if !webhooks.ValidEventType(t) {
+			return ErrInvalidWebhook.Withf("unknown event type %q", t)
+		}
+	}
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
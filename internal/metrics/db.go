@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "db_query_duration_seconds",
+	Help:      "Database query latency by sqlc query name.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"query", "status"})
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	name  string
+	start time.Time
+}
+
+// QueryTracer times every query. Set it as the pool's ConnConfig.Tracer.
+type QueryTracer struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := db.QueryName(data.SQL)
+	if name == "" {
+		name = "other"
+	}
+	return context.WithValue(ctx, queryStartKey{}, queryStart{name: name, start: time.Now()})
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	status := "ok"
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		status = "error"
+	}
+	queryDuration.WithLabelValues(q.name, status).Observe(time.Since(q.start).Seconds())
+}
+
+// RegisterPool exports the pool's connection stats
+func RegisterPool(pool *pgxpool.Pool) error {
+	return Registry.Register(poolCollector{pool})
+}
+
+var (
+	poolAcquired      = poolDesc("acquired_conns", "Connections in use.")
+	poolIdle          = poolDesc("idle_conns", "Idle connections.")
+	poolTotal         = poolDesc("total_conns", "Open connections.")
+	poolMax           = poolDesc("max_conns", "Maximum pool size.")
+	poolAcquires      = poolDesc("acquires_total", "Connections acquired from the pool.")
+	poolEmptyAcquires = poolDesc("empty_acquires_total", "Acquires that had to wait for a connection.")
+	poolAcquireTime   = poolDesc("acquire_seconds_total", "Time spent waiting to acquire connections.")
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{poolAcquired, poolIdle, poolTotal, poolMax, poolAcquires, poolEmptyAcquires, poolAcquireTime} {
+		ch <- d
+	}
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotal, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMax, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireTime, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
@@ -0,0 +1,99 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, the database
+// and sales. Everything is registered on Registry, which Handler serves.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ecommerce"
+
+// Registry holds every metric the API exports, plus the Go runtime and
+// process collectors
+var Registry = prometheus.NewRegistry()
+
+// Business counters
+var (
+	CartsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "carts_created_total",
+		Help:      "Carts created.",
+	})
+	CartItemsAdded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cart_items_added_total",
+		Help:      "Units added to carts.",
+	})
+	CheckoutsStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkouts_started_total",
+		Help:      "Checkout sessions created.",
+	})
+	CheckoutsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkouts_completed_total",
+		Help:      "Orders paid.",
+	})
+	Revenue = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "revenue_total",
+		Help:      "Total of paid orders in the currency's major unit.",
+	}, []string{"currency"})
+)
+
+// RateLimited counts requests refused with 429, by limit
+var RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "rate_limited_total",
+	Help:      "Requests refused by a rate limit.",
+}, []string{"limit"})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		CartsCreated, CartItemsAdded, CheckoutsStarted, CheckoutsCompleted, Revenue,
+		RateLimited,
+		httpRequests, httpDuration, queryDuration,
+	)
+}
+
+// RecordSale counts a paid order
+func RecordSale(currency string, total float64) {
+	CheckoutsCompleted.Inc()
+	Revenue.WithLabelValues(strings.ToLower(currency)).Add(total)
+}
+
+type Config struct {
+	Addr  string // separate listener for /metrics, e.g. ":9090"
+	Token string // bearer token required to scrape, if set
+}
+
+// Enabled reports whether /metrics is served at all. Without an internal
+// address or a token the endpoint would be public, so it stays off.
+func (c Config) Enabled() bool {
+	return c.Addr != "" || c.Token != ""
+}
+
+// Handler serves Registry, requiring "Authorization: Bearer <token>" when a
+// token is configured
+func Handler(token string) http.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Server serves /metrics on its own address so it can stay off the public
+// listener. It runs beside the API's main listener and stops with the
+// workers.
+type Server struct {
+	Config Config
+}
+
+func NewServer(config Config) *Server {
+	return &Server{Config: config}
+}
+
+// Serve serves until ctx is cancelled, or returns the error if the listener
+// fails, e.g. the port is taken
+func (s *Server) Serve(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler(s.Config.Token))
+	srv := &http.Server{
+		Addr:              s.Config.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving metrics", "addr", s.Config.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+const (
+	DefaultInterval    = 10 * time.Second
+	DefaultBatchSize   = 20
+	DefaultMaxAttempts = 8
+	DefaultBaseBackoff = 30 * time.Second
+	MaxBackoff         = 6 * time.Hour
+
+	// sendLease hides a claimed email from other dispatchers while it is
+	// being sent. If the process dies mid-send the email is retried after it.
+	sendLease = 5 * time.Minute
+)
+
+// Email statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Dispatcher sends due emails from the outbox
+type Dispatcher struct {
+	Transport   Transport
+	Store       db.Store
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+}
+
+// NewDispatcher sends through transport every c.Interval
+func NewDispatcher(transport Transport, c Config, store db.Store) *Dispatcher {
+	return &Dispatcher{
+		Transport:   transport,
+		Store:       store,
+		Interval:    c.Interval,
+		BatchSize:   DefaultBatchSize,
+		MaxAttempts: c.MaxAttempts,
+		BaseBackoff: DefaultBaseBackoff,
+	}
+}
+
+// Run sends emails every Interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "Email dispatch error", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of due emails and returns how many went out
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	q := db.New(d.Store)
+	emails, err := q.ClaimDueEmails(ctx, db.ClaimDueEmailsParams{
+		Lease:     pgtype.Interval{Microseconds: sendLease.Microseconds(), Valid: true},
+		BatchSize: int32(d.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range emails {
+		err := d.Transport.Send(ctx, Message{
+			To:      email.Recipient,
+			Subject: email.Subject,
+			HTML:    email.HtmlBody,
+			Text:    email.TextBody,
+		})
+		if err == nil {
+			if err := q.MarkEmailSent(ctx, email.ID); err != nil {
+				return sent, err
+			}
+			sent++
+			continue
+		}
+
+		status := StatusPending
+		if int(email.Attempts) >= d.MaxAttempts {
+			status = StatusFailed
+		}
+		slog.WarnContext(ctx, "Email delivery failed", "email_id", email.ID, "recipient", email.Recipient, "attempt", email.Attempts, "status", status, "err", err)
+
+		if err := q.MarkEmailFailed(ctx, db.MarkEmailFailedParams{
+			ID:            email.ID,
+			Status:        status,
+			LastError:     pgtype.Text{String: err.Error(), Valid: true},
+			NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(d.Backoff(int(email.Attempts))), Valid: true},
+		}); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// Backoff doubles the wait after every failed attempt, capped at MaxBackoff
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	wait := float64(d.BaseBackoff) * math.Pow(2, float64(attempts-1))
+	if wait > float64(MaxBackoff) {
+		return MaxBackoff
+	}
+	return time.Duration(wait)
+}
@@ -0,0 +1,178 @@
+// Package notifications renders customer emails from templates and queues
+// them in the email_outbox table. A Dispatcher sends queued emails through a
+// Transport, retrying failures with exponential backoff, so sends survive
+// restarts and can be queued inside the same transaction as the change that
+// caused them.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Template names, each backed by templates/<name>.html and templates/<name>.txt
+type Template string
+
+const (
+	OrderConfirmation Template = "order_confirmation"
+	ShippingUpdate    Template = "shipping_update"
+	PasswordReset     Template = "password_reset"
+	AbandonedCart     Template = "abandoned_cart"
+)
+
+// Transports
+const (
+	TransportLog  = "log"
+	TransportSMTP = "smtp"
+)
+
+const (
+	DefaultStoreName = "Our Store"
+	DefaultSMTPPort  = 587
+)
+
+// Config holds the email settings
+type Config struct {
+	StoreName   string        // shown in every email
+	Transport   string        // TransportLog or TransportSMTP
+	SMTP        SMTPTransport // server and sender for the smtp transport
+	Interval    time.Duration // how often the dispatcher sends due emails
+	MaxAttempts int           // sends before an email is marked failed
+}
+
+var templateNames = []Template{OrderConfirmation, ShippingUpdate, PasswordReset, AbandonedCart}
+
+// LineItem is a product line shown in an email, Price is the line total
+type LineItem struct {
+	Name     string
+	Quantity int32
+	Price    float64
+}
+
+type OrderConfirmationData struct {
+	OrderNumber int32
+	Items       []LineItem
+	Subtotal    float64
+	Shipping    float64
+	Tax         float64
+	Total       float64
+	Currency    string
+}
+
+type ShippingUpdateData struct {
+	OrderNumber    int32
+	Status         string
+	Carrier        string
+	TrackingNumber string
+	TrackingURL    string
+	Items          []LineItem
+}
+
+type PasswordResetData struct {
+	ResetURL  string
+	ExpiresIn string
+}
+
+type AbandonedCartData struct {
+	Items      []LineItem
+	RestoreURL string
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var funcs = map[string]any{
+	"money": func(f float64) string { return fmt.Sprintf("%.2f", f) },
+	"upper": strings.ToUpper,
+	"store": StoreName,
+}
+
+var (
+	htmlTemplates = map[Template]*htmltemplate.Template{}
+	textTemplates = map[Template]*texttemplate.Template{}
+)
+
+func init() {
+	for _, name := range templateNames {
+		htmlTemplates[name] = htmltemplate.Must(htmltemplate.New(string(name)).Funcs(htmltemplate.FuncMap(funcs)).
+			ParseFS(templateFS, "templates/layout.html", "templates/"+string(name)+".html"))
+		textTemplates[name] = texttemplate.Must(texttemplate.New(string(name)).Funcs(texttemplate.FuncMap(funcs)).
+			ParseFS(templateFS, "templates/"+string(name)+".txt"))
+	}
+}
+
+// storeName is shown in every email
+var storeName = DefaultStoreName
+
+// SetStoreName changes the name shown in every email. main calls it once at
+// startup, before anything is rendered.
+func SetStoreName(name string) {
+	if name != "" {
+		storeName = name
+	}
+}
+
+// StoreName returns the name shown in every email
+func StoreName() string {
+	return storeName
+}
+
+// Message is a rendered email
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Render executes a template with data
+func Render(name Template, to string, data any) (Message, error) {
+	html, ok := htmlTemplates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+	text := textTemplates[name]
+
+	var subject, htmlBody, textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := html.ExecuteTemplate(&htmlBody, "layout", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.ExecuteTemplate(&textBody, string(name)+".txt", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    htmlBody.String(),
+		Text:    textBody.String(),
+	}, nil
+}
+
+// Enqueue renders a template and stores it in the outbox. Pass queries bound
+// to a transaction to queue the email only if the transaction commits.
+func Enqueue(ctx context.Context, q *db.Queries, name Template, to string, data any) (db.EmailOutbox, error) {
+	msg, err := Render(name, to, data)
+	if err != nil {
+		return db.EmailOutbox{}, err
+	}
+
+	return q.EnqueueEmail(ctx, db.EnqueueEmailParams{
+		Template:  string(name),
+		Recipient: msg.To,
+		Subject:   msg.Subject,
+		HtmlBody:  msg.HTML,
+		TextBody:  msg.Text,
+	})
+}
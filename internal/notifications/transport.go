@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Transport delivers a rendered email
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogTransport writes emails to the log instead of sending them, for
+// development
+type LogTransport struct{}
+
+func (LogTransport) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "Email", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+// SMTPTransport sends through an SMTP server. The connection is upgraded with
+// STARTTLS when the server offers it, and authentication is skipped when no
+// username is set, so local sinks such as Mailpit on port 1025 work as is.
+type SMTPTransport struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (t SMTPTransport) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+
+	var auth smtp.Auth
+	if t.Username != "" {
+		auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	}
+
+	body, err := buildMIME(t.From, msg)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, t.From, []string{msg.To}, body)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildMIME writes a multipart/alternative message with text and HTML parts
+func buildMIME(from string, msg Message) ([]byte, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		w := quotedprintable.NewWriter(&b)
+		if _, err := w.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+
+	return b.Bytes(), nil
+}
+
+func randomBoundary() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// NewTransport returns the transport named by c.Transport, the log
+// transport unless it's TransportSMTP
+func NewTransport(c Config) (Transport, error) {
+	switch c.Transport {
+	case "", TransportLog:
+		return LogTransport{}, nil
+	case TransportSMTP:
+		if c.SMTP.Host == "" || c.SMTP.From == "" {
+			return nil, fmt.Errorf("SMTP_HOST and EMAIL_FROM are required for the smtp transport")
+		}
+		return c.SMTP, nil
+	default:
+		return nil, fmt.Errorf("unknown EMAIL_TRANSPORT %q", c.Transport)
+	}
+}
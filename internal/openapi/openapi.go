@@ -0,0 +1,251 @@
+// Package openapi builds the API's OpenAPI 3 document. Paths and methods
+// come from walking the chi router and each one is described by an entry in
+// operations; Generate fails when a route has no entry or an entry no longer
+// matches a route. The server's router test checks that, so the document
+// can't drift from the router.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/idempotency"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Security scheme names
+const (
+	CartSession  = "cartSession"
+	AdminSession = "adminSession"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Generate walks routes and builds the document from the matching entries
+// in operations. The session cookie names come from sessions. When routes
+// and operations don't match, the document for the documented routes is
+// returned along with an error listing every mismatch.
+func Generate(routes chi.Routes, sessions auth.Config) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: "Ecommerce API", Version: "1.0.0"},
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				CartSession: {
+					Type:        "apiKey",
+					In:          "cookie",
+					Name:        sessions.CookieName,
+					Description: "Set by POST /api/new-cart, renewed by POST /api/cart/refresh",
+				},
+				AdminSession: {
+					Type:        "apiKey",
+					In:          "cookie",
+					Name:        sessions.AdminCookieName,
+					Description: "Set by POST /api/auth/login, renewed by POST /api/auth/refresh",
+				},
+			},
+		},
+	}
+	s := schemas(doc.Components.Schemas)
+	errorRef := s.ref(reflect.TypeOf(apperr.Body{}))
+
+	var problems []string
+	seen := map[string]bool{}
+	err := chi.Walk(routes, func(method, pattern string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		key := method + " " + pattern
+		spec, ok := operations[key]
+		if !ok {
+			problems = append(problems, "route "+key+" is not documented")
+			return nil
+		}
+		seen[key] = true
+
+		op := &Operation{
+			OperationID: spec.ID,
+			Summary:     spec.Summary,
+			Tags:        []string{spec.Tag},
+			Responses:   map[string]*Response{},
+		}
+
+		for _, name := range pathParam.FindAllStringSubmatch(pattern, -1) {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     name[1],
+				In:       "path",
+				Required: true,
+				Schema:   paramSchema(name[1]),
+			})
+		}
+		for _, p := range spec.Query {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:        p.Name,
+				In:          "query",
+				Description: p.Description,
+				Required:    p.Required,
+				Schema:      &Schema{Type: p.Type, Format: p.Format},
+			})
+		}
+
+		switch security(middlewares) {
+		case CartSession:
+			op.Security = []map[string][]string{{CartSession: {}}}
+		case AdminSession:
+			op.Security = []map[string][]string{{AdminSession: {}}}
+		}
+		if spec.Idempotent {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:        idempotency.Header,
+				In:          "header",
+				Description: "Retries with the same key and body replay the first response",
+				Schema:      &Schema{Type: "string", MaxLength: intPtr(idempotency.MaxKeyLength)},
+			})
+		}
+
+		if spec.Body != nil {
+			t := reflect.TypeOf(spec.Body)
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]*MediaType{
+					"application/json":                  {Schema: s.ref(t)},
+					"application/x-www-form-urlencoded": {Schema: s.formSchema(t)},
+				},
+			}
+		}
+
+		status := spec.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &Response{Description: http.StatusText(status)}
+		switch {
+		case spec.Response != nil:
+			success.Content = map[string]*MediaType{
+				"application/json": {Schema: s.ref(reflect.TypeOf(spec.Response))},
+			}
+		case spec.Text != "":
+			success.Description = spec.Text
+			success.Content = map[string]*MediaType{
+				"text/plain": {Schema: &Schema{Type: "string"}},
+			}
+		}
+		op.Responses[fmt.Sprint(status)] = success
+		op.Responses["default"] = &Response{
+			Description: "Error",
+			Content:     map[string]*MediaType{"application/json": {Schema: errorRef}},
+		}
+
+		path := pathParam.ReplaceAllString(pattern, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range operations {
+		if !seen[key] && !operations[key].Optional {
+			problems = append(problems, "documented route "+key+" is not on the router")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return doc, fmt.Errorf("openapi document does not match the router:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	return doc, nil
+}
+
+// security returns the session a route requires from its middleware stack.
+// Method values share their code pointer across receivers, so a nil
+// *auth.Sessions matches whichever one the router was built with.
+func security(middlewares []func(http.Handler) http.Handler) string {
+	var sessions *auth.Sessions
+	for _, mw := range middlewares {
+		switch reflect.ValueOf(mw).Pointer() {
+		case reflect.ValueOf(sessions.CartMiddleware).Pointer():
+			return CartSession
+		case reflect.ValueOf(sessions.AdminMiddleware).Pointer():
+			return AdminSession
+		}
+	}
+	return ""
+}
+
+// paramSchema types a path parameter. Every ID is an integer.
+func paramSchema(name string) *Schema {
+	if name == "id" || strings.HasSuffix(name, "ID") {
+		return &Schema{Type: "integer", Format: "int32"}
+	}
+	return &Schema{Type: "string"}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
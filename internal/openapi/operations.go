@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/cleanup"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/handlers"
+	"github.com/petermazzocco/go-ecommerce-api/internal/health"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+)
+
+// route describes one method and pattern on the router. Body and Response
+// are zero values of the request and response types; a route without a
+// Response answers with Text as plain text.
+type route struct {
+	ID         string
+	Summary    string
+	Tag        string
+	Query      []param
+	Body       any
+	Response   any
+	Text       string
+	Status     int
+	Idempotent bool // honors the Idempotency-Key header
+	Optional   bool // only on the router in some configurations
+}
+
+type param struct {
+	Name        string
+	Type        string
+	Format      string
+	Required    bool
+	Description string
+}
+
+var (
+	limitParam   = param{Name: "limit", Type: "integer", Description: "Number of rows to return"}
+	addressQuery = []param{
+		{Name: "country", Type: "string", Description: "Two letter country code"},
+		{Name: "state", Type: "string"},
+		{Name: "postalCode", Type: "string"},
+	}
+)
+
+// operations is keyed by method and chi route pattern
+var operations = map[string]route{
+	// Probes and metrics
+	"GET /healthz": {ID: "liveness", Summary: "Liveness probe", Tag: "Health", Text: "ok"},
+	"GET /readyz":  {ID: "readiness", Summary: "Readiness probe, 503 with the failing checks when not ready", Tag: "Health", Response: health.Report{}},
+	"GET /metrics": {ID: "metrics", Summary: "Prometheus metrics, needs Authorization: Bearer METRICS_TOKEN", Tag: "Health", Text: "Prometheus text format", Optional: true},
+
+	// Public
+	"GET /api/":                 {ID: "healthCheck", Summary: "Health check", Tag: "Public", Text: "Ecommerce API"},
+	"GET /api/openapi.json":     {ID: "getOpenAPI", Summary: "This document", Tag: "Public", Text: "OpenAPI document"},
+	"GET /api/docs":             {ID: "getDocs", Summary: "Interactive API docs", Tag: "Public", Text: "HTML page"},
+	"GET /api/products/":        {ID: "listProducts", Summary: "List all products", Tag: "Products", Response: []methods.Product{}},
+	"GET /api/products/{id}":    {ID: "getProduct", Summary: "Get product details", Tag: "Products", Response: methods.Product{}},
+	"GET /api/collections/":     {ID: "listCollections", Summary: "List all collections", Tag: "Collections", Response: []db.Collection{}},
+	"GET /api/collections/{id}": {ID: "getCollection", Summary: "Get collection details", Tag: "Collections", Response: db.Collection{}},
+	"GET /api/checkout/confirmation": {
+		ID: "getCheckoutConfirmation", Summary: "Order confirmation after returning from Stripe", Tag: "Checkout",
+		Query:    []param{{Name: "session_id", Type: "string", Required: true, Description: "Stripe checkout session ID"}},
+		Response: methods.CheckoutConfirmation{},
+	},
+	"POST /api/stripe/webhook": {ID: "stripeWebhook", Summary: "Stripe events, signed with STRIPE_WEBHOOK_SECRET", Tag: "Checkout", Text: "Event received"},
+	"GET /api/orders/track": {
+		ID: "trackOrder", Summary: "Shipment status, carriers and tracking links for an order", Tag: "Orders",
+		Query: []param{
+			{Name: "orderNumber", Type: "integer", Format: "int32", Required: true},
+			{Name: "email", Type: "string", Format: "email", Required: true, Description: "Email used at checkout"},
+		},
+		Response: methods.OrderTracking{},
+	},
+	"POST /api/returns": {ID: "requestReturn", Summary: "Request a return", Tag: "Returns", Body: handlers.ReturnRequest{}, Response: methods.ReturnDetail{}, Status: http.StatusCreated},
+
+	// Cart
+	"POST /api/new-cart":     {ID: "newCart", Summary: "Create a new cart session", Tag: "Cart", Text: "Sets the cart cookie"},
+	"POST /api/cart/refresh": {ID: "refreshCartSession", Summary: "New cart access token from the refresh cookie", Tag: "Cart", Text: "Sets new access and refresh cookies"},
+	"GET /api/cart/restore": {
+		ID: "restoreCart", Summary: "Signed link from a cart recovery reminder", Tag: "Cart",
+		Query: []param{{Name: "token", Type: "string", Required: true}},
+		Text:  "Sets the cart cookie, or redirects to CART_RECOVERY_REDIRECT_URL when it is set",
+	},
+	"GET /api/cart/":     {ID: "getCart", Summary: "View cart contents", Tag: "Cart", Response: []handlers.NewProduct{}},
+	"DELETE /api/cart/":  {ID: "clearCart", Summary: "Clear cart", Tag: "Cart", Text: "Cart has been cleared", Idempotent: true},
+	"POST /api/cart/add": {ID: "addCartItem", Summary: "Add item to cart", Tag: "Cart", Body: handlers.AddItemRequest{}, Text: "Item has been added to cart", Idempotent: true},
+	"GET /api/cart/summary": {
+		ID: "getCartSummary", Summary: "Cart subtotal, tax lines, shipping and total", Tag: "Cart",
+		Query:    append(addressQuery, param{Name: "shippingMethodID", Type: "integer", Format: "int32"}),
+		Response: methods.CartSummary{},
+	},
+	"GET /api/cart/shipping-rates":  {ID: "getShippingRates", Summary: "Shipping options for a destination", Tag: "Cart", Query: addressQuery, Response: []methods.ShippingRate{}},
+	"PUT /api/cart/{productID}/":    {ID: "updateCartItem", Summary: "Update item quantity", Tag: "Cart", Body: handlers.QuantityRequest{}, Text: "Item has been updated in the cart", Idempotent: true},
+	"DELETE /api/cart/{productID}/": {ID: "removeCartItem", Summary: "Remove item from cart", Tag: "Cart", Text: "Item has been removed from cart", Idempotent: true},
+	"POST /api/cart/checkout":       {ID: "checkout", Summary: "Create a Stripe checkout session", Tag: "Checkout", Body: handlers.CheckoutRequest{}, Text: "Stripe checkout URL", Idempotent: true},
+
+	// Auth
+	"POST /api/auth/login":      {ID: "login", Summary: "Admin login, or the start of a two-factor login", Tag: "Auth", Body: handlers.LoginRequest{}, Response: handlers.LoginResponse{}},
+	"POST /api/auth/logout":     {ID: "logout", Summary: "Admin logout", Tag: "Auth", Text: "Revokes the session and clears its cookies"},
+	"POST /api/auth/refresh":    {ID: "refreshAdminSession", Summary: "New admin access token from the refresh cookie", Tag: "Auth", Text: "Sets new access and refresh cookies"},
+	"POST /api/auth/mfa/setup":  {ID: "startMFASetup", Summary: "New secret for an authenticator app", Tag: "Auth", Response: methods.MFASetup{}},
+	"POST /api/auth/mfa/enable": {ID: "enableMFA", Summary: "Turn on two-factor authentication with a first code", Tag: "Auth", Body: handlers.MFACodeRequest{}, Response: handlers.RecoveryCodesResponse{}},
+	"POST /api/auth/mfa/verify": {ID: "verifyMFA", Summary: "Finish a login with a two-factor or recovery code", Tag: "Auth", Body: handlers.MFACodeRequest{}, Response: handlers.LoginResponse{}},
+
+	// Admin
+	"GET /api/admin/":                    {ID: "adminPortal", Summary: "Admin portal access", Tag: "Admin", Text: "Admin Portal"},
+	"POST /api/admin/users/register":     {ID: "registerAdmin", Summary: "Register admin user", Tag: "Admin", Body: handlers.UserRequest{}, Response: db.User{}},
+	"GET /api/admin/users/{id}/":         {ID: "getUser", Summary: "Get user details", Tag: "Admin", Response: db.User{}},
+	"DELETE /api/admin/users/{id}/":      {ID: "deleteUser", Summary: "Delete user", Tag: "Admin", Text: "User deleted"},
+	"DELETE /api/admin/users/{id}/mfa":   {ID: "resetMFA", Summary: "Turn off another admin's two-factor authentication", Tag: "Admin", Text: "Two-factor authentication reset"},
+	"GET /api/admin/mfa/":                {ID: "getMFAStatus", Summary: "The logged in admin's two-factor status", Tag: "Admin", Response: methods.MFAStatus{}},
+	"POST /api/admin/mfa/recovery-codes": {ID: "regenerateRecoveryCodes", Summary: "Replace the logged in admin's recovery codes", Tag: "Admin", Body: handlers.MFACodeRequest{}, Response: handlers.RecoveryCodesResponse{}},
+	"GET /api/admin/emails":              {ID: "listEmails", Summary: "Recent emails with their delivery status", Tag: "Admin", Query: []param{limitParam}, Response: []db.EmailOutbox{}},
+	"GET /api/admin/events":              {ID: "listEvents", Summary: "Recent domain events with their dispatch status", Tag: "Admin", Query: []param{limitParam}, Response: []db.DomainEvent{}},
+	"PUT /api/admin/password":            {ID: "changePassword", Summary: "Change the logged in admin's password", Tag: "Admin", Body: handlers.ChangePasswordRequest{}, Text: "Password changed"},
+	"GET /api/admin/maintenance/cart-gc": {ID: "getCartGCStats", Summary: "Expired cart cleanup stats", Tag: "Admin", Response: cleanup.Stats{}},
+	"GET /api/admin/security-events": {
+		ID: "listSecurityEvents", Summary: "Recent logins, failures, lockouts and password changes", Tag: "Admin",
+		Query:    []param{limitParam, {Name: "email", Type: "string", Description: "Only this account's events"}},
+		Response: []db.SecurityEvent{},
+	},
+	"POST /api/admin/users/{id}/deactivate": {ID: "deactivateUser", Summary: "Take away an admin's role and revoke their sessions", Tag: "Admin", Text: "User deactivated"},
+	"GET /api/admin/reports/cart-recovery": {
+		ID: "getCartRecoveryReport", Summary: "Recovery reminders sent, restored and converted", Tag: "Admin",
+		Query: []param{
+			{Name: "from", Type: "string", Format: "date", Description: "Defaults to 30 days ago"},
+			{Name: "to", Type: "string", Format: "date", Description: "Defaults to today"},
+		},
+		Response: methods.RecoveryReport{},
+	},
+
+	// Admin products
+	"GET /api/admin/products/":                  {ID: "adminListProducts", Summary: "List all products", Tag: "Products", Response: []methods.Product{}},
+	"POST /api/admin/products/":                 {ID: "createProduct", Summary: "Create a product", Tag: "Products", Body: handlers.CreateProductRequest{}, Response: db.Product{}},
+	"GET /api/admin/products/{id}/":             {ID: "adminGetProduct", Summary: "Get product details", Tag: "Products", Response: methods.Product{}},
+	"PUT /api/admin/products/{id}/":             {ID: "updateProduct", Summary: "Update a product", Tag: "Products", Body: handlers.UpdateProductRequest{}, Text: "Product updated"},
+	"DELETE /api/admin/products/{id}/":          {ID: "deleteProduct", Summary: "Delete a product", Tag: "Products", Text: "Product deleted"},
+	"PUT /api/admin/products/{id}/sizes/{size}": {ID: "setProductStock", Summary: "Set the stock of a size", Tag: "Products", Body: handlers.StockRequest{}, Text: "Stock updated"},
+
+	// Admin tax rates
+	"GET /api/admin/tax-rates/":        {ID: "listTaxRates", Summary: "List tax rules", Tag: "Tax", Response: []methods.TaxRate{}},
+	"POST /api/admin/tax-rates/":       {ID: "createTaxRate", Summary: "Add a tax rule", Tag: "Tax", Body: handlers.TaxRateRequest{}, Response: methods.TaxRate{}},
+	"DELETE /api/admin/tax-rates/{id}": {ID: "deleteTaxRate", Summary: "Delete a tax rule", Tag: "Tax", Text: "Tax rate deleted"},
+
+	// Admin shipping
+	"GET /api/admin/shipping/zones/":              {ID: "listShippingZones", Summary: "List zones with their methods", Tag: "Shipping", Response: []methods.ShippingZone{}},
+	"POST /api/admin/shipping/zones/":             {ID: "createShippingZone", Summary: "Create a zone", Tag: "Shipping", Body: handlers.ShippingZoneRequest{}, Response: methods.ShippingZone{}},
+	"DELETE /api/admin/shipping/zones/{id}/":      {ID: "deleteShippingZone", Summary: "Delete a zone and its methods", Tag: "Shipping", Text: "Shipping zone deleted"},
+	"POST /api/admin/shipping/zones/{id}/methods": {ID: "createShippingMethod", Summary: "Add a method to a zone", Tag: "Shipping", Body: handlers.ShippingMethodRequest{}, Response: methods.ShippingMethod{}},
+	"PUT /api/admin/shipping/methods/{id}/":       {ID: "updateShippingMethod", Summary: "Enable or disable a method", Tag: "Shipping", Body: handlers.ShippingMethodUpdateRequest{}, Text: "Shipping method updated"},
+	"DELETE /api/admin/shipping/methods/{id}/":    {ID: "deleteShippingMethod", Summary: "Delete a method", Tag: "Shipping", Text: "Shipping method deleted"},
+
+	// Admin orders
+	"GET /api/admin/orders/":                                  {ID: "listOrders", Summary: "List orders", Tag: "Orders", Response: []db.Order{}},
+	"GET /api/admin/orders/{id}/":                             {ID: "getOrder", Summary: "Order with items, history and fulfillments", Tag: "Orders", Response: methods.OrderDetail{}},
+	"POST /api/admin/orders/{id}/status":                      {ID: "updateOrderStatus", Summary: "Move the order to a new status", Tag: "Orders", Body: handlers.OrderStatusRequest{}, Response: db.Order{}},
+	"GET /api/admin/orders/{id}/refunds":                      {ID: "listRefunds", Summary: "Refunds with the amount left to refund", Tag: "Orders", Response: methods.OrderRefunds{}},
+	"POST /api/admin/orders/{id}/refunds":                     {ID: "createRefund", Summary: "Full or partial refund", Tag: "Orders", Body: handlers.RefundRequest{}, Response: db.Refund{}, Status: http.StatusCreated},
+	"POST /api/admin/orders/{id}/refunds/{refundID}/retry":    {ID: "retryRefund", Summary: "Send an unconfirmed refund again", Tag: "Orders", Response: db.Refund{}},
+	"POST /api/admin/orders/{id}/fulfillments":                {ID: "createFulfillment", Summary: "Ship items", Tag: "Orders", Body: handlers.FulfillmentRequest{}, Response: methods.Fulfillment{}, Status: http.StatusCreated},
+	"PUT /api/admin/orders/{id}/fulfillments/{fulfillmentID}": {ID: "updateFulfillment", Summary: "Update tracking or status of a shipment", Tag: "Orders", Body: handlers.FulfillmentRequest{}, Response: db.Fulfillment{}},
+
+	// Admin returns
+	"GET /api/admin/returns/":              {ID: "listReturns", Summary: "List return requests", Tag: "Returns", Response: []db.Return{}},
+	"GET /api/admin/returns/{id}/":         {ID: "getReturn", Summary: "Return with its items", Tag: "Returns", Response: methods.ReturnDetail{}},
+	"POST /api/admin/returns/{id}/approve": {ID: "approveReturn", Summary: "Approve a return, optionally restocking and refunding", Tag: "Returns", Body: handlers.ApproveReturnRequest{}, Response: methods.ReturnDetail{}},
+	"POST /api/admin/returns/{id}/reject":  {ID: "rejectReturn", Summary: "Reject a return", Tag: "Returns", Body: handlers.NoteRequest{}, Response: methods.ReturnDetail{}},
+
+	// Admin webhooks
+	"GET /api/admin/webhooks/":                                   {ID: "listWebhooks", Summary: "List webhook endpoints", Tag: "Webhooks", Response: []methods.Webhook{}},
+	"POST /api/admin/webhooks/":                                  {ID: "createWebhook", Summary: "Create an endpoint", Tag: "Webhooks", Body: handlers.WebhookRequest{}, Response: methods.Webhook{}, Status: http.StatusCreated},
+	"GET /api/admin/webhooks/{id}/":                              {ID: "getWebhook", Summary: "Endpoint with its most recent deliveries", Tag: "Webhooks", Query: []param{limitParam}, Response: methods.WebhookDetail{}},
+	"PUT /api/admin/webhooks/{id}/":                              {ID: "updateWebhook", Summary: "Update an endpoint", Tag: "Webhooks", Body: handlers.WebhookUpdateRequest{}, Response: methods.Webhook{}},
+	"DELETE /api/admin/webhooks/{id}/":                           {ID: "deleteWebhook", Summary: "Delete an endpoint and its delivery log", Tag: "Webhooks", Text: "Webhook deleted"},
+	"GET /api/admin/webhooks/deliveries/{deliveryID}/":           {ID: "getWebhookDelivery", Summary: "Delivery with every attempt", Tag: "Webhooks", Response: methods.WebhookDeliveryDetail{}},
+	"POST /api/admin/webhooks/deliveries/{deliveryID}/redeliver": {ID: "redeliverWebhook", Summary: "Send a delivery again", Tag: "Webhooks", Response: methods.WebhookDeliveryDetail{}, Status: http.StatusAccepted},
+
+	// Admin collections
+	"GET /api/admin/collections/":                             {ID: "adminListCollections", Summary: "List all collections", Tag: "Collections", Response: []db.Collection{}},
+	"POST /api/admin/collections/":                            {ID: "createCollection", Summary: "Create a collection", Tag: "Collections", Body: handlers.CollectionRequest{}, Response: db.Collection{}},
+	"GET /api/admin/collections/{id}/":                        {ID: "adminGetCollection", Summary: "Get collection details", Tag: "Collections", Response: db.Collection{}},
+	"PUT /api/admin/collections/{id}/":                        {ID: "updateCollection", Summary: "Update a collection", Tag: "Collections", Body: handlers.CollectionRequest{}, Text: "Collection updated"},
+	"DELETE /api/admin/collections/{id}/":                     {ID: "deleteCollection", Summary: "Delete a collection", Tag: "Collections", Text: "Collection deleted"},
+	"POST /api/admin/collections/{id}/product/{productID}/":   {ID: "addCollectionProduct", Summary: "Add a product to a collection", Tag: "Collections", Text: "Product added to collection"},
+	"DELETE /api/admin/collections/{id}/product/{productID}/": {ID: "removeCollectionProduct", Summary: "Remove a product from a collection", Tag: "Collections", Text: "Product removed from collection"},
+}
@@ -0,0 +1,302 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the API uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawJSONType       = reflect.TypeOf(json.RawMessage{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	codeType          = reflect.TypeOf(apperr.Code(""))
+	pgTextType        = reflect.TypeOf(pgtype.Text{})
+	pgBoolType        = reflect.TypeOf(pgtype.Bool{})
+	pgInt4Type        = reflect.TypeOf(pgtype.Int4{})
+	pgNumericType     = reflect.TypeOf(pgtype.Numeric{})
+	pgTimestamptzType = reflect.TypeOf(pgtype.Timestamptz{})
+	pgUUIDType        = reflect.TypeOf(pgtype.UUID{})
+)
+
+// schemas collects named struct schemas for components/schemas
+type schemas map[string]*Schema
+
+// ref returns the schema for t. Named structs are added to s once and
+// referenced by package and type name, e.g. db.Collection.
+func (s schemas) ref(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawJSONType:
+		return &Schema{}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case codeType:
+		return &Schema{Type: "string", Enum: []string{
+			string(apperr.CodeNotFound),
+			string(apperr.CodeValidation),
+			string(apperr.CodeConflict),
+			string(apperr.CodeUnauthorized),
+			string(apperr.CodeRateLimited),
+			string(apperr.CodeInternal),
+		}}
+	// pgtype values marshal to null when they aren't valid
+	case pgTextType:
+		return &Schema{Type: "string", Nullable: true}
+	case pgBoolType:
+		return &Schema{Type: "boolean", Nullable: true}
+	case pgInt4Type:
+		return &Schema{Type: "integer", Format: "int32", Nullable: true}
+	case pgNumericType:
+		return &Schema{Type: "number", Nullable: true}
+	case pgTimestamptzType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case pgUUIDType:
+		return &Schema{Type: "string", Format: "uuid", Nullable: true}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.ref(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.ref(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.ref(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := s[name]; !ok {
+			s[name] = nil // placeholder for recursive types
+			s[name] = s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+// object describes a struct the way encoding/json marshals it, with its
+// validate tags as constraints
+func (s schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// embedded structs without a name are flattened like encoding/json
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addFields(schema, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var prop *Schema
+		if strings.Contains(opts, "string") {
+			prop = &Schema{Type: "string"}
+		} else {
+			prop = s.ref(f.Type)
+		}
+		if required := constrain(prop, f.Tag.Get("validate")); required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+}
+
+// constrain applies validate rules to prop and reports whether the field is
+// required
+func constrain(prop *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			setBound(prop, name, n)
+		case "email":
+			prop.Format = "email"
+		case "url":
+			prop.Format = "uri"
+		case "oneof":
+			prop.Enum = strings.Fields(arg)
+		}
+	}
+	return required
+}
+
+func setBound(prop *Schema, rule string, n float64) {
+	count := int(n)
+	switch prop.Type {
+	case "string":
+		if rule != "max" {
+			prop.MinLength = &count
+		}
+		if rule != "min" {
+			prop.MaxLength = &count
+		}
+	case "array":
+		if rule != "max" {
+			prop.MinItems = &count
+		}
+		if rule != "min" {
+			prop.MaxItems = &count
+		}
+	case "integer", "number":
+		if rule != "max" {
+			prop.Minimum = &n
+		}
+		if rule != "min" {
+			prop.Maximum = &n
+		}
+	}
+}
+
+// formSchema describes a request struct sent as a form. Form tags rename
+// keys and slices of structs become parallel repeated keys.
+func (s schemas) formSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, key, ok := formKey(f)
+		if !ok {
+			continue
+		}
+
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct {
+			elem := f.Type.Elem()
+			for j := 0; j < elem.NumField(); j++ {
+				_, subKey, ok := formKey(elem.Field(j))
+				if !ok {
+					continue
+				}
+				item := s.ref(elem.Field(j).Type)
+				constrain(item, elem.Field(j).Tag.Get("validate"))
+				item.Nullable = false
+				schema.Properties[subKey] = &Schema{
+					Type:        "array",
+					Items:       item,
+					Description: "One value per " + strings.TrimSuffix(name, "s"),
+				}
+			}
+			continue
+		}
+
+		prop := s.ref(f.Type)
+		prop.Nullable = false
+		if constrain(prop, f.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, key)
+		}
+		schema.Properties[key] = prop
+	}
+	return schema
+}
+
+// formKey matches request.Decode: the json name, unless a form tag says
+// otherwise
+func formKey(f reflect.StructField) (string, string, bool) {
+	if !f.IsExported() {
+		return "", "", false
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	key := name
+	if tag := f.Tag.Get("form"); tag != "" {
+		key = tag
+	}
+	return name, key, true
+}
+
+// schemaName is the type's package and name, e.g. methods.Product
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
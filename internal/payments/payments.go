@@ -0,0 +1,279 @@
+// Package payments wraps the payment provider: hosted checkout and refunds.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// CheckoutRequest starts a hosted checkout for a cart. Amounts are in cents.
+type CheckoutRequest struct {
+	CartID     string
+	Email      string
+	Name       string
+	Currency   string
+	SuccessURL string
+	CancelURL  string
+	Items      []CheckoutItem
+	// Charges are extra lines, e.g. exclusive tax, charged once each
+	Charges          []CheckoutCharge
+	ShippingOptions  []ShippingOption
+	AllowedCountries []string
+	// AutomaticTax leaves tax to the provider
+	AutomaticTax bool
+	// IdempotencyKey, when set, stops a retried request creating a second
+	// customer or session
+	IdempotencyKey string
+}
+
+type CheckoutItem struct {
+	PriceID  string
+	Quantity int64
+}
+
+type CheckoutCharge struct {
+	Name   string
+	Amount int64
+}
+
+type ShippingOption struct {
+	MethodID int
+	Name     string
+	Amount   int64
+	MinDays  int
+	MaxDays  int
+}
+
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// CheckoutStatus is what the provider knows about a checkout session
+type CheckoutStatus struct {
+	ID            string
+	Status        string
+	PaymentStatus string
+	Paid          bool
+	AmountTotal   int64
+	Currency      string
+	CustomerEmail string
+}
+
+// RefundRequest refunds Amount cents of the payment taken by a checkout session
+type RefundRequest struct {
+	SessionID string
+	Amount    int64
+	Reason    string
+	OrderID   int32
+	RefundID  int32
+}
+
+type RefundResult struct {
+	ProviderRefundID string
+	Status           string
+}
+
+// ErrRejected wraps errors where the provider answered and refused the
+// request, so nothing was refunded. Any other error, e.g. a timeout, leaves
+// the outcome unknown and the same request should be sent again.
+var ErrRejected = errors.New("rejected by the payment provider")
+
+// Provider takes payments through hosted checkout and refunds them
+type Provider interface {
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (CheckoutSession, error)
+	GetCheckout(ctx context.Context, sessionID string) (CheckoutStatus, error)
+	Refund(ctx context.Context, req RefundRequest) (RefundResult, error)
+}
+
+// Stripe takes payments with Stripe checkout sessions and refunds the
+// payment intent behind them
+type Stripe struct {
+	Key    string
+	client *stripe.Client
+}
+
+// NewStripe builds a client for key that sends its requests with
+// httpClient, or Stripe's default client when it is nil
+func NewStripe(key string, httpClient *http.Client) *Stripe {
+	var opts []stripe.ClientOption
+	if httpClient != nil {
+		opts = append(opts, stripe.WithBackends(stripe.NewBackends(httpClient)))
+	}
+	return &Stripe{Key: key, client: stripe.NewClient(key, opts...)}
+}
+
+// Ready reports whether the Stripe key is set
+func (s *Stripe) Ready() error {
+	if s.Key == "" {
+		return errors.New("STRIPE_KEY is not set")
+	}
+	return nil
+}
+
+// CreateCheckout creates a Stripe customer and a checkout session for them
+func (s *Stripe) CreateCheckout(ctx context.Context, req CheckoutRequest) (CheckoutSession, error) {
+	customerParams := &stripe.CustomerCreateParams{
+		Email: stripe.String(req.Email),
+		Name:  stripe.String(req.Name),
+	}
+	if req.IdempotencyKey != "" {
+		customerParams.SetIdempotencyKey(req.IdempotencyKey + "-customer")
+	}
+	customer, err := s.client.V1Customers.Create(ctx, customerParams)
+	if err != nil {
+		return CheckoutSession{}, stripeError(err)
+	}
+
+	var lineItems []*stripe.CheckoutSessionCreateLineItemParams
+	for _, item := range req.Items {
+		lineItems = append(lineItems, &stripe.CheckoutSessionCreateLineItemParams{
+			Price:    stripe.String(item.PriceID),
+			Quantity: stripe.Int64(item.Quantity),
+		})
+	}
+	for _, charge := range req.Charges {
+		lineItems = append(lineItems, &stripe.CheckoutSessionCreateLineItemParams{
+			PriceData: &stripe.CheckoutSessionCreateLineItemPriceDataParams{
+				Currency: stripe.String(req.Currency),
+				ProductData: &stripe.CheckoutSessionCreateLineItemPriceDataProductDataParams{
+					Name: stripe.String(charge.Name),
+				},
+				UnitAmount: stripe.Int64(charge.Amount),
+			},
+			Quantity: stripe.Int64(1),
+		})
+	}
+
+	var shippingOptions []*stripe.CheckoutSessionCreateShippingOptionParams
+	for _, option := range req.ShippingOptions {
+		data := &stripe.CheckoutSessionCreateShippingOptionShippingRateDataParams{
+			DisplayName: stripe.String(option.Name),
+			Type:        stripe.String("fixed_amount"),
+			FixedAmount: &stripe.CheckoutSessionCreateShippingOptionShippingRateDataFixedAmountParams{
+				Amount:   stripe.Int64(option.Amount),
+				Currency: stripe.String(req.Currency),
+			},
+			Metadata: map[string]string{
+				"shippingMethodID": strconv.Itoa(option.MethodID),
+			},
+		}
+		if option.MinDays > 0 && option.MaxDays > 0 {
+			data.DeliveryEstimate = &stripe.CheckoutSessionCreateShippingOptionShippingRateDataDeliveryEstimateParams{
+				Minimum: &stripe.CheckoutSessionCreateShippingOptionShippingRateDataDeliveryEstimateMinimumParams{
+					Unit:  stripe.String("business_day"),
+					Value: stripe.Int64(int64(option.MinDays)),
+				},
+				Maximum: &stripe.CheckoutSessionCreateShippingOptionShippingRateDataDeliveryEstimateMaximumParams{
+					Unit:  stripe.String("business_day"),
+					Value: stripe.Int64(int64(option.MaxDays)),
+				},
+			}
+		}
+		shippingOptions = append(shippingOptions, &stripe.CheckoutSessionCreateShippingOptionParams{
+			ShippingRateData: data,
+		})
+	}
+
+	params := &stripe.CheckoutSessionCreateParams{
+		SuccessURL: stripe.String(req.SuccessURL),
+		CancelURL:  stripe.String(req.CancelURL),
+		LineItems:  lineItems,
+		Metadata: map[string]string{
+			"cartID": req.CartID,
+		},
+		BillingAddressCollection: stripe.String(stripe.CheckoutSessionBillingAddressCollectionRequired),
+		ShippingAddressCollection: &stripe.CheckoutSessionCreateShippingAddressCollectionParams{
+			AllowedCountries: stripe.StringSlice(req.AllowedCountries),
+		},
+		ShippingOptions: shippingOptions,
+		Customer:        stripe.String(customer.ID),
+		Mode:            stripe.String(stripe.CheckoutSessionModePayment),
+	}
+	if req.AutomaticTax {
+		params.AutomaticTax = &stripe.CheckoutSessionCreateAutomaticTaxParams{
+			Enabled: stripe.Bool(true),
+		}
+		params.CustomerUpdate = &stripe.CheckoutSessionCreateCustomerUpdateParams{
+			Address:  stripe.String("auto"),
+			Shipping: stripe.String("auto"),
+		}
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey + "-session")
+	}
+
+	cs, err := s.client.V1CheckoutSessions.Create(ctx, params)
+	if err != nil {
+		return CheckoutSession{}, stripeError(err)
+	}
+	return CheckoutSession{ID: cs.ID, URL: cs.URL}, nil
+}
+
+// GetCheckout looks up a checkout session a customer returned from
+func (s *Stripe) GetCheckout(ctx context.Context, sessionID string) (CheckoutStatus, error) {
+	cs, err := s.client.V1CheckoutSessions.Retrieve(ctx, sessionID, nil)
+	if err != nil {
+		return CheckoutStatus{}, stripeError(err)
+	}
+
+	status := CheckoutStatus{
+		ID:            cs.ID,
+		Status:        string(cs.Status),
+		PaymentStatus: string(cs.PaymentStatus),
+		Paid:          cs.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid,
+		AmountTotal:   cs.AmountTotal,
+		Currency:      string(cs.Currency),
+	}
+	if cs.CustomerDetails != nil {
+		status.CustomerEmail = cs.CustomerDetails.Email
+	}
+	return status, nil
+}
+
+func (s *Stripe) Refund(ctx context.Context, req RefundRequest) (RefundResult, error) {
+	cs, err := s.client.V1CheckoutSessions.Retrieve(ctx, req.SessionID, nil)
+	if err != nil {
+		return RefundResult{}, stripeError(err)
+	}
+	if cs.PaymentIntent == nil || cs.PaymentIntent.ID == "" {
+		return RefundResult{}, fmt.Errorf("%w: checkout session %s has no payment", ErrRejected, req.SessionID)
+	}
+
+	refundParams := &stripe.RefundCreateParams{
+		PaymentIntent: stripe.String(cs.PaymentIntent.ID),
+		Amount:        stripe.Int64(req.Amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	refundParams.AddMetadata("orderID", strconv.Itoa(int(req.OrderID)))
+	refundParams.AddMetadata("refundID", strconv.Itoa(int(req.RefundID)))
+	refundParams.AddMetadata("reason", req.Reason)
+	// Retrying the same refund row must never refund twice
+	refundParams.SetIdempotencyKey("refund-" + strconv.Itoa(int(req.RefundID)))
+
+	re, err := s.client.V1Refunds.Create(ctx, refundParams)
+	if err != nil {
+		return RefundResult{}, stripeError(err)
+	}
+
+	return RefundResult{ProviderRefundID: re.ID, Status: string(re.Status)}, nil
+}
+
+// stripeError wraps Stripe's answers that refuse a request in ErrRejected.
+// Rate limits, idempotency conflicts, server errors and network failures
+// are left as they are.
+func stripeError(err error) error {
+	var se *stripe.Error
+	if errors.As(err, &se) {
+		switch se.HTTPStatusCode {
+		case http.StatusBadRequest, http.StatusPaymentRequired, http.StatusNotFound:
+			return fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many takes pass between sweeps of full buckets
+const sweepEvery = 1024
+
+// MemoryStore keeps buckets in the process. Each instance counts on its own,
+// so behind a load balancer a client gets the limit once per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket is full again if left alone
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (m *MemoryStore) Take(_ context.Context, key string, l Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.takes++
+	if m.takes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.updated).Seconds()*l.Rate())
+	b.updated = now
+
+	res := Result{Allowed: b.tokens >= 1}
+	if res.Allowed {
+		b.tokens--
+	} else {
+		res.RetryAfter = l.retryAfter(b.tokens)
+	}
+	res.Remaining = remaining(b.tokens)
+	b.full = now.Add(time.Duration((float64(l.Burst) - b.tokens) / l.Rate() * float64(time.Second)))
+	return res, nil
+}
+
+// sweep forgets buckets that have filled up, which behave the same as new
+// ones. Callers hold mu.
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
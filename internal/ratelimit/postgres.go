@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// PruneInterval is how often the Postgres store deletes idle buckets
+const PruneInterval = time.Hour
+
+// PostgresStore keeps buckets in the rate_limits table so every instance
+// shares them. Refilling and taking a token is one upsert, timed by the
+// database clock.
+type PostgresStore struct {
+	DB     db.Store
+	MaxAge time.Duration // buckets idle this long are full and get pruned
+}
+
+func NewPostgresStore(conn db.Store, maxAge time.Duration) *PostgresStore {
+	return &PostgresStore{DB: conn, MaxAge: maxAge}
+}
+
+func (p *PostgresStore) Take(ctx context.Context, key string, l Limit) (Result, error) {
+	row, err := db.New(p.DB).TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(l.Burst),
+		Rate:  l.Rate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Allowed: row.Allowed, Remaining: remaining(row.Tokens)}
+	if !res.Allowed {
+		res.RetryAfter = l.retryAfter(row.Tokens)
+	}
+	return res, nil
+}
+
+// Run prunes idle buckets every PruneInterval until ctx is cancelled
+func (p *PostgresStore) Run(ctx context.Context) {
+	if p.MaxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := db.New(p.DB).DeleteStaleRateLimits(ctx, pgtype.Timestamptz{Time: time.Now().Add(-p.MaxAge), Valid: true})
+		if err != nil {
+			slog.ErrorContext(ctx, "Rate limit prune error", "err", err)
+			continue
+		}
+		if n > 0 {
+			slog.DebugContext(ctx, "Pruned idle rate limit buckets", "buckets", n)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package ratelimit throttles the endpoints scripts abuse: creating carts,
+// logging in, adding items and looking up orders. Each limit is a token
+// bucket kept per client IP, cart or account, in memory for a single
+// instance or in Postgres when several instances share the traffic.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+// Backends
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Limit is a token bucket holding Burst requests that refills at
+// Burst/Per. The zero Limit is off.
+type Limit struct {
+	Burst int           // requests allowed at once
+	Per   time.Duration // time for an empty bucket to fill up again
+}
+
+// ParseLimit reads "<requests>/<period>", e.g. "10/m" or "5/15m", with an
+// optional burst after a colon, e.g. "60/m:10". "off" and "0" disable it.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" || s == "0" {
+		return Limit{}, nil
+	}
+	rate, burst, hasBurst := strings.Cut(s, ":")
+	count, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit must look like 10/m, got %q", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("rate limit must start with a positive count, got %q", s)
+	}
+	// "m" means one minute
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("rate limit period must be s, m, h or a duration like 15m, got %q", s)
+	}
+
+	l := Limit{Burst: n, Per: per}
+	if hasBurst {
+		b, err := strconv.Atoi(burst)
+		if err != nil || b <= 0 {
+			return Limit{}, fmt.Errorf("rate limit burst must be a positive count, got %q", s)
+		}
+		// keep the refill rate, only the bucket size changes
+		l.Per = time.Duration(float64(per) * float64(b) / float64(n))
+		l.Burst = b
+	}
+	return l, nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Per > 0
+}
+
+// Rate is the refill rate in requests per second
+func (l Limit) Rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Per)
+}
+
+// retryAfter is how long until a bucket holding tokens has a whole one
+func (l Limit) retryAfter(tokens float64) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / l.Rate() * float64(time.Second))
+}
+
+type Config struct {
+	Backend      string // BackendMemory or BackendPostgres
+	IP           Limit  // every /api request, per client IP
+	NewCart      Limit  // cart creation, per client IP
+	Login        Limit  // admin login attempts, per client IP
+	LoginAccount Limit  // admin login attempts, per email
+	CartAdd      Limit  // items added, per cart
+	OrderLookup  Limit  // order tracking and return requests, per client IP
+}
+
+// Defaults for a single storefront. The general per IP limit is off because
+// behind a proxy that doesn't set X-Forwarded-For every client shares one IP.
+var DefaultConfig = Config{
+	Backend:      BackendMemory,
+	NewCart:      Limit{Burst: 10, Per: time.Minute},
+	Login:        Limit{Burst: 10, Per: time.Minute},
+	LoginAccount: Limit{Burst: 5, Per: 15 * time.Minute},
+	CartAdd:      Limit{Burst: 60, Per: time.Minute},
+	OrderLookup:  Limit{Burst: 20, Per: time.Minute},
+}
+
+// longest is the longest time any configured bucket takes to fill up. A
+// bucket idle for longer is full and can be forgotten.
+func (c Config) longest() time.Duration {
+	var d time.Duration
+	for _, l := range []Limit{c.IP, c.NewCart, c.Login, c.LoginAccount, c.CartAdd, c.OrderLookup} {
+		if l.Enabled() && l.Per > d {
+			d = l.Per
+		}
+	}
+	return d
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left in the bucket
+	RetryAfter time.Duration // until the next token when not allowed
+}
+
+// Store keeps the buckets
+type Store interface {
+	// Take removes a token from key's bucket if there is one
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
+
+// NewStore returns the store for c.Backend. The Postgres store is also a
+// worker that prunes idle buckets.
+func NewStore(c Config, conn db.Store) Store {
+	if c.Backend == BackendPostgres {
+		return NewPostgresStore(conn, c.longest())
+	}
+	return NewMemoryStore()
+}
+
+func remaining(tokens float64) int {
+	return int(math.Max(0, math.Floor(tokens)))
+}
@@ -0,0 +1,204 @@
+// Package recovery finds carts that were left idle after the customer gave
+// an email at checkout and sends them a reminder with a signed link that
+// restores the cart cookie.
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/methods"
+	"github.com/petermazzocco/go-ecommerce-api/internal/notifications"
+)
+
+const (
+	DefaultInterval  = 15 * time.Minute
+	DefaultIdle      = time.Hour
+	DefaultMaxAge    = 72 * time.Hour
+	DefaultLinkTTL   = 7 * 24 * time.Hour
+	DefaultBatchSize = 100
+	DefaultBaseURL   = "http://localhost:8080"
+
+	// tokenPurpose keeps cart cookies from being accepted as restore links
+	tokenPurpose = "cart_recovery"
+)
+
+type Config struct {
+	Interval    time.Duration // zero disables the worker
+	Idle        time.Duration // how long a cart must be untouched
+	MaxAge      time.Duration // carts idle for longer are not reminded
+	LinkTTL     time.Duration
+	BatchSize   int
+	BaseURL     string // public URL of this API, used to build restore links
+	RedirectURL string // where a restored cart lands, e.g. the cart page
+	Key         string // signs restore links, the same key as the session JWTs
+}
+
+// Token signs a restore link for a cart with key
+func Token(key string, cartID uuid.UUID, ttl time.Duration) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("no key to sign cart recovery links")
+	}
+
+	claims := jwt.MapClaims{
+		"cartID":  cartID.String(),
+		"purpose": tokenPurpose,
+		"exp":     jwt.NewNumericDate(time.Now().Add(ttl)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+}
+
+// VerifyToken returns the cart a restore link was signed for
+func VerifyToken(key, token string) (uuid.UUID, error) {
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
+		return []byte(key), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != tokenPurpose {
+		return uuid.UUID{}, fmt.Errorf("not a cart recovery token")
+	}
+	id, _ := claims["cartID"].(string)
+	return uuid.Parse(id)
+}
+
+// Reminder is one recovery notification
+type Reminder struct {
+	CartID uuid.UUID
+	Email  string
+	Link   string
+	Items  []notifications.LineItem
+}
+
+// Notifier delivers recovery reminders to customers. conn is the worker's
+// store, the pool the API shares.
+type Notifier interface {
+	NotifyAbandonedCart(ctx context.Context, conn db.Store, r Reminder) error
+}
+
+// LogNotifier writes reminders to the log instead of sending them
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyAbandonedCart(ctx context.Context, conn db.Store, r Reminder) error {
+	slog.InfoContext(ctx, "Cart recovery reminder", "cart_id", r.CartID, "email", r.Email)
+	return nil
+}
+
+// EmailNotifier queues the abandoned cart email in the outbox
+type EmailNotifier struct{}
+
+func (EmailNotifier) NotifyAbandonedCart(ctx context.Context, conn db.Store, r Reminder) error {
+	_, err := notifications.Enqueue(ctx, db.New(conn), notifications.AbandonedCart, r.Email, notifications.AbandonedCartData{
+		Items:      r.Items,
+		RestoreURL: r.Link,
+	})
+	return err
+}
+
+// Worker periodically reminds customers about abandoned carts
+type Worker struct {
+	Config   Config
+	Store    db.Store
+	Notifier Notifier
+}
+
+func NewWorker(config Config, store db.Store, notifier Notifier) *Worker {
+	return &Worker{Config: config, Store: store, Notifier: notifier}
+}
+
+// Run sends reminders every Interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	if w.Config.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := w.RunOnce(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Cart recovery error", "err", err)
+		} else if sent > 0 {
+			slog.InfoContext(ctx, "Cart recovery reminders sent", "sent", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of reminders and returns how many were sent
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	carts, err := methods.FindAbandonedCarts(ctx, w.Store, w.Config.Idle, w.Config.MaxAge, w.Config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, cart := range carts {
+		claim, ok, err := methods.ClaimCartRecovery(ctx, w.Store, cart.ID, cart.Email)
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			continue
+		}
+
+		reminder := Reminder{CartID: cart.ID, Email: cart.Email}
+		reminder.Link, err = w.link(cart.ID)
+		if err == nil {
+			reminder.Items, err = cartItems(ctx, w.Store, cart.ID)
+		}
+		if err == nil {
+			err = w.Notifier.NotifyAbandonedCart(ctx, w.Store, reminder)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Cart recovery reminder failed", "cart_id", cart.ID, "err", err)
+			if err := methods.ReleaseCartRecovery(ctx, w.Store, claim.ID); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func cartItems(ctx context.Context, conn db.Store, cartID uuid.UUID) ([]notifications.LineItem, error) {
+	rows, err := methods.GetItems(ctx, conn, cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]notifications.LineItem, 0, len(rows))
+	for _, row := range rows {
+		price, _ := row.Price.Float64Value()
+		items = append(items, notifications.LineItem{
+			Name:     row.Name,
+			Quantity: row.Quantity,
+			Price:    price.Float64 * float64(row.Quantity),
+		})
+	}
+	return items, nil
+}
+
+func (w *Worker) link(cartID uuid.UUID) (string, error) {
+	token, err := Token(w.Config.Key, cartID, w.Config.LinkTTL)
+	if err != nil {
+		return "", err
+	}
+	return w.Config.BaseURL + "/api/cart/restore?token=" + url.QueryEscape(token), nil
+}
@@ -0,0 +1,304 @@
+// Package request decodes and validates write request bodies. Handlers
+// describe a body as a struct; Decode fills it from JSON or from a form and
+// checks its validate tags, so both encodings get the same rules and the
+// same per-field errors.
+//
+// Field names come from the json tag. A form tag overrides the name used for
+// form posts, e.g. for repeated keys like itemID. A slice of structs is read
+// from a form as parallel repeated keys, one per struct field.
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+)
+
+// MaxBodyBytes caps the size of a request body
+const MaxBodyBytes = 1 << 20
+
+// Decode reads r's body into dst, which must be a pointer to a struct, and
+// validates it. JSON is used when the Content-Type says so, otherwise the
+// body is parsed as a form.
+func Decode(r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(nil, r.Body, MaxBodyBytes)
+
+	var fields []apperr.FieldError
+	if IsJSON(r) {
+		if err := decodeJSON(r.Body, dst); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if fields, err = decodeForm(r, dst); err != nil {
+			return err
+		}
+	}
+
+	// a field that failed to parse isn't validated as well
+	failed := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		failed[f.Field] = true
+	}
+	for _, f := range Validate(dst) {
+		if !failed[f.Field] {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) > 0 {
+		return invalid(fields...)
+	}
+	return nil
+}
+
+// IsJSON reports whether the request body is JSON
+func IsJSON(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+func invalid(fields ...apperr.FieldError) error {
+	return apperr.Validation("Invalid request", fields...)
+}
+
+func decodeJSON(body io.Reader, dst any) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil || errors.Is(err, io.EOF) {
+		if dec.More() {
+			return apperr.Validation("Request body must be a single JSON object")
+		}
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &typeErr):
+		return invalid(apperr.Field(typeErr.Field, typeErr.Field+" must be "+describe(typeErr.Type)))
+	case errors.As(err, &maxErr):
+		return apperr.Validation("Request body is too large")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return invalid(apperr.Field(name, "Unknown field "+name))
+	default:
+		return apperr.Validation("Request body must be valid JSON")
+	}
+}
+
+func decodeForm(r *http.Request, dst any) ([]apperr.FieldError, error) {
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		err = r.ParseMultipartForm(MaxBodyBytes)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return nil, apperr.Validation("Could not read request body")
+	}
+
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+
+	var fields []apperr.FieldError
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, key, ok := fieldNames(f)
+		if !ok {
+			continue
+		}
+
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct {
+			fields = append(fields, setStructSlice(v.Field(i), name, r.Form)...)
+			continue
+		}
+
+		values, present := r.Form[key]
+		if !present {
+			continue
+		}
+		if err := setValues(v.Field(i), values); err != nil {
+			fields = append(fields, apperr.Field(name, name+" must be "+describe(f.Type)))
+		}
+	}
+	return fields, nil
+}
+
+// setStructSlice builds a slice of structs from parallel repeated form keys,
+// e.g. itemID=1&quantity=2&itemID=3&quantity=1
+func setStructSlice(field reflect.Value, name string, form map[string][]string) []apperr.FieldError {
+	elem := field.Type().Elem()
+
+	n := -1
+	for i := 0; i < elem.NumField(); i++ {
+		_, key, ok := fieldNames(elem.Field(i))
+		if !ok {
+			continue
+		}
+		if values, present := form[key]; present {
+			if n >= 0 && len(values) != n {
+				return []apperr.FieldError{apperr.Field(name, "Every "+singular(name)+" needs the same number of values")}
+			}
+			n = len(values)
+		}
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	var fields []apperr.FieldError
+	slice := reflect.MakeSlice(field.Type(), n, n)
+	for i := 0; i < elem.NumField(); i++ {
+		sub, key, ok := fieldNames(elem.Field(i))
+		if !ok {
+			continue
+		}
+		for j, v := range form[key] {
+			if err := setValues(slice.Index(j).Field(i), []string{v}); err != nil {
+				path := name + "[" + strconv.Itoa(j) + "]." + sub
+				fields = append(fields, apperr.Field(path, path+" must be "+describe(elem.Field(i).Type)))
+			}
+		}
+	}
+	field.Set(slice)
+	return fields
+}
+
+func setValues(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), 0, len(values))
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			item := reflect.New(field.Type().Elem()).Elem()
+			if err := setValue(item, v); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, item)
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, v string) error {
+	if field.Kind() == reflect.Pointer {
+		// an empty value only clears strings, e.g. description=
+		if v == "" && field.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), v); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	v = strings.TrimSpace(v)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(v)
+	case reflect.Bool:
+		if v == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(v, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return errors.New("unsupported field type " + field.Type().String())
+	}
+	return nil
+}
+
+// fieldNames returns the name used in errors and the form key for f
+func fieldNames(f reflect.StructField) (string, string, bool) {
+	if !f.IsExported() {
+		return "", "", false
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	key := name
+	if tag := f.Tag.Get("form"); tag != "" {
+		key = tag
+	}
+	return name, key, true
+}
+
+// describe names the kind of value a field expects, for error messages
+func describe(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "a list of " + plural(t.Elem())
+	case reflect.Struct:
+		return "an object"
+	default:
+		return "a string"
+	}
+}
+
+func plural(t reflect.Type) string {
+	switch describe(t) {
+	case "a whole number":
+		return "whole numbers"
+	case "a number":
+		return "numbers"
+	case "an object":
+		return "objects"
+	case "true or false":
+		return "booleans"
+	default:
+		return "strings"
+	}
+}
+
+func singular(name string) string {
+	return strings.TrimSuffix(name, "s")
+}
@@ -0,0 +1,176 @@
+package request
+
+import (
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+)
+
+// Validate checks dst's validate tags and returns an error for each field
+// that breaks a rule. Rules are comma separated:
+//
+//	required   set, non-zero and not blank; for a pointer, present
+//	min=N      numbers at least N, strings and lists at least N long
+//	max=N      numbers at most N, strings and lists at most N long
+//	len=N      strings exactly N long
+//	email      an email address
+//	url        an absolute http or https URL
+//	oneof=a b  one of the space separated values
+//
+// Rules other than required skip strings and lists that are empty. Slices
+// of structs are checked element by element.
+func Validate(dst any) []apperr.FieldError {
+	v := reflect.ValueOf(dst)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	return validateStruct(v, "")
+}
+
+func validateStruct(v reflect.Value, prefix string) []apperr.FieldError {
+	var fields []apperr.FieldError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, ok := fieldNames(f)
+		if !ok {
+			continue
+		}
+		path := prefix + name
+
+		fv := v.Field(i)
+		if msg := checkRules(fv, f.Tag.Get("validate")); msg != "" {
+			fields = append(fields, apperr.Field(path, path+" "+msg))
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct {
+			for j := 0; j < fv.Len(); j++ {
+				fields = append(fields, validateStruct(fv.Index(j), path+"["+strconv.Itoa(j)+"].")...)
+			}
+		}
+	}
+	return fields
+}
+
+// checkRules returns why v breaks the rules, or "" when it doesn't
+func checkRules(v reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+
+	rules := strings.Split(tag, ",")
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			for _, rule := range rules {
+				if rule == "required" {
+					return "is required"
+				}
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name != "required" && isEmpty(v) {
+			continue
+		}
+
+		switch name {
+		case "required":
+			if isZero(v) {
+				return "is required"
+			}
+		case "min":
+			if n, _ := strconv.ParseFloat(arg, 64); size(v) < n {
+				return "must be at least " + arg + unit(v)
+			}
+		case "max":
+			if n, _ := strconv.ParseFloat(arg, 64); size(v) > n {
+				return "must be at most " + arg + unit(v)
+			}
+		case "len":
+			if n, _ := strconv.Atoi(arg); v.Len() != n {
+				return "must be exactly " + arg + " characters"
+			}
+		case "email":
+			if _, err := mail.ParseAddress(v.String()); err != nil {
+				return "must be a valid email address"
+			}
+		case "url":
+			if u, err := url.Parse(v.String()); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return "must be an absolute http or https URL"
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			if !contains(allowed, v.String()) {
+				return "must be one of " + strings.Join(allowed, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// isEmpty reports whether a string or list was left out. Numbers are always
+// checked; use a pointer for an optional number.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return isZero(v)
+	default:
+		return false
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// size is a number's value or the length of a string or list
+func size(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return float64(len([]rune(v.String())))
+	case reflect.Slice, reflect.Map:
+		return float64(v.Len())
+	default:
+		return 0
+	}
+}
+
+func unit(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
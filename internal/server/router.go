@@ -0,0 +1,295 @@
+// Package server wires the handlers into the API's router
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/petermazzocco/go-ecommerce-api/internal/apperr"
+	"github.com/petermazzocco/go-ecommerce-api/internal/handlers"
+	"github.com/petermazzocco/go-ecommerce-api/internal/idempotency"
+	"github.com/petermazzocco/go-ecommerce-api/internal/logging"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+	"github.com/petermazzocco/go-ecommerce-api/internal/openapi"
+	"github.com/petermazzocco/go-ecommerce-api/internal/ratelimit"
+	"github.com/petermazzocco/go-ecommerce-api/internal/tracing"
+)
+
+// NewRouter builds the chi router for every API route and the OpenAPI
+// document describing them
+func NewRouter(app *handlers.App) (*chi.Mux, error) {
+	// Served once the router is complete and the document has been generated
+	var openAPIHandler http.HandlerFunc
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP)
+	r.Use(tracing.Middleware)
+	r.Use(logging.AccessLog(app.Logger))
+	r.Use(metrics.Middleware)
+	r.Use(middleware.Recoverer)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		apperr.Write(w, r, apperr.NotFound("Route not found"))
+	})
+
+	// Probes for orchestrators and load balancers
+	r.Get("/healthz", app.LivenessHandler)
+	r.Get("/readyz", app.ReadinessHandler)
+
+	// Prometheus metrics, here only when they aren't on an internal port
+	if m := app.Config.Metrics; m.Addr == "" && m.Token != "" {
+		r.Get("/metrics", metrics.Handler(m.Token))
+	}
+
+	limits := app.Config.RateLimits
+	limit := func(name string, l ratelimit.Limit, key ratelimit.KeyFunc) func(http.Handler) http.Handler {
+		return ratelimit.Middleware(app.Limits, name, l, key)
+	}
+
+	r.Route("/api", func(r chi.Router) {
+		// Every request from one address, behind RealIP
+		r.Use(limit("ip", limits.IP, ratelimit.ByIP))
+
+		// Health check
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Ecommerce API"))
+		})
+
+		// OpenAPI document for every route below, and a docs page to browse it
+		r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+			openAPIHandler(w, r)
+		})
+		r.Get("/docs", openapi.DocsHandler)
+
+		// Portal login for admin users
+		r.Route("/auth", func(r chi.Router) {
+			r.With(limit("login", limits.Login, ratelimit.ByIP)).Post("/login", app.LoginHandler)
+			r.Post("/logout", app.LogoutHandler)
+			// New access token for the admin session, rotating its refresh token
+			r.Post("/refresh", app.RefreshAdminSessionHandler)
+
+			// Second step of the login for admins with two-factor authentication
+			r.Route("/mfa", func(r chi.Router) {
+				r.Use(limit("login", limits.Login, ratelimit.ByIP))
+				r.Post("/setup", app.StartMFASetupHandler)
+				r.Post("/enable", app.EnableMFAHandler)
+				r.Post("/verify", app.VerifyMFAHandler)
+			})
+		})
+
+		// Admin route group to require admin role
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.Auth.AdminMiddleware) // Require each route has a valid JWT with a maps claim to an admin role
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("Admin Portal"))
+			})
+			r.Route("/users", func(r chi.Router) {
+				r.Post("/register", app.RegisterAdminUserHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetUserHandler)
+					r.Delete("/", app.DeleteUserHandler)
+					// Take away the admin role and revoke the user's sessions
+					r.Post("/deactivate", app.DeactivateUserHandler)
+					// Turn off two-factor authentication for an admin locked out of it
+					r.Delete("/mfa", app.ResetMFAHandler)
+				})
+			})
+
+			// Products route group to manage products
+			r.Route("/products", func(r chi.Router) {
+				r.Get("/", app.ListProductsHandler)
+				r.Post("/", app.CreateProductHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetProductHandler)
+					r.Put("/", app.UpdateProductHandler)
+					r.Delete("/", app.DeleteProductHandler)
+					// Set the stock of a size
+					r.Put("/sizes/{size}", app.UpdateProductStockHandler)
+				})
+			})
+
+			// Tax rates route group to manage the tax rule table
+			r.Route("/tax-rates", func(r chi.Router) {
+				r.Get("/", app.ListTaxRatesHandler)
+				r.Post("/", app.CreateTaxRateHandler)
+				r.Delete("/{id}", app.DeleteTaxRateHandler)
+			})
+
+			// Shipping route group to manage zones and their methods
+			r.Route("/shipping", func(r chi.Router) {
+				r.Route("/zones", func(r chi.Router) {
+					r.Get("/", app.ListShippingZonesHandler)
+					r.Post("/", app.CreateShippingZoneHandler)
+					r.Route("/{id}", func(r chi.Router) {
+						r.Delete("/", app.DeleteShippingZoneHandler)
+						r.Post("/methods", app.CreateShippingMethodHandler)
+					})
+				})
+				r.Route("/methods/{id}", func(r chi.Router) {
+					r.Put("/", app.UpdateShippingMethodHandler)
+					r.Delete("/", app.DeleteShippingMethodHandler)
+				})
+			})
+
+			// Orders route group to review orders
+			r.Route("/orders", func(r chi.Router) {
+				r.Get("/", app.ListOrdersHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetOrderHandler)
+					// Move the order through its state machine
+					r.Post("/status", app.UpdateOrderStatusHandler)
+					// Full or partial refunds through the payment provider
+					r.Get("/refunds", app.ListRefundsHandler)
+					r.Post("/refunds", app.CreateRefundHandler)
+					r.Post("/refunds/{refundID}/retry", app.RetryRefundHandler)
+					// Ship some or all of the order's items
+					r.Post("/fulfillments", app.CreateFulfillmentHandler)
+					r.Put("/fulfillments/{fulfillmentID}", app.UpdateFulfillmentHandler)
+				})
+			})
+
+			// Recent emails in the outbox with their delivery status
+			r.Get("/emails", app.ListEmailsHandler)
+
+			// Change the logged in admin's password
+			r.Put("/password", app.ChangePasswordHandler)
+
+			// The logged in admin's two-factor authentication
+			r.Route("/mfa", func(r chi.Router) {
+				r.Get("/", app.MFAStatusHandler)
+				r.Post("/recovery-codes", app.RegenerateRecoveryCodesHandler)
+			})
+
+			// Logins, failures, lockouts and password changes
+			r.Get("/security-events", app.ListSecurityEventsHandler)
+
+			// Recent domain events in the outbox with their dispatch status
+			r.Get("/events", app.ListEventsHandler)
+
+			// Webhooks route group to manage outbound webhook endpoints
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Get("/", app.ListWebhooksHandler)
+				r.Post("/", app.CreateWebhookHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetWebhookHandler)
+					r.Put("/", app.UpdateWebhookHandler)
+					r.Delete("/", app.DeleteWebhookHandler)
+				})
+				// Delivery log with every attempt, and manual redelivery
+				r.Route("/deliveries/{deliveryID}", func(r chi.Router) {
+					r.Get("/", app.GetWebhookDeliveryHandler)
+					r.Post("/redeliver", app.RedeliverWebhookHandler)
+				})
+			})
+
+			// Background job health
+			r.Get("/maintenance/cart-gc", app.CartGCStatsHandler)
+
+			// Reports route group for store analytics
+			r.Route("/reports", func(r chi.Router) {
+				r.Get("/cart-recovery", app.CartRecoveryReportHandler)
+			})
+
+			// Returns route group to review customer return requests
+			r.Route("/returns", func(r chi.Router) {
+				r.Get("/", app.ListReturnsHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetReturnHandler)
+					r.Post("/approve", app.ApproveReturnHandler)
+					r.Post("/reject", app.RejectReturnHandler)
+				})
+			})
+
+			// Collections route group to manage collections
+			r.Route("/collections", func(r chi.Router) {
+				r.Get("/", app.GetCollectionsHandler)
+				r.Post("/", app.CreateCollectionHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetCollectionByIDHandler)
+					r.Put("/", app.UpdateCollectionByIDHandler)
+					r.Delete("/", app.DeleteCollectionByIDHandler)
+					r.Route("/product", func(r chi.Router) {
+						// Add or remove products from a collection
+						r.Route("/{productID}", func(r chi.Router) {
+							r.Post("/", app.AddProductToCollectionHandler)
+							r.Delete("/", app.RemoveProductFromCollectionHandler)
+						})
+					})
+				})
+			})
+		})
+
+		// Public facing products route group to return product information
+		r.Route("/products", func(r chi.Router) {
+			r.Get("/", app.ListProductsHandler)
+			r.Get("/{id}", app.GetProductHandler)
+		})
+
+		// Public facing collections route group to return collection information
+		r.Route("/collections", func(r chi.Router) {
+			r.Get("/", app.GetCollectionsHandler)
+			r.Get("/{id}", app.GetCollectionByIDHandler)
+		})
+
+		// Creates a new cart with a unique ID that is stored in a cookie with a JWT for authentication
+		r.With(limit("new_cart", limits.NewCart, ratelimit.ByIP)).Post("/new-cart", app.NewCartHandler)
+
+		// Order confirmation for customers returning from Stripe checkout
+		r.Get("/checkout/confirmation", app.CheckoutConfirmationHandler)
+
+		// Signed Stripe events that mark orders paid when checkout completes
+		r.Post("/stripe/webhook", app.StripeWebhookHandler)
+
+		// Customers track their order with the order number and checkout email
+		r.With(limit("order_lookup", limits.OrderLookup, ratelimit.ByIP)).Get("/orders/track", app.TrackOrderHandler)
+
+		// Customers request returns with their order ID and email
+		r.With(limit("order_lookup", limits.OrderLookup, ratelimit.ByIP)).Post("/returns", app.CreateReturnHandler)
+
+		// Signed link from a recovery reminder that sets the cart cookie again
+		r.Get("/cart/restore", app.RestoreCartHandler)
+
+		// New access token for the cart session once the old one expired
+		r.Post("/cart/refresh", app.RefreshCartSessionHandler)
+
+		// Cart route group requires a valid JWT and cart session ID
+		r.Route("/cart", func(r chi.Router) {
+			r.Use(app.Auth.CartMiddleware)                                                     // Require each route has a valid JWT and cart session ID
+			r.Use(idempotency.Middleware(app.Store, app.Config.IdempotencyTTL, app.CartScope)) // Replay retried writes sent with an Idempotency-Key
+			r.Get("/", app.GetCartProductsHandler)
+			r.Delete("/", app.ClearCartHandler)
+			r.With(limit("cart_add", limits.CartAdd, app.CartScope)).Post("/add", app.AddItemHandler)
+			// Subtotal, tax lines and total for a destination address
+			r.Get("/summary", app.GetCartSummaryHandler)
+			// Shipping options for a destination address
+			r.Get("/shipping-rates", app.GetShippingRatesHandler)
+			// Product ID in the cart to update quan or remove
+			r.Route("/{productID}", func(r chi.Router) {
+				r.Put("/", app.UpdateItemQuantityHandler)
+				r.Delete("/", app.RemoveItemHandler)
+			})
+			// Create a Stripe check out session
+			r.Post("/checkout", app.CreateCheckoutSession)
+		})
+	})
+
+	// Describe the router. Undocumented routes are caught by the router
+	// test, so here they are only logged and left out of the document.
+	doc, err := openapi.Generate(r, app.Auth.Config)
+	if doc == nil {
+		return nil, err
+	}
+	if err != nil {
+		app.Logger.Warn(err.Error())
+	}
+	if openAPIHandler, err = openapi.Handler(doc); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
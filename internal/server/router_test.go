@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/petermazzocco/go-ecommerce-api/internal/auth"
+	"github.com/petermazzocco/go-ecommerce-api/internal/handlers"
+	"github.com/petermazzocco/go-ecommerce-api/internal/metrics"
+	"github.com/petermazzocco/go-ecommerce-api/internal/openapi"
+)
+
+// newTestRouter builds the full router with every optional route on. No
+// request is served, so it needs no database or payment provider.
+func newTestRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+	app := handlers.NewApp(handlers.Config{
+		Auth:    auth.Config{JWTKey: "test", CookieName: "cart", AdminCookieName: "admin"},
+		Metrics: metrics.Config{Token: "test"},
+	}, nil, nil, nil)
+	r, err := NewRouter(app)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	return r
+}
+
+// TestOpenAPIMatchesRouter fails when a route is added without an entry in
+// internal/openapi/operations.go, or an entry outlives its route
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	r := newTestRouter(t)
+
+	doc, err := openapi.Generate(r, auth.Config{CookieName: "cart", AdminCookieName: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = chi.Walk(r, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if doc.Paths[pattern][strings.ToLower(method)] == nil {
+			t.Errorf("%s %s is missing from the OpenAPI document", method, pattern)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
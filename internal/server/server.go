@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAddr              = ":8080"
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 15 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 60 * time.Second
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+type Config struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // how long in-flight requests and workers get to finish
+	DrainDelay        time.Duration // how long /readyz fails before the listener closes
+}
+
+// Worker is a background job that runs until its context is cancelled
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+// Listener is an extra server, e.g. for metrics, that runs beside the main
+// one and stops with the workers. If it fails, the API stops as it does when
+// the main listener fails.
+type Listener interface {
+	Serve(ctx context.Context) error
+}
+
+// Server runs the HTTP server and the background workers together
+type Server struct {
+	Config    Config
+	Handler   http.Handler
+	Workers   []Worker
+	Listeners []Listener
+	OnDrain   func() // called as soon as shutdown starts, e.g. to fail readiness
+}
+
+// Run serves until ctx is cancelled, then shuts down in order: OnDrain, the
+// drain delay so load balancers stop sending traffic, in-flight requests,
+// and finally the workers. It returns early if any listener fails.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.Config.Addr,
+		Handler:           s.Handler,
+		ReadHeaderTimeout: s.Config.ReadHeaderTimeout,
+		ReadTimeout:       s.Config.ReadTimeout,
+		WriteTimeout:      s.Config.WriteTimeout,
+		IdleTimeout:       s.Config.IdleTimeout,
+	}
+
+	// Room for every listener, so a failing one never blocks
+	serveErr := make(chan error, len(s.Listeners)+1)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var wg sync.WaitGroup
+	for _, w := range s.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Run(workerCtx)
+		}()
+	}
+	for _, l := range s.Listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Serve(workerCtx); err != nil {
+				serveErr <- err
+			}
+		}()
+	}
+
+	go func() {
+		slog.Info("Listening", "addr", s.Config.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		// a listener failed, e.g. the port is taken
+		srv.Close()
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+		if s.OnDrain != nil {
+			s.OnDrain()
+		}
+		time.Sleep(s.Config.DrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.Config.ShutdownTimeout)
+		defer cancel()
+		if err = srv.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Error draining requests", "err", err)
+			srv.Close()
+		}
+	}
+
+	stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(s.Config.ShutdownTimeout):
+		slog.Warn("Workers did not stop within the shutdown timeout")
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
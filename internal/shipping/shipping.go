@@ -0,0 +1,152 @@
+// Package shipping matches a destination to an admin defined shipping zone
+// and quotes the zone's methods for a cart. All amounts are in the smallest
+// currency unit (cents) and weights are in grams.
+package shipping
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	TypeFlat   = "flat"
+	TypeWeight = "weight"
+	TypePrice  = "price"
+)
+
+func ValidType(t string) bool {
+	return t == TypeFlat || t == TypeWeight || t == TypePrice
+}
+
+type Destination struct {
+	Country    string `json:"country"`
+	State      string `json:"state"`
+	PostalCode string `json:"postalCode"`
+}
+
+// Region is a country, or a single state when State is set
+type Region struct {
+	Country string `json:"country"`
+	State   string `json:"state"`
+}
+
+type Zone struct {
+	ID      int32    `json:"id"`
+	Name    string   `json:"name"`
+	Regions []Region `json:"regions"`
+}
+
+// Tier charges Rate once the cart weight (grams) or subtotal (cents) reaches Min
+type Tier struct {
+	Min  int64 `json:"min"`
+	Rate int64 `json:"rate"`
+}
+
+type Method struct {
+	ID     int32  `json:"id"`
+	ZoneID int32  `json:"zoneId"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Rate   int64  `json:"rate"`
+	// FreeOver makes the method free once the subtotal reaches it, 0 disables it
+	FreeOver int64  `json:"freeOver"`
+	MinDays  int32  `json:"minDays"`
+	MaxDays  int32  `json:"maxDays"`
+	Tiers    []Tier `json:"tiers"`
+	Active   bool   `json:"active"`
+}
+
+type Cart struct {
+	Subtotal    int64
+	WeightGrams int64
+}
+
+type Rate struct {
+	MethodID int32  `json:"methodId"`
+	Zone     string `json:"zone"`
+	Name     string `json:"name"`
+	Amount   int64  `json:"amount"`
+	MinDays  int32  `json:"minDays"`
+	MaxDays  int32  `json:"maxDays"`
+}
+
+// MatchZone returns the zone covering a destination. A zone listing the
+// destination's state wins over one that only lists its country.
+func MatchZone(zones []Zone, dest Destination) (Zone, bool) {
+	var match *Zone
+	var specific bool
+	for i := range zones {
+		for _, r := range zones[i].Regions {
+			if !strings.EqualFold(r.Country, dest.Country) {
+				continue
+			}
+			if r.State != "" && !strings.EqualFold(r.State, dest.State) {
+				continue
+			}
+			if match == nil || (r.State != "" && !specific) {
+				match = &zones[i]
+				specific = r.State != ""
+			}
+		}
+	}
+	if match == nil {
+		return Zone{}, false
+	}
+	return *match, true
+}
+
+// Quote prices every active method of a zone for the cart, cheapest first.
+// Tiered methods without a tier for the cart are skipped.
+func Quote(zone Zone, methods []Method, cart Cart) []Rate {
+	rates := []Rate{}
+	for _, m := range methods {
+		if m.ZoneID != zone.ID || !m.Active {
+			continue
+		}
+
+		amount, ok := price(m, cart)
+		if !ok {
+			continue
+		}
+		if m.FreeOver > 0 && cart.Subtotal >= m.FreeOver {
+			amount = 0
+		}
+
+		rates = append(rates, Rate{
+			MethodID: m.ID,
+			Zone:     zone.Name,
+			Name:     m.Name,
+			Amount:   amount,
+			MinDays:  m.MinDays,
+			MaxDays:  m.MaxDays,
+		})
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool { return rates[i].Amount < rates[j].Amount })
+	return rates
+}
+
+func price(m Method, cart Cart) (int64, bool) {
+	switch m.Type {
+	case TypeWeight:
+		return tierRate(m.Tiers, cart.WeightGrams)
+	case TypePrice:
+		return tierRate(m.Tiers, cart.Subtotal)
+	default:
+		return m.Rate, true
+	}
+}
+
+// tierRate picks the tier with the highest minimum that the value reaches
+func tierRate(tiers []Tier, value int64) (int64, bool) {
+	var best *Tier
+	for i := range tiers {
+		if value >= tiers[i].Min && (best == nil || tiers[i].Min > best.Min) {
+			best = &tiers[i]
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.Rate, true
+}
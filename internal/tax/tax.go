@@ -0,0 +1,224 @@
+// Package tax calculates sales tax for a cart. A Calculator is chosen at
+// runtime: the built-in RuleCalculator matches rates from the tax_rates
+// table, while StripeCalculator defers the calculation to Stripe Tax at
+// checkout. All amounts are in the smallest currency unit (cents).
+package tax
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Category applied to products that don't set one
+const DefaultCategory = "standard"
+
+const (
+	ProviderRules  = "rules"
+	ProviderStripe = "stripe"
+)
+
+type Address struct {
+	Country    string `json:"country"`
+	State      string `json:"state"`
+	PostalCode string `json:"postalCode"`
+}
+
+type Item struct {
+	ProductID  int32  `json:"productId"`
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	UnitAmount int64  `json:"unitAmount"`
+	Quantity   int64  `json:"quantity"`
+}
+
+type Request struct {
+	Address Address `json:"address"`
+	Items   []Item  `json:"items"`
+}
+
+// Line is the tax collected for a single rule across every item it applies to
+type Line struct {
+	Name          string  `json:"name"`
+	Jurisdiction  string  `json:"jurisdiction"`
+	Rate          float64 `json:"rate"`
+	TaxableAmount int64   `json:"taxableAmount"`
+	Amount        int64   `json:"amount"`
+}
+
+type Result struct {
+	Provider  string `json:"provider"`
+	Inclusive bool   `json:"inclusive"`
+	// Deferred is set when the provider calculates tax during payment instead
+	Deferred bool   `json:"deferred"`
+	Lines    []Line `json:"lines"`
+	Total    int64  `json:"total"`
+}
+
+type Calculator interface {
+	Calculate(ctx context.Context, req Request) (Result, error)
+}
+
+// Rule is a single row of the rate table. Empty State, PostalPrefix or
+// Category act as wildcards. Rate is a percentage, e.g. 7.25.
+type Rule struct {
+	ID           int32
+	Name         string
+	Country      string
+	State        string
+	PostalPrefix string
+	Category     string
+	Rate         float64
+}
+
+func (r Rule) jurisdiction() string {
+	parts := []string{strings.ToUpper(r.Country)}
+	if r.State != "" {
+		parts = append(parts, strings.ToUpper(r.State))
+	}
+	if r.PostalPrefix != "" {
+		parts = append(parts, r.PostalPrefix+"*")
+	}
+	return strings.Join(parts, "-")
+}
+
+// RuleCalculator applies the rate table. Rules for the country, the state and
+// the longest matching postal prefix stack on top of each other; within each
+// of those levels a rule for the product's category wins over the generic one,
+// so a 0% category rule acts as an exemption.
+type RuleCalculator struct {
+	Rules     []Rule
+	Inclusive bool
+}
+
+func NewRuleCalculator(rules []Rule, inclusive bool) *RuleCalculator {
+	return &RuleCalculator{Rules: rules, Inclusive: inclusive}
+}
+
+func (c *RuleCalculator) Calculate(ctx context.Context, req Request) (Result, error) {
+	res := Result{Provider: ProviderRules, Inclusive: c.Inclusive, Lines: []Line{}}
+
+	type acc struct {
+		rule    Rule
+		taxable float64
+		amount  float64
+	}
+	lines := map[int32]*acc{}
+	var order []int32
+
+	for _, item := range req.Items {
+		rules := c.match(req.Address, item.Category)
+		if len(rules) == 0 {
+			continue
+		}
+
+		var combined float64
+		for _, r := range rules {
+			combined += r.Rate / 100
+		}
+
+		gross := float64(item.UnitAmount * item.Quantity)
+		taxable := gross
+		if c.Inclusive {
+			taxable = gross / (1 + combined)
+		}
+
+		for _, r := range rules {
+			a, ok := lines[r.ID]
+			if !ok {
+				a = &acc{rule: r}
+				lines[r.ID] = a
+				order = append(order, r.ID)
+			}
+			a.taxable += taxable
+			a.amount += taxable * r.Rate / 100
+		}
+	}
+
+	for _, id := range order {
+		a := lines[id]
+		amount := int64(math.Round(a.amount))
+		res.Lines = append(res.Lines, Line{
+			Name:          a.rule.Name,
+			Jurisdiction:  a.rule.jurisdiction(),
+			Rate:          a.rule.Rate,
+			TaxableAmount: int64(math.Round(a.taxable)),
+			Amount:        amount,
+		})
+		res.Total += amount
+	}
+
+	return res, nil
+}
+
+// match returns the rules that apply to an address and product category
+func (c *RuleCalculator) match(addr Address, category string) []Rule {
+	if category == "" {
+		category = DefaultCategory
+	}
+	postal := strings.ReplaceAll(strings.ToUpper(addr.PostalCode), " ", "")
+
+	// level 0 = country, 1 = state, 2 = postal prefix
+	var best [3]*Rule
+	var bestPrefix int
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if !strings.EqualFold(r.Country, addr.Country) {
+			continue
+		}
+		if r.Category != "" && !strings.EqualFold(r.Category, category) {
+			continue
+		}
+		if r.State != "" && !strings.EqualFold(r.State, addr.State) {
+			continue
+		}
+
+		level := 0
+		if r.PostalPrefix != "" {
+			prefix := strings.ReplaceAll(strings.ToUpper(r.PostalPrefix), " ", "")
+			if !strings.HasPrefix(postal, prefix) {
+				continue
+			}
+			// only the most specific postal area applies
+			if len(prefix) < bestPrefix {
+				continue
+			}
+			if len(prefix) > bestPrefix {
+				best[2] = nil
+				bestPrefix = len(prefix)
+			}
+			level = 2
+		} else if r.State != "" {
+			level = 1
+		}
+
+		if cur := best[level]; cur == nil || (cur.Category == "" && r.Category != "") {
+			best[level] = r
+		}
+	}
+
+	var rules []Rule
+	for _, r := range best {
+		if r != nil && r.Rate > 0 {
+			rules = append(rules, *r)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// StripeCalculator leaves tax to Stripe Tax, which is enabled on the checkout
+// session and computed once the customer enters their address
+type StripeCalculator struct {
+	Inclusive bool
+}
+
+func (c *StripeCalculator) Calculate(ctx context.Context, req Request) (Result, error) {
+	return Result{
+		Provider:  ProviderStripe,
+		Inclusive: c.Inclusive,
+		Deferred:  true,
+		Lines:     []Line{},
+	}, nil
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/payments"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Payments wraps a payment provider with a span per call. The provider's
+// own HTTP requests show up beneath it when its client uses Transport.
+func Payments(p payments.Provider) payments.Provider {
+	return tracedProvider{p}
+}
+
+type tracedProvider struct {
+	payments.Provider
+}
+
+func (t tracedProvider) Refund(ctx context.Context, req payments.RefundRequest) (payments.RefundResult, error) {
+	ctx, span := tracer().Start(ctx, "payments.Refund")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("order.id", int(req.OrderID)),
+		attribute.Int("refund.id", int(req.RefundID)),
+		attribute.Int64("refund.amount", req.Amount),
+	)
+
+	res, err := t.Provider.Refund(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	span.SetAttributes(attribute.String("refund.status", res.Status))
+	return res, nil
+}
+
+func (t tracedProvider) CreateCheckout(ctx context.Context, req payments.CheckoutRequest) (payments.CheckoutSession, error) {
+	ctx, span := tracer().Start(ctx, "payments.CreateCheckout")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("cart.id", req.CartID),
+		attribute.Int("checkout.items", len(req.Items)),
+	)
+
+	res, err := t.Provider.CreateCheckout(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	span.SetAttributes(attribute.String("checkout.session_id", res.ID))
+	return res, nil
+}
+
+func (t tracedProvider) GetCheckout(ctx context.Context, sessionID string) (payments.CheckoutStatus, error) {
+	ctx, span := tracer().Start(ctx, "payments.GetCheckout")
+	defer span.End()
+	span.SetAttributes(attribute.String("checkout.session_id", sessionID))
+
+	res, err := t.Provider.GetCheckout(ctx, sessionID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	span.SetAttributes(attribute.String("checkout.payment_status", res.PaymentStatus))
+	return res, nil
+}
+
+// Ready passes the readiness check through to the wrapped provider
+func (t tracedProvider) Ready() error {
+	if r, ok := t.Provider.(interface{ Ready() error }); ok {
+		return r.Ready()
+	}
+	return nil
+}
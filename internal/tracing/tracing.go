@@ -0,0 +1,76 @@
+// Package tracing sets up OpenTelemetry tracing: a span per HTTP request,
+// per database query and per payment provider call, exported over OTLP and
+// linked to callers through the W3C traceparent header.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultServiceName = "ecommerce-api"
+
+	// Exporters
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// instrumentation names the tracer every span in the API comes from
+const instrumentation = "github.com/petermazzocco/go-ecommerce-api"
+
+type Config struct {
+	Exporter    string  // ExporterOTLP or ExporterNone
+	Endpoint    string  // OTLP/HTTP collector URL, localhost:4318 when empty
+	ServiceName string  // service.name on every span
+	SampleRatio float64 // share of new traces kept, callers' decisions are followed
+}
+
+// Setup installs the global tracer provider and the traceparent propagator.
+// The returned function flushes spans still buffered and must be called
+// before exiting. With the exporter off spans are never recorded, but
+// incoming trace context is still passed on.
+func Setup(ctx context.Context, c Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if c.Exporter != ExporterOTLP {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if c.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(c.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
@@ -0,0 +1,98 @@
+// Package webhooks delivers store events to admin-managed HTTP endpoints.
+// Subscriber runs on the domain event bus and queues a delivery for every
+// enabled endpoint listening to the event. A Worker posts due deliveries with
+// an HMAC signature, retries failures with exponential backoff and disables
+// endpoints that keep failing.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+	"github.com/petermazzocco/go-ecommerce-api/internal/events"
+)
+
+// Headers sent with every delivery
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// AllEvents subscribes an endpoint to every event type
+const AllEvents = "*"
+
+// Payload is the JSON body posted to endpoints
+type Payload struct {
+	ID        int32           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func ValidEventType(t string) bool {
+	return t == AllEvents || events.ValidType(t)
+}
+
+// NewSecret returns a random signing secret for a new endpoint
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">"
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := timestamp.Unix()
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Subscriber queues a delivery of the event for every enabled endpoint that
+// listens to it. Deliveries are unique per endpoint and event, so running it
+// again for the same event queues nothing new.
+func Subscriber(ctx context.Context, conn db.Store, e events.Envelope) error {
+	q := db.New(conn)
+
+	endpoints, err := q.ListWebhookEndpointsForEvent(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:        e.ID,
+		Type:      e.Type,
+		CreatedAt: e.OccurredAt,
+		Data:      e.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if err := q.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			EndpointID: endpoint.ID,
+			EventID:    e.ID,
+			EventType:  e.Type,
+			Payload:    body,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/petermazzocco/go-ecommerce-api/internal/db"
+)
+
+const (
+	DefaultInterval     = 10 * time.Second
+	DefaultTimeout      = 10 * time.Second
+	DefaultBatchSize    = 20
+	DefaultMaxAttempts  = 8
+	DefaultDisableAfter = 20
+	DefaultBaseBackoff  = 30 * time.Second
+	MaxBackoff          = 12 * time.Hour
+
+	// sendLease hides a claimed delivery from other workers while it is being
+	// sent. If the process dies mid-send the delivery is retried after it.
+	sendLease = 5 * time.Minute
+
+	// maxResponseBody is how much of an endpoint's response is kept in the log
+	maxResponseBody = 1024
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Worker posts due webhook deliveries
+type Worker struct {
+	Store        db.Store
+	Client       *http.Client
+	Interval     time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	DisableAfter int
+	BaseBackoff  time.Duration
+}
+
+// Config holds the delivery settings
+type Config struct {
+	Interval     time.Duration // how often due deliveries are sent
+	Timeout      time.Duration // per request to an endpoint
+	MaxAttempts  int           // sends before a delivery is marked failed
+	DisableAfter int           // failed attempts in a row before an endpoint is disabled
+}
+
+// NewWorker sends due deliveries every c.Interval
+func NewWorker(c Config, store db.Store) *Worker {
+	return &Worker{
+		Store:        store,
+		Client:       &http.Client{Timeout: c.Timeout},
+		Interval:     c.Interval,
+		BatchSize:    DefaultBatchSize,
+		MaxAttempts:  c.MaxAttempts,
+		DisableAfter: c.DisableAfter,
+		BaseBackoff:  DefaultBaseBackoff,
+	}
+}
+
+// Run sends deliveries every Interval until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "Webhook delivery error", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends one batch of due deliveries and returns how many succeeded
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	q := db.New(w.Store)
+	deliveries, err := q.ClaimDueWebhookDeliveries(ctx, db.ClaimDueWebhookDeliveriesParams{
+		Lease:     pgtype.Interval{Microseconds: sendLease.Microseconds(), Valid: true},
+		BatchSize: int32(w.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	endpoints := make(map[int32]db.WebhookEndpoint)
+	succeeded := 0
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = q.GetWebhookEndpoint(ctx, delivery.EndpointID)
+			if err != nil {
+				return succeeded, err
+			}
+			endpoints[endpoint.ID] = endpoint
+		}
+		// The endpoint was disabled earlier in this batch; its deliveries
+		// wait until it is enabled again
+		if !endpoint.Enabled {
+			continue
+		}
+
+		status, err := w.send(ctx, q, endpoint, delivery)
+		if err == nil {
+			if err := q.MarkWebhookDeliverySucceeded(ctx, db.MarkWebhookDeliverySucceededParams{
+				ID:             delivery.ID,
+				ResponseStatus: pgtype.Int4{Int32: int32(status), Valid: true},
+			}); err != nil {
+				return succeeded, err
+			}
+			if endpoint.ConsecutiveFailures > 0 {
+				if err := q.RecordWebhookSuccess(ctx, endpoint.ID); err != nil {
+					return succeeded, err
+				}
+				endpoint.ConsecutiveFailures = 0
+				endpoints[endpoint.ID] = endpoint
+			}
+			succeeded++
+			continue
+		}
+
+		deliveryStatus := StatusPending
+		if int(delivery.Attempts) >= w.MaxAttempts {
+			deliveryStatus = StatusFailed
+		}
+		slog.WarnContext(ctx, "Webhook delivery failed", "delivery_id", delivery.ID, "url", endpoint.Url, "attempt", delivery.Attempts, "status", deliveryStatus, "err", err)
+
+		if err := q.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+			ID:             delivery.ID,
+			Status:         deliveryStatus,
+			ResponseStatus: pgtype.Int4{Int32: int32(status), Valid: status != 0},
+			LastError:      pgtype.Text{String: err.Error(), Valid: true},
+			NextAttemptAt:  pgtype.Timestamptz{Time: time.Now().Add(w.Backoff(int(delivery.Attempts))), Valid: true},
+		}); err != nil {
+			return succeeded, err
+		}
+
+		updated, err := q.RecordWebhookFailure(ctx, db.RecordWebhookFailureParams{
+			DisableAfter: int32(w.DisableAfter),
+			Reason:       fmt.Sprintf("Disabled after %d consecutive failed deliveries", w.DisableAfter),
+			ID:           endpoint.ID,
+		})
+		if err != nil {
+			return succeeded, err
+		}
+		if endpoint.Enabled && !updated.Enabled {
+			slog.WarnContext(ctx, "Webhook endpoint disabled", "endpoint_id", endpoint.ID, "url", endpoint.Url, "consecutive_failures", updated.ConsecutiveFailures)
+		}
+		endpoints[endpoint.ID] = updated
+	}
+	return succeeded, nil
+}
+
+// send posts a delivery and records the attempt. It returns the response
+// status, or zero when no response was received, and an error unless the
+// endpoint answered with a 2xx status.
+func (w *Worker) send(ctx context.Context, q *db.Queries, endpoint db.WebhookEndpoint, delivery db.WebhookDelivery) (int, error) {
+	status, body, duration, err := w.post(ctx, endpoint, delivery)
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("endpoint responded with %d", status)
+	}
+
+	attempt := db.AddWebhookDeliveryAttemptParams{
+		DeliveryID:     delivery.ID,
+		ResponseStatus: pgtype.Int4{Int32: int32(status), Valid: status != 0},
+		ResponseBody:   pgtype.Text{String: body, Valid: status != 0},
+		DurationMs:     int32(duration.Milliseconds()),
+	}
+	if err != nil {
+		attempt.Error = pgtype.Text{String: err.Error(), Valid: true}
+	}
+	if err := q.AddWebhookDeliveryAttempt(ctx, attempt); err != nil {
+		slog.ErrorContext(ctx, "Add webhook attempt error", "err", err)
+	}
+
+	return status, err
+}
+
+func (w *Worker) post(ctx context.Context, endpoint db.WebhookEndpoint, delivery db.WebhookDelivery) (int, string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-ecommerce-api-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(int(delivery.ID)))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, time.Now(), delivery.Payload))
+
+	start := time.Now()
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return 0, "", time.Since(start), err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+	return res.StatusCode, cleanBody(body), time.Since(start), nil
+}
+
+// cleanBody makes a response body safe to store in a TEXT column
+func cleanBody(b []byte) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(string(b), ""), "\x00", "")
+}
+
+// Backoff doubles the wait after every failed attempt, capped at MaxBackoff
+func (w *Worker) Backoff(attempts int) time.Duration {
+	wait := float64(w.BaseBackoff) * math.Pow(2, float64(attempts-1))
+	if wait > float64(MaxBackoff) {
+		return MaxBackoff
+	}
+	return time.Duration(wait)
+}
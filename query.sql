@@ -19,9 +19,120 @@ RETURNING *;
 DELETE FROM users
 WHERE id = $1 RETURNING *;
 
--- name: GetUserByEmailAndPassword :one
+-- name: GetUserByEmail :one
 SELECT * FROM users
-WHERE email = $1 and password_hash = $2 LIMIT 1;
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+  SET password_hash = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeactivateUser :execrows
+UPDATE users
+  SET is_admin = FALSE,
+  updated_at = NOW()
+WHERE id = $1 AND is_admin = TRUE;
+
+-- Login Failures
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (
+  key, failures
+) VALUES (
+  @key, 1
+)
+ON CONFLICT (key) DO UPDATE
+  SET failures = CASE WHEN login_failures.last_failure_at < NOW() - make_interval(secs => @window_seconds::float8) THEN 1 ELSE login_failures.failures + 1 END,
+  last_failure_at = NOW()
+RETURNING failures;
+
+-- name: LockLogin :exec
+UPDATE login_failures
+  SET locked_until = NOW() + make_interval(secs => @seconds::float8)
+WHERE key = @key;
+
+-- name: GetLoginLockout :one
+SELECT COALESCE(MAX(EXTRACT(EPOCH FROM locked_until - NOW())), 0)::float8 AS seconds
+FROM login_failures
+WHERE key = ANY(@keys::text[]) AND locked_until > NOW();
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures
+WHERE key = $1;
+
+-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < $1
+  AND (locked_until IS NULL OR locked_until < NOW());
+
+-- Security Events
+-- name: AddSecurityEvent :exec
+INSERT INTO security_events (
+  event_type, user_id, email, ip, user_agent, detail
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+);
+
+-- name: ListSecurityEvents :many
+SELECT * FROM security_events
+WHERE @email::text = '' OR email = @email
+ORDER BY created_at DESC
+LIMIT @max_rows;
+
+-- Two-Factor Authentication
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE user_id = $1 LIMIT 1;
+
+-- name: StartUserMFA :one
+INSERT INTO user_mfa (
+  user_id, secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (user_id) DO UPDATE
+  SET secret = EXCLUDED.secret,
+  last_step = 0,
+  created_at = NOW()
+WHERE user_mfa.enabled = FALSE
+RETURNING *;
+
+-- name: EnableUserMFA :execrows
+UPDATE user_mfa
+  SET enabled = TRUE,
+  enabled_at = NOW(),
+  last_step = $2
+WHERE user_id = $1 AND enabled = FALSE;
+
+-- name: UseUserMFAStep :execrows
+UPDATE user_mfa
+  SET last_step = $2
+WHERE user_id = $1 AND last_step < $2;
+
+-- name: DeleteUserMFA :execrows
+DELETE FROM user_mfa
+WHERE user_id = $1;
+
+-- name: AddMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+  user_id, code_hash
+) VALUES (
+  $1, $2
+);
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+  SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountMFARecoveryCodes :one
+SELECT COUNT(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
 
 -- Products
 -- name: GetProduct :one
@@ -81,7 +192,7 @@ INSERT INTO product_sizes (
 SELECT size_name, stock FROM product_sizes
 WHERE product_id = $1;
 
--- name: UpdateProductStock :exec
+-- name: UpdateProductStock :execrows
 UPDATE product_sizes
   SET stock = $3,
   updated_at = NOW()
@@ -135,7 +246,32 @@ UPDATE carts
   SET updated_at = NOW()
 WHERE id = $1;
 
+-- name: SetCartEmail :exec
+UPDATE carts
+  SET email = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListExpiredCarts :many
+SELECT id FROM carts
+WHERE updated_at < $1
+  AND NOT EXISTS (
+    SELECT 1 FROM cart_recoveries cr
+    WHERE cr.cart_id = carts.id AND cr.sent_at > $3
+  )
+ORDER BY updated_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteCarts :execrows
+DELETE FROM carts
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
 -- Cart Items
+-- name: DeleteCartItemsByCarts :execrows
+DELETE FROM cart_items
+WHERE cart_id = ANY(sqlc.arg(cart_ids)::uuid[]);
+
 -- name: GetCartItems :many
 SELECT 
     ci.product_id, 
@@ -143,7 +279,9 @@ SELECT
     ci.price_id,
     p.name, 
     p.description, 
-    p.price
+    p.price,
+    p.tax_category,
+    p.weight_grams
 FROM cart_items ci
 JOIN products p ON ci.product_id = p.id
 WHERE ci.cart_id = $1;
@@ -175,6 +313,100 @@ WHERE cart_id = $1;
 SELECT COUNT(*) FROM cart_items
 WHERE cart_id = $1;
 
+-- Sessions
+-- name: CreateSession :exec
+INSERT INTO sessions (
+  id, kind, user_id, cart_id, refresh_hash, access_jti, access_expires_at, expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: RotateSession :one
+WITH old AS (
+  SELECT id, access_jti, access_expires_at FROM sessions
+  WHERE refresh_hash = @refresh_hash AND kind = @kind AND revoked_at IS NULL AND expires_at > NOW()
+  FOR UPDATE
+), revoked AS (
+  INSERT INTO revoked_tokens (jti, expires_at)
+  SELECT access_jti, access_expires_at FROM old
+  ON CONFLICT (jti) DO NOTHING
+)
+UPDATE sessions s
+  SET refresh_hash = @new_refresh_hash,
+  previous_hash = @refresh_hash,
+  access_jti = @access_jti,
+  access_expires_at = @access_expires_at,
+  expires_at = @expires_at,
+  refreshed_at = NOW()
+FROM old
+WHERE s.id = old.id
+RETURNING s.*;
+
+-- name: RevokeReusedSession :one
+WITH s AS (
+  UPDATE sessions
+    SET revoked_at = NOW()
+  WHERE previous_hash = @previous_hash AND revoked_at IS NULL
+    AND refreshed_at < NOW() - make_interval(secs => @grace_seconds::float8)
+  RETURNING id, kind, user_id, access_jti, access_expires_at
+), revoked AS (
+  INSERT INTO revoked_tokens (jti, expires_at)
+  SELECT access_jti, access_expires_at FROM s
+  ON CONFLICT (jti) DO NOTHING
+)
+SELECT id, kind, user_id FROM s;
+
+-- name: SessionRefreshedWithin :one
+SELECT EXISTS (
+  SELECT 1 FROM sessions
+  WHERE previous_hash = @previous_hash AND revoked_at IS NULL
+    AND refreshed_at >= NOW() - make_interval(secs => @grace_seconds::float8)
+);
+
+-- name: RevokeSession :execrows
+WITH s AS (
+  UPDATE sessions
+    SET revoked_at = NOW()
+  WHERE (id = @id OR refresh_hash = @refresh_hash) AND revoked_at IS NULL
+  RETURNING access_jti, access_expires_at
+)
+INSERT INTO revoked_tokens (jti, expires_at)
+SELECT access_jti, access_expires_at FROM s
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: RevokeUserSessions :execrows
+WITH s AS (
+  UPDATE sessions
+    SET revoked_at = NOW()
+  WHERE user_id = $1 AND revoked_at IS NULL
+  RETURNING access_jti, access_expires_at
+)
+INSERT INTO revoked_tokens (jti, expires_at)
+SELECT access_jti, access_expires_at FROM s
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at < NOW() OR revoked_at IS NOT NULL;
+
+-- Revoked Tokens
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (
+  jti, expires_at
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+  SELECT 1 FROM revoked_tokens WHERE jti = $1
+);
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < NOW();
+
 -- Collections
 -- name: GetCollection :one
 SELECT * FROM collections
@@ -236,3 +468,647 @@ WHERE collection_id = $1 AND product_id = $2;
 SELECT p.* FROM products p
 JOIN collection_products cp ON p.id = cp.product_id
 WHERE cp.collection_id = $1;
+
+-- name: UpdateProductTaxCategory :exec
+UPDATE products
+  SET tax_category = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateProductWeight :exec
+UPDATE products
+  SET weight_grams = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- Tax Rates
+-- name: ListTaxRates :many
+SELECT * FROM tax_rates
+ORDER BY country, state, postal_prefix, tax_category;
+
+-- name: ListTaxRatesByCountry :many
+SELECT * FROM tax_rates
+WHERE country = $1;
+
+-- name: CreateTaxRate :one
+INSERT INTO tax_rates (
+  name, country, state, postal_prefix, tax_category, rate
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: DeleteTaxRate :exec
+DELETE FROM tax_rates
+WHERE id = $1;
+
+-- Orders
+-- name: CreateOrder :one
+INSERT INTO orders (
+  cart_id, checkout_session_id, email, currency, subtotal, tax_total, total, tax_inclusive,
+  shipping_method, shipping_total
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: GetOrder :one
+SELECT * FROM orders
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOrders :many
+SELECT * FROM orders
+ORDER BY created_at DESC;
+
+-- Order Items
+-- name: AddOrderItem :exec
+INSERT INTO order_items (
+  order_id, product_id, name, price_id, quantity, unit_price, tax_category
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: GetOrderItems :many
+SELECT * FROM order_items
+WHERE order_id = $1
+ORDER BY id;
+
+-- Order Tax Lines
+-- name: AddOrderTaxLine :exec
+INSERT INTO order_tax_lines (
+  order_id, name, jurisdiction, rate, taxable_amount, amount
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+);
+
+-- name: GetOrderTaxLines :many
+SELECT * FROM order_tax_lines
+WHERE order_id = $1
+ORDER BY id;
+
+-- Shipping Zones
+-- name: ListShippingZones :many
+SELECT * FROM shipping_zones
+ORDER BY name;
+
+-- name: GetShippingZone :one
+SELECT * FROM shipping_zones
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateShippingZone :one
+INSERT INTO shipping_zones (
+  name
+) VALUES (
+  $1
+)
+RETURNING *;
+
+-- name: DeleteShippingZone :exec
+DELETE FROM shipping_zones
+WHERE id = $1;
+
+-- name: AddShippingZoneRegion :exec
+INSERT INTO shipping_zone_regions (
+  zone_id, country, state
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (zone_id, country, state) DO NOTHING;
+
+-- name: ListShippingZoneRegions :many
+SELECT * FROM shipping_zone_regions
+ORDER BY zone_id, country, state;
+
+-- Shipping Methods
+-- name: ListShippingMethods :many
+SELECT * FROM shipping_methods
+ORDER BY zone_id, rate;
+
+-- name: GetShippingMethod :one
+SELECT * FROM shipping_methods
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateShippingMethod :one
+INSERT INTO shipping_methods (
+  zone_id, name, type, rate, free_over, min_days, max_days
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: SetShippingMethodActive :exec
+UPDATE shipping_methods
+  SET active = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteShippingMethod :exec
+DELETE FROM shipping_methods
+WHERE id = $1;
+
+-- name: AddShippingMethodTier :exec
+INSERT INTO shipping_method_tiers (
+  method_id, min_value, rate
+) VALUES (
+  $1, $2, $3
+);
+
+-- name: ListShippingMethodTiers :many
+SELECT * FROM shipping_method_tiers
+ORDER BY method_id, min_value;
+
+-- name: GetOrderByCheckoutSession :one
+SELECT * FROM orders
+WHERE checkout_session_id = $1 LIMIT 1;
+
+-- Idempotency Keys
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+  key, scope, request_hash, expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (key, scope) DO NOTHING
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE key = $1 AND scope = $2 LIMIT 1;
+
+-- name: SaveIdempotencyResponse :exec
+UPDATE idempotency_keys
+  SET status_code = $2,
+  response_headers = $3,
+  response_body = $4
+WHERE id = $1;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE id = $1;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at < NOW();
+
+-- Rate Limits
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limits AS r (
+  key, tokens, allowed
+) VALUES (
+  @key, @burst::float8 - 1, TRUE
+)
+ON CONFLICT (key) DO UPDATE
+  SET tokens = LEAST(@burst::float8, r.tokens + EXTRACT(EPOCH FROM NOW() - r.updated_at)::float8 * @rate::float8)
+    - CASE WHEN LEAST(@burst::float8, r.tokens + EXTRACT(EPOCH FROM NOW() - r.updated_at)::float8 * @rate::float8) >= 1 THEN 1 ELSE 0 END,
+  allowed = LEAST(@burst::float8, r.tokens + EXTRACT(EPOCH FROM NOW() - r.updated_at)::float8 * @rate::float8) >= 1,
+  updated_at = NOW()
+RETURNING tokens, allowed;
+
+-- name: DeleteStaleRateLimits :execrows
+DELETE FROM rate_limits
+WHERE updated_at < $1;
+
+-- name: GetOrderForUpdate :one
+SELECT * FROM orders
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: UpdateOrderStatus :exec
+UPDATE orders
+  SET status = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: IncrementProductStock :execrows
+UPDATE product_sizes
+  SET stock = stock + sqlc.arg(quantity),
+  updated_at = NOW()
+WHERE product_id = $1 AND size_name = $2;
+
+-- Returns
+-- name: CreateReturn :one
+INSERT INTO returns (
+  order_id, email, reason
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetReturn :one
+SELECT * FROM returns
+WHERE id = $1 LIMIT 1;
+
+-- name: GetReturnForUpdate :one
+SELECT * FROM returns
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: ListReturns :many
+SELECT * FROM returns
+ORDER BY created_at DESC;
+
+-- A null note keeps the one written when the return was resolved
+-- name: UpdateReturnStatus :exec
+UPDATE returns
+  SET status = $2,
+  note = COALESCE($3, note),
+  updated_at = NOW()
+WHERE id = $1;
+
+-- Return Items
+-- name: AddReturnItem :exec
+INSERT INTO return_items (
+  return_id, order_item_id, quantity, size_name, reason
+) VALUES (
+  $1, $2, $3, $4, $5
+);
+
+-- name: GetReturnItems :many
+SELECT * FROM return_items
+WHERE return_id = $1
+ORDER BY id;
+
+-- name: MarkReturnItemRestocked :exec
+UPDATE return_items
+  SET restocked = TRUE
+WHERE id = $1;
+
+-- name: GetReturnedQuantity :one
+SELECT COALESCE(SUM(ri.quantity), 0)::int AS returned
+FROM return_items ri
+JOIN returns r ON r.id = ri.return_id
+WHERE ri.order_item_id = $1 AND r.status <> 'rejected';
+
+-- Refunds
+-- name: CreateRefund :one
+INSERT INTO refunds (
+  order_id, return_id, amount, reason, created_by
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: UpdateRefundStatus :exec
+UPDATE refunds
+  SET status = $2,
+  provider_refund_id = $3,
+  failure_reason = $4,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetOrderRefund :one
+SELECT * FROM refunds
+WHERE id = $1 AND order_id = $2 LIMIT 1;
+
+-- name: ListOrderRefunds :many
+SELECT * FROM refunds
+WHERE order_id = $1
+ORDER BY created_at;
+
+-- name: GetRefundedTotal :one
+SELECT COALESCE(SUM(amount), 0)::decimal AS refunded
+FROM refunds
+WHERE order_id = $1 AND status <> 'failed';
+
+-- Refund Events
+-- name: AddRefundEvent :exec
+INSERT INTO refund_events (
+  refund_id, status, message
+) VALUES (
+  $1, $2, $3
+);
+
+-- name: ListOrderRefundEvents :many
+SELECT re.* FROM refund_events re
+JOIN refunds rf ON rf.id = re.refund_id
+WHERE rf.order_id = $1
+ORDER BY re.created_at, re.id;
+
+-- name: UpdateOrderFulfillmentStatus :exec
+UPDATE orders
+  SET fulfillment_status = $2,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- Fulfillments
+-- name: CreateFulfillment :one
+INSERT INTO fulfillments (
+  order_id, carrier, tracking_number, tracking_url, status, shipped_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetFulfillment :one
+SELECT * FROM fulfillments
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOrderFulfillments :many
+SELECT * FROM fulfillments
+WHERE order_id = $1
+ORDER BY created_at, id;
+
+-- name: UpdateFulfillment :exec
+UPDATE fulfillments
+  SET carrier = $2,
+  tracking_number = $3,
+  tracking_url = $4,
+  status = $5,
+  shipped_at = $6,
+  delivered_at = $7,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- Fulfillment Items
+-- name: AddFulfillmentItem :exec
+INSERT INTO fulfillment_items (
+  fulfillment_id, order_item_id, quantity
+) VALUES (
+  $1, $2, $3
+);
+
+-- name: ListOrderFulfillmentItems :many
+SELECT fi.* FROM fulfillment_items fi
+JOIN fulfillments f ON f.id = fi.fulfillment_id
+WHERE f.order_id = $1
+ORDER BY fi.id;
+
+-- Order Events
+-- name: AddOrderEvent :exec
+INSERT INTO order_events (
+  order_id, from_status, to_status, actor, user_id, note
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+);
+
+-- name: ListOrderEvents :many
+SELECT * FROM order_events
+WHERE order_id = $1
+ORDER BY created_at, id;
+
+-- Cart Recoveries
+-- name: ListAbandonedCarts :many
+SELECT c.id, c.email, c.updated_at FROM carts c
+WHERE c.email IS NOT NULL
+  AND c.updated_at < sqlc.arg(idle_before)
+  AND c.updated_at > sqlc.arg(idle_after)
+  AND EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = c.id)
+  AND NOT EXISTS (SELECT 1 FROM cart_recoveries cr WHERE cr.cart_id = c.id)
+  AND NOT EXISTS (
+    SELECT 1 FROM orders o
+    WHERE o.cart_id = c.id AND o.status NOT IN ('pending_payment', 'cancelled')
+  )
+ORDER BY c.updated_at
+LIMIT sqlc.arg(batch_size);
+
+-- name: CreateCartRecovery :one
+INSERT INTO cart_recoveries (
+  cart_id, email
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (cart_id) DO NOTHING
+RETURNING *;
+
+-- name: DeleteCartRecovery :exec
+DELETE FROM cart_recoveries
+WHERE id = $1;
+
+-- name: MarkCartRecoveryRestored :exec
+UPDATE cart_recoveries
+  SET restored_at = NOW()
+WHERE cart_id = $1 AND restored_at IS NULL;
+
+-- name: MarkCartRecoveryConverted :exec
+UPDATE cart_recoveries
+  SET order_id = $2,
+  converted_at = NOW()
+WHERE cart_id = $1 AND converted_at IS NULL;
+
+-- name: GetCartRecoveryStats :one
+SELECT
+  COUNT(*)::int AS sent,
+  COUNT(cr.restored_at)::int AS restored,
+  COUNT(cr.converted_at)::int AS converted,
+  COALESCE(SUM(o.total), 0)::decimal AS recovered_revenue
+FROM cart_recoveries cr
+LEFT JOIN orders o ON o.id = cr.order_id
+WHERE cr.sent_at >= sqlc.arg(sent_from) AND cr.sent_at < sqlc.arg(sent_to);
+
+-- Email Outbox
+-- name: EnqueueEmail :one
+INSERT INTO email_outbox (
+  template, recipient, subject, html_body, text_body
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: ClaimDueEmails :many
+UPDATE email_outbox
+  SET attempts = attempts + 1,
+  next_attempt_at = NOW() + sqlc.arg(lease)::interval
+WHERE id IN (
+  SELECT id FROM email_outbox
+  WHERE status = 'pending' AND next_attempt_at <= NOW()
+  ORDER BY next_attempt_at
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkEmailSent :exec
+UPDATE email_outbox
+  SET status = 'sent',
+  sent_at = NOW(),
+  last_error = NULL
+WHERE id = $1;
+
+-- name: MarkEmailFailed :exec
+UPDATE email_outbox
+  SET status = $2,
+  last_error = $3,
+  next_attempt_at = $4
+WHERE id = $1;
+
+-- name: ListEmails :many
+SELECT * FROM email_outbox
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- Domain Events
+-- name: AddDomainEvent :one
+INSERT INTO domain_events (
+  event_type, aggregate_type, aggregate_id, payload
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: ClaimDueDomainEvents :many
+UPDATE domain_events
+  SET attempts = attempts + 1,
+  next_attempt_at = NOW() + sqlc.arg(lease)::interval
+WHERE id IN (
+  SELECT id FROM domain_events
+  WHERE status = 'pending' AND next_attempt_at <= NOW()
+  ORDER BY id
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkDomainEventDispatched :exec
+UPDATE domain_events
+  SET status = 'dispatched',
+  dispatched_at = NOW(),
+  last_error = NULL
+WHERE id = $1;
+
+-- name: MarkDomainEventFailed :exec
+UPDATE domain_events
+  SET status = $2,
+  last_error = $3,
+  next_attempt_at = $4
+WHERE id = $1;
+
+-- name: ListDomainEvents :many
+SELECT * FROM domain_events
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: AddDomainEventDelivery :exec
+INSERT INTO domain_event_deliveries (
+  event_id, subscriber
+) VALUES (
+  $1, $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListDomainEventDeliveries :many
+SELECT subscriber FROM domain_event_deliveries
+WHERE event_id = $1;
+
+-- Webhook Endpoints
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (
+  url, description, event_types, secret
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: ListWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+ORDER BY id;
+
+-- name: ListWebhookEndpointsForEvent :many
+SELECT * FROM webhook_endpoints
+WHERE enabled AND (sqlc.arg(event_type)::text = ANY(event_types) OR '*' = ANY(event_types));
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+  SET url = sqlc.arg(url),
+  description = sqlc.arg(description),
+  event_types = sqlc.arg(event_types),
+  consecutive_failures = CASE WHEN sqlc.arg(enabled)::boolean AND NOT enabled THEN 0 ELSE consecutive_failures END,
+  disabled_at = CASE WHEN sqlc.arg(enabled)::boolean THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+  disabled_reason = CASE WHEN sqlc.arg(enabled)::boolean THEN NULL ELSE COALESCE(disabled_reason, 'Disabled by an admin') END,
+  enabled = sqlc.arg(enabled)::boolean,
+  updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: RecordWebhookSuccess :exec
+UPDATE webhook_endpoints
+  SET consecutive_failures = 0
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :one
+UPDATE webhook_endpoints
+  SET consecutive_failures = consecutive_failures + 1,
+  enabled = enabled AND consecutive_failures + 1 < sqlc.arg(disable_after)::int,
+  disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= sqlc.arg(disable_after)::int THEN NOW() ELSE disabled_at END,
+  disabled_reason = CASE WHEN enabled AND consecutive_failures + 1 >= sqlc.arg(disable_after)::int THEN sqlc.arg(reason)::text ELSE disabled_reason END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- Webhook Deliveries
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (
+  endpoint_id, event_id, event_type, payload
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (endpoint_id, event_id) DO NOTHING;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+  SET attempts = attempts + 1,
+  next_attempt_at = NOW() + sqlc.arg(lease)::interval
+WHERE id IN (
+  SELECT d.id FROM webhook_deliveries d
+  JOIN webhook_endpoints e ON e.id = d.endpoint_id
+  WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND e.enabled
+  ORDER BY d.next_attempt_at
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+  SET status = 'succeeded',
+  response_status = $2,
+  last_error = NULL,
+  delivered_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+  SET status = $2,
+  response_status = $3,
+  last_error = $4,
+  next_attempt_at = $5
+WHERE id = $1;
+
+-- name: RedeliverWebhookDelivery :execrows
+UPDATE webhook_deliveries
+  SET status = 'pending',
+  attempts = 0,
+  next_attempt_at = NOW()
+WHERE id = $1;
+
+-- name: AddWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (
+  delivery_id, response_status, response_body, error, duration_ms
+) VALUES (
+  $1, $2, $3, $4, $5
+);
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY created_at;
+
+-- name: ListSchemaColumns :many
+SELECT table_name::text, column_name::text FROM information_schema.columns
+WHERE table_schema = current_schema();
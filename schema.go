@@ -0,0 +1,10 @@
+// Package ecommerce exposes files kept at the repository root to the rest of
+// the module
+package ecommerce
+
+import _ "embed"
+
+// Schema is schema.sql, the database schema this build expects
+//
+//go:embed schema.sql
+var Schema string
@@ -1,5 +1,5 @@
 -- User table (track if the user is a customer or admin)
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     email VARCHAR(255) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
@@ -8,20 +8,66 @@ CREATE TABLE users (
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Failed admin logins per account ("account:<email>") and client IP ("ip:<addr>")
+CREATE TABLE IF NOT EXISTS login_failures (
+    key VARCHAR(255) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Admin security log: logins, failures, lockouts, password, two-factor and session changes
+CREATE TABLE IF NOT EXISTS security_events (
+    id SERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL CHECK (event_type IN ('login_succeeded', 'login_failed', 'account_locked', 'ip_locked', 'password_changed', 'mfa_enabled', 'mfa_failed', 'mfa_reset', 'mfa_recovery_code_used', 'mfa_recovery_codes_regenerated', 'admin_deactivated', 'refresh_token_reused')),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS security_events_created_at_idx ON security_events (created_at);
+CREATE INDEX IF NOT EXISTS security_events_email_idx ON security_events (email);
+
+-- TOTP two-factor authentication for admin users. A secret stays pending
+-- until a code from the authenticator app confirms it.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0, -- last TOTP step used, so a code only works once
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    enabled_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One-time recovery codes for a lost authenticator, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, code_hash)
+);
+
 
 -- Products table (already implied in your code)
-CREATE TABLE products (
+CREATE TABLE IF NOT EXISTS products (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     description TEXT,
     price DECIMAL(10, 2) NOT NULL,
     price_id VARCHAR(255) NOT NULL,
+    tax_category VARCHAR(50) NOT NULL DEFAULT 'standard',
+    weight_grams INTEGER NOT NULL DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
 -- Product images
-CREATE TABLE product_images (
+CREATE TABLE IF NOT EXISTS product_images (
     id SERIAL PRIMARY KEY,
     product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     image_url VARCHAR(255) NOT NULL,
@@ -31,7 +77,7 @@ CREATE TABLE product_images (
 );
 
 -- Product sizes
-CREATE TABLE product_sizes (
+CREATE TABLE IF NOT EXISTS product_sizes (
     id SERIAL PRIMARY KEY,
     product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     size_name VARCHAR(50) NOT NULL,
@@ -41,7 +87,7 @@ CREATE TABLE product_sizes (
 );
 
 -- Fit guide
-CREATE TABLE fit_guides (
+CREATE TABLE IF NOT EXISTS fit_guides (
     id SERIAL PRIMARY KEY,
     product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     body_length DECIMAL(5, 2),
@@ -62,7 +108,7 @@ CREATE TABLE fit_guides (
 );
 
 -- Collections
-CREATE TABLE collections (
+CREATE TABLE IF NOT EXISTS collections (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     description TEXT,
@@ -71,7 +117,7 @@ CREATE TABLE collections (
 );
 
 -- Collection images
-CREATE TABLE collection_images (
+CREATE TABLE IF NOT EXISTS collection_images (
     id SERIAL PRIMARY KEY,
     collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
     image_url VARCHAR(255) NOT NULL,
@@ -81,7 +127,7 @@ CREATE TABLE collection_images (
 );
 
 -- Products in collections (many-to-many)
-CREATE TABLE collection_products (
+CREATE TABLE IF NOT EXISTS collection_products (
     collection_id INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
     product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     PRIMARY KEY (collection_id, product_id),
@@ -90,14 +136,15 @@ CREATE TABLE collection_products (
 );
 
 -- Carts
-CREATE TABLE carts (
+CREATE TABLE IF NOT EXISTS carts (
     id UUID PRIMARY KEY,
+    email VARCHAR(255),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
 -- Cart items
-CREATE TABLE cart_items (
+CREATE TABLE IF NOT EXISTS cart_items (
     id SERIAL PRIMARY KEY,
     cart_id UUID NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
     product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
@@ -107,3 +154,365 @@ CREATE TABLE cart_items (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
+
+-- Cart and admin sessions behind the short-lived access tokens. The refresh
+-- token is stored as a SHA-256 hash and replaced on every refresh; the one
+-- before it is kept so reusing it ends the session.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('cart', 'admin')),
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    cart_id UUID REFERENCES carts(id) ON DELETE CASCADE,
+    refresh_hash VARCHAR(64) NOT NULL UNIQUE,
+    previous_hash VARCHAR(64),
+    access_jti VARCHAR(64) NOT NULL, -- latest access token, revoked with the session
+    access_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    refreshed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_previous_hash_idx ON sessions (previous_hash);
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions (expires_at);
+
+-- Access tokens that stop working before they expire, kept until they would have
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+
+-- Tax rates (rules matched by country, state and postal code prefix)
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    country VARCHAR(2) NOT NULL,
+    state VARCHAR(50) NOT NULL DEFAULT '',
+    postal_prefix VARCHAR(20) NOT NULL DEFAULT '',
+    tax_category VARCHAR(50) NOT NULL DEFAULT '',
+    rate DECIMAL(7, 4) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Orders
+CREATE TABLE IF NOT EXISTS orders (
+    id SERIAL PRIMARY KEY,
+    cart_id UUID REFERENCES carts(id) ON DELETE SET NULL,
+    checkout_session_id VARCHAR(255),
+    email VARCHAR(255),
+    currency VARCHAR(3) NOT NULL DEFAULT 'usd',
+    subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    tax_total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    shipping_method VARCHAR(255),
+    shipping_total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending_payment' CHECK (status IN ('pending_payment', 'paid', 'processing', 'shipped', 'delivered', 'cancelled', 'refunded')),
+    fulfillment_status VARCHAR(50) NOT NULL DEFAULT 'unfulfilled',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Order items (snapshot of the cart at checkout)
+CREATE TABLE IF NOT EXISTS order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INTEGER REFERENCES products(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    price_id VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    tax_category VARCHAR(50) NOT NULL DEFAULT 'standard',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Order tax lines
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    jurisdiction VARCHAR(100) NOT NULL,
+    rate DECIMAL(7, 4) NOT NULL,
+    taxable_amount DECIMAL(10, 2) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Shipping zones
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Countries (and optionally states) covered by a shipping zone
+CREATE TABLE IF NOT EXISTS shipping_zone_regions (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    country VARCHAR(2) NOT NULL,
+    state VARCHAR(50) NOT NULL DEFAULT '',
+    UNIQUE(zone_id, country, state),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Shipping methods (flat, weight or price tiered, optionally free over a threshold)
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('flat', 'weight', 'price')),
+    rate DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    free_over DECIMAL(10, 2),
+    min_days INTEGER,
+    max_days INTEGER,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Tiers for weight (grams) and price (subtotal) based methods
+CREATE TABLE IF NOT EXISTS shipping_method_tiers (
+    id SERIAL PRIMARY KEY,
+    method_id INTEGER NOT NULL REFERENCES shipping_methods(id) ON DELETE CASCADE,
+    min_value DECIMAL(10, 2) NOT NULL,
+    rate DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Idempotency keys (first response stored per key and cart/user scope)
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(255) NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    response_headers JSONB,
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE(key, scope)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+-- Token buckets for the Postgres rate limit backend, shared by every instance
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_idx ON rate_limits (updated_at);
+
+-- Customer return requests (RMA)
+CREATE TABLE IF NOT EXISTS returns (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'rejected', 'refunded', 'refund_failed')),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Items selected for a return
+CREATE TABLE IF NOT EXISTS return_items (
+    id SERIAL PRIMARY KEY,
+    return_id INTEGER NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    size_name VARCHAR(50) NOT NULL DEFAULT '',
+    reason TEXT,
+    restocked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Refunds issued through the payment provider
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    return_id INTEGER REFERENCES returns(id) ON DELETE SET NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    provider_refund_id VARCHAR(255),
+    failure_reason TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Audit trail of every refund state change
+CREATE TABLE IF NOT EXISTS refund_events (
+    id SERIAL PRIMARY KEY,
+    refund_id INTEGER NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Shipments of order items
+CREATE TABLE IF NOT EXISTS fulfillments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    carrier VARCHAR(100),
+    tracking_number VARCHAR(255),
+    tracking_url TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'shipped', 'delivered')),
+    shipped_at TIMESTAMP WITH TIME ZONE,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Order items included in a fulfillment
+CREATE TABLE IF NOT EXISTS fulfillment_items (
+    id SERIAL PRIMARY KEY,
+    fulfillment_id INTEGER NOT NULL REFERENCES fulfillments(id) ON DELETE CASCADE,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+-- Order status history, one row per transition
+CREATE TABLE IF NOT EXISTS order_events (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    actor VARCHAR(50) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Recovery reminders sent for abandoned carts, one per cart
+CREATE TABLE IF NOT EXISTS cart_recoveries (
+    id SERIAL PRIMARY KEY,
+    cart_id UUID UNIQUE REFERENCES carts(id) ON DELETE SET NULL,
+    email VARCHAR(255) NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    restored_at TIMESTAMP WITH TIME ZONE,
+    order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+    converted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_updated_at ON carts(updated_at);
+
+-- Rendered emails waiting to be sent, so sends survive restarts
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id SERIAL PRIMARY KEY,
+    template VARCHAR(100) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(next_attempt_at) WHERE status = 'pending';
+
+-- Domain events are written in the same transaction as the change they
+-- describe and delivered to subscribers by the event dispatcher
+CREATE TABLE IF NOT EXISTS domain_events (
+    id SERIAL PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dispatched', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    dispatched_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_domain_events_due ON domain_events(next_attempt_at) WHERE status = 'pending';
+
+-- Subscribers that already handled an event are skipped when it is retried
+CREATE TABLE IF NOT EXISTS domain_event_deliveries (
+    event_id INTEGER NOT NULL REFERENCES domain_events(id) ON DELETE CASCADE,
+    subscriber VARCHAR(100) NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (event_id, subscriber)
+);
+
+-- Outbound webhooks, fed by the domain event bus
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    description TEXT,
+    event_types TEXT[] NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    disabled_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_id INTEGER NOT NULL REFERENCES domain_events(id) ON DELETE CASCADE,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id SERIAL PRIMARY KEY,
+    delivery_id INTEGER NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Upgrades for databases created from an earlier schema.sql. CREATE TABLE IF
+-- NOT EXISTS leaves an existing table alone, so a column added to a table, or
+-- a changed constraint, is repeated here in a form that is safe to re-run.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_category VARCHAR(50) NOT NULL DEFAULT 'standard';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fulfillment_status VARCHAR(50) NOT NULL DEFAULT 'unfulfilled';
+
+ALTER TABLE returns DROP CONSTRAINT IF EXISTS returns_status_check;
+ALTER TABLE returns ADD CONSTRAINT returns_status_check CHECK (status IN ('requested', 'approved', 'rejected', 'refunded', 'refund_failed'));
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('pending_payment', 'paid', 'processing', 'shipped', 'delivered', 'cancelled', 'refunded'));
+
+-- Recovery history outlives the carts it reminded about
+ALTER TABLE cart_recoveries ALTER COLUMN cart_id DROP NOT NULL;
+ALTER TABLE cart_recoveries DROP CONSTRAINT IF EXISTS cart_recoveries_cart_id_fkey;
+ALTER TABLE cart_recoveries ADD CONSTRAINT cart_recoveries_cart_id_fkey FOREIGN KEY (cart_id) REFERENCES carts(id) ON DELETE SET NULL;
+
+ALTER TABLE security_events DROP CONSTRAINT IF EXISTS security_events_event_type_check;
+ALTER TABLE security_events ADD CONSTRAINT security_events_event_type_check CHECK (event_type IN ('login_succeeded', 'login_failed', 'account_locked', 'ip_locked', 'password_changed', 'mfa_enabled', 'mfa_failed', 'mfa_reset', 'mfa_recovery_code_used', 'mfa_recovery_codes_regenerated', 'admin_deactivated', 'refresh_token_reused'));